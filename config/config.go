@@ -0,0 +1,421 @@
+// Loads the server's settings from an optional YAML file & environment variables
+// Environment variables take precedence over the file, so that a deployment can override single settings (e.g. secrets)
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The environment variable holding the path to the YAML file. The file is optional
+const FileEnvVar = "HRIS_CONFIG_FILE"
+
+// File storage modes
+const (
+	S3ModeFake = "fake" // An in-memory S3 server (gofakes3) that is started with the server. Files are lost on restart
+	S3ModeAws  = "aws"
+)
+
+// Trace exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOtlp   = "otlp"   // OTLP over HTTP, e.g. to an OpenTelemetry collector or Jaeger
+	TracingExporterStdout = "stdout" // Prints spans to stdout, for local testing
+)
+
+const redacted = "[REDACTED]"
+
+// A string that is redacted when it is logged or printed, e.g. a password or key
+type Secret string
+
+func (secret Secret) String() string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (secret Secret) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(secret.String())), nil
+}
+
+type Config struct {
+	ListenAddress string `yaml:"listenAddress" json:"listenAddress"`
+	LogOutput     string `yaml:"logOutput" json:"logOutput"` // stdout, stderr or the path of a file that logs are appended to
+
+	// Metrics are served on a separate address, so that they are not exposed with the API
+	MetricsListenAddress string `yaml:"metricsListenAddress" json:"metricsListenAddress"`
+
+	// Time that requests in progress have to complete once the server is asked to stop
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" json:"shutdownTimeout"`
+
+	Postgres Postgres `yaml:"postgres" json:"postgres"`
+	Session  Session  `yaml:"session" json:"session"`
+	Lockout  Lockout  `yaml:"lockout" json:"lockout"`
+	S3       S3       `yaml:"s3" json:"s3"`
+	Tracing  Tracing  `yaml:"tracing" json:"tracing"`
+}
+
+// The api user can only read & write data, whereas the migration user owns the schema
+type Postgres struct {
+	Host              string `yaml:"host" json:"host"`
+	Port              int    `yaml:"port" json:"port"`
+	Database          string `yaml:"database" json:"database"`
+	SslMode           string `yaml:"sslMode" json:"sslMode"`
+	User              string `yaml:"user" json:"user"`
+	Password          Secret `yaml:"password" json:"password"`
+	MigrationUser     string `yaml:"migrationUser" json:"migrationUser"`
+	MigrationPassword Secret `yaml:"migrationPassword" json:"migrationPassword"`
+}
+
+// Every instance behind a load balancer must use the same keys
+type Session struct {
+	AuthKey        Secret   `yaml:"authKey" json:"authKey"`
+	EncryptionKey  Secret   `yaml:"encryptionKey" json:"encryptionKey"`
+	Secure         bool     `yaml:"secure" json:"secure"`
+	SameSite       string   `yaml:"sameSite" json:"sameSite"` // lax, strict or none
+	TrustedOrigins []string `yaml:"trustedOrigins" json:"trustedOrigins"`
+}
+
+type Lockout struct {
+	MaxFailedAttempts   int           `yaml:"maxFailedAttempts" json:"maxFailedAttempts"`
+	BaseLockoutDuration time.Duration `yaml:"baseLockoutDuration" json:"baseLockoutDuration"`
+	MaxLockoutDuration  time.Duration `yaml:"maxLockoutDuration" json:"maxLockoutDuration"`
+}
+
+// The access keys are optional in aws mode. If they are not set, the default AWS credential chain (e.g. an IAM role) is used
+type S3 struct {
+	Mode            string `yaml:"mode" json:"mode"`
+	Region          string `yaml:"region" json:"region"`
+	AccessKeyId     string `yaml:"accessKeyId" json:"accessKeyId"`
+	SecretAccessKey Secret `yaml:"secretAccessKey" json:"secretAccessKey"`
+}
+
+// The sample ratio is the fraction of new traces that are recorded. Requests that continue a caller's trace follow the caller's decision
+type Tracing struct {
+	Exporter     string  `yaml:"exporter" json:"exporter"`
+	OtlpEndpoint string  `yaml:"otlpEndpoint" json:"otlpEndpoint"` // e.g. http://localhost:4318
+	SampleRatio  float64 `yaml:"sampleRatio" json:"sampleRatio"`
+}
+
+// Returns the settings for local development. Secrets have no defaults & must always be provided
+func Default() Config {
+	return Config{
+		ListenAddress:        "localhost:3000",
+		LogOutput:            "stdout",
+		MetricsListenAddress: "localhost:9090",
+		ShutdownTimeout:      30 * time.Second,
+		Postgres: Postgres{
+			Host:          "localhost",
+			Port:          5433,
+			Database:      "hr_information_system",
+			SslMode:       "disable",
+			User:          "hr_information_system",
+			MigrationUser: "postgres",
+		},
+		Session: Session{
+			SameSite: "lax",
+		},
+		Lockout: Lockout{
+			MaxFailedAttempts:   5,
+			BaseLockoutDuration: time.Minute,
+			MaxLockoutDuration:  time.Hour,
+		},
+		S3: S3{
+			Mode: S3ModeFake,
+		},
+		Tracing: Tracing{
+			Exporter:     TracingExporterNone,
+			OtlpEndpoint: "http://localhost:4318",
+			SampleRatio:  1,
+		},
+	}
+}
+
+// Applies the YAML file (if HRIS_CONFIG_FILE is set) & then the environment variables over the defaults
+// The settings are not validated, as the server & the migrate subcommand need different settings. Use Validate or ValidateMigration
+func Load() (Config, error) {
+	config := Default()
+
+	if path := os.Getenv(FileEnvVar); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("could not open config file: %w", err)
+		}
+		defer file.Close()
+
+		err = config.applyYaml(file)
+		if err != nil {
+			return Config{}, fmt.Errorf("could not parse config file %s: %w", path, err)
+		}
+	}
+
+	err := config.applyEnv(os.LookupEnv)
+	if err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Settings that are missing from the file keep their current values
+func (config *Config) applyYaml(r io.Reader) error {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true) // Typos should not be silently ignored
+
+	err := decoder.Decode(config)
+	if errors.Is(err, io.EOF) {
+		return nil // The file is empty
+	}
+	return err
+}
+
+// Lists (e.g. HRIS_SESSION_TRUSTED_ORIGINS) are separated by commas
+func (config *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	env := envParser{lookupEnv: lookupEnv}
+
+	env.string("HRIS_LISTEN_ADDRESS", &config.ListenAddress)
+	env.string("HRIS_LOG_OUTPUT", &config.LogOutput)
+	env.string("HRIS_METRICS_LISTEN_ADDRESS", &config.MetricsListenAddress)
+	env.duration("HRIS_SHUTDOWN_TIMEOUT", &config.ShutdownTimeout)
+
+	env.string("HRIS_POSTGRES_HOST", &config.Postgres.Host)
+	env.int("HRIS_POSTGRES_PORT", &config.Postgres.Port)
+	env.string("HRIS_POSTGRES_DATABASE", &config.Postgres.Database)
+	env.string("HRIS_POSTGRES_SSL_MODE", &config.Postgres.SslMode)
+	env.string("HRIS_POSTGRES_USER", &config.Postgres.User)
+	env.secret("HRIS_POSTGRES_PASSWORD", &config.Postgres.Password)
+	env.string("HRIS_POSTGRES_MIGRATION_USER", &config.Postgres.MigrationUser)
+	env.secret("HRIS_POSTGRES_MIGRATION_PASSWORD", &config.Postgres.MigrationPassword)
+
+	env.secret("HRIS_SESSION_AUTH_KEY", &config.Session.AuthKey)
+	env.secret("HRIS_SESSION_ENCRYPTION_KEY", &config.Session.EncryptionKey)
+	env.bool("HRIS_SESSION_SECURE", &config.Session.Secure)
+	env.string("HRIS_SESSION_SAME_SITE", &config.Session.SameSite)
+	env.list("HRIS_SESSION_TRUSTED_ORIGINS", &config.Session.TrustedOrigins)
+
+	env.int("HRIS_LOCKOUT_MAX_FAILED_ATTEMPTS", &config.Lockout.MaxFailedAttempts)
+	env.duration("HRIS_LOCKOUT_BASE_DURATION", &config.Lockout.BaseLockoutDuration)
+	env.duration("HRIS_LOCKOUT_MAX_DURATION", &config.Lockout.MaxLockoutDuration)
+
+	env.string("HRIS_S3_MODE", &config.S3.Mode)
+	env.string("HRIS_S3_REGION", &config.S3.Region)
+	env.string("HRIS_S3_ACCESS_KEY_ID", &config.S3.AccessKeyId)
+	env.secret("HRIS_S3_SECRET_ACCESS_KEY", &config.S3.SecretAccessKey)
+
+	env.string("HRIS_TRACING_EXPORTER", &config.Tracing.Exporter)
+	env.string("HRIS_TRACING_OTLP_ENDPOINT", &config.Tracing.OtlpEndpoint)
+	env.float("HRIS_TRACING_SAMPLE_RATIO", &config.Tracing.SampleRatio)
+
+	return errors.Join(env.errs...)
+}
+
+// Collects the errors of every invalid setting, so that they can all be fixed at once
+type validation struct {
+	errs []error
+}
+
+func (v *validation) check(ok bool, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf(format, args...))
+	}
+}
+
+// Returns an error listing every invalid setting of the server
+func (config Config) Validate() error {
+	v := &validation{}
+	check := v.check
+
+	config.validateMigration(v)
+
+	check(config.ListenAddress != "", "listenAddress is required")
+	check(config.MetricsListenAddress != "", "metricsListenAddress is required")
+	check(config.MetricsListenAddress != config.ListenAddress, "metricsListenAddress must differ from listenAddress")
+	check(config.ShutdownTimeout > 0, "shutdownTimeout must be positive")
+
+	check(config.Postgres.User != "", "postgres.user is required")
+	check(config.Postgres.Password != "", "postgres.password is required")
+
+	// The keys are used by securecookie to sign & encrypt (AES) the session cookie
+	check(len(config.Session.AuthKey) >= 32, "session.authKey must be at least 32 bytes")
+	switch len(config.Session.EncryptionKey) {
+	case 16, 24, 32:
+	default:
+		check(false, "session.encryptionKey must be 16, 24 or 32 bytes")
+	}
+	sameSite := strings.ToLower(config.Session.SameSite)
+	check(sameSite == "lax" || sameSite == "strict" || sameSite == "none", "session.sameSite must be lax, strict or none")
+	check(sameSite != "none" || config.Session.Secure, "session.secure must be enabled if session.sameSite is none")
+	for _, origin := range config.Session.TrustedOrigins {
+		originUrl, err := url.Parse(origin)
+		check(err == nil && originUrl.Scheme != "" && originUrl.Host != "" && strings.TrimSuffix(originUrl.Path, "/") == "",
+			"session.trustedOrigins must only contain origins (e.g. https://hr.example.com), got %q", origin)
+	}
+
+	check(config.Lockout.MaxFailedAttempts >= 0, "lockout.maxFailedAttempts must not be negative")
+	check(config.Lockout.BaseLockoutDuration <= config.Lockout.MaxLockoutDuration, "lockout.baseLockoutDuration must not exceed lockout.maxLockoutDuration")
+
+	check(config.S3.Mode == S3ModeFake || config.S3.Mode == S3ModeAws, "s3.mode must be %s or %s", S3ModeFake, S3ModeAws)
+	check(config.S3.Mode != S3ModeAws || config.S3.Region != "", "s3.region is required in aws mode")
+	check((config.S3.AccessKeyId == "") == (config.S3.SecretAccessKey == ""), "s3.accessKeyId & s3.secretAccessKey must be set together")
+
+	switch config.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOtlp:
+		endpointUrl, err := url.Parse(config.Tracing.OtlpEndpoint)
+		check(err == nil && (endpointUrl.Scheme == "http" || endpointUrl.Scheme == "https") && endpointUrl.Host != "",
+			"tracing.otlpEndpoint must be a http or https URL (e.g. http://localhost:4318), got %q", config.Tracing.OtlpEndpoint)
+	default:
+		check(false, "tracing.exporter must be %s, %s or %s", TracingExporterNone, TracingExporterOtlp, TracingExporterStdout)
+	}
+	check(config.Tracing.SampleRatio >= 0 && config.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 & 1")
+
+	return errors.Join(v.errs...)
+}
+
+// Returns an error listing every invalid setting of the migrate subcommand
+// Migrations only need the log output & the schema owner's connection, so the api user's & the server's settings are not required
+func (config Config) ValidateMigration() error {
+	v := &validation{}
+	config.validateMigration(v)
+	return errors.Join(v.errs...)
+}
+
+func (config Config) validateMigration(v *validation) {
+	check := v.check
+
+	check(config.LogOutput != "", "logOutput is required")
+
+	check(config.Postgres.Host != "", "postgres.host is required")
+	check(config.Postgres.Port > 0 && config.Postgres.Port <= 65535, "postgres.port must be between 1 & 65535")
+	check(config.Postgres.Database != "", "postgres.database is required")
+	check(config.Postgres.MigrationUser != "", "postgres.migrationUser is required")
+}
+
+// Connection string for the api user, used by the storage & session store
+func (postgres Postgres) ConnString() string {
+	return postgres.connString(postgres.User, postgres.Password)
+}
+
+// Connection string for the schema owner, used to run migrations
+func (postgres Postgres) MigrationConnString() string {
+	return postgres.connString(postgres.MigrationUser, postgres.MigrationPassword)
+}
+
+func (postgres Postgres) connString(user string, password Secret) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", quoteConnValue(postgres.Host), postgres.Port,
+		quoteConnValue(user), quoteConnValue(string(password)), quoteConnValue(postgres.Database), quoteConnValue(postgres.SslMode))
+}
+
+// The URL form of ConnString, used by the casbin adapter (go-pg)
+func (postgres Postgres) Url() string {
+	connUrl := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(postgres.User, string(postgres.Password)),
+		Host:     fmt.Sprintf("%s:%d", postgres.Host, postgres.Port),
+		Path:     postgres.Database,
+		RawQuery: url.Values{"sslmode": {postgres.SslMode}}.Encode(),
+	}
+	return connUrl.String()
+}
+
+// Values in a key=value connection string must be quoted if they are empty or contain spaces or quotes
+func quoteConnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+func (session Session) SameSiteMode() http.SameSite {
+	switch strings.ToLower(session.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// Collects every invalid environment variable instead of stopping at the first
+type envParser struct {
+	lookupEnv func(string) (string, bool)
+	errs      []error
+}
+
+func (env *envParser) string(name string, target *string) {
+	if value, ok := env.lookupEnv(name); ok {
+		*target = value
+	}
+}
+
+func (env *envParser) secret(name string, target *Secret) {
+	if value, ok := env.lookupEnv(name); ok {
+		*target = Secret(value)
+	}
+}
+
+func (env *envParser) list(name string, target *[]string) {
+	if value, ok := env.lookupEnv(name); ok {
+		*target = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*target = append(*target, item)
+			}
+		}
+	}
+}
+
+func (env *envParser) int(name string, target *int) {
+	if value, ok := env.lookupEnv(name); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%s must be an integer", name))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (env *envParser) float(name string, target *float64) {
+	if value, ok := env.lookupEnv(name); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%s must be a number", name))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (env *envParser) bool(name string, target *bool) {
+	if value, ok := env.lookupEnv(name); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%s must be true or false", name))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (env *envParser) duration(name string, target *time.Duration) {
+	if value, ok := env.lookupEnv(name); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			env.errs = append(env.errs, fmt.Errorf("%s must be a duration (e.g. 1m)", name))
+			return
+		}
+		*target = parsed
+	}
+}
@@ -16,6 +16,16 @@ type Error struct {
 	Status  int
 	Message string
 	Code    string
+	Errors  []FieldError // The inputs that caused the error, if any. Lets clients show each message next to its form field
+	Args    []string     // The values in the message (e.g. the entity of a not found error), so that it can be translated by its Code
+}
+
+// A problem with a single input, e.g. a missing field or a column that must be unique
+// Field is the input's name in the request (e.g. tenantId or scopes[0]) & Tag is the check that failed (e.g. required or unique)
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
 }
 
 func (err *Error) Error() string {
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"multi-tenant-HR-information-system-backend/storage/postgres"
+)
+
+type migrationLogger interface {
+	Info(msg string, args ...any)
+	Fatal(msg string, args ...any)
+}
+
+const migrateUsage = "usage: migrate up [steps] | migrate down [steps] | migrate version"
+
+// Runs the migrate subcommand
+// "up" applies all pending migrations by default, whereas "down" only reverts the latest migration by default
+func runMigrateCommand(args []string, connString string, rootLogger migrationLogger) {
+	if len(args) == 0 || len(args) > 2 {
+		rootLogger.Fatal("MIGRATION-FAILED", "errorMessage", migrateUsage)
+	}
+
+	direction := args[0]
+	steps := 0
+	if direction == "down" {
+		steps = 1
+	}
+	if len(args) == 2 {
+		var err error
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			rootLogger.Fatal("MIGRATION-FAILED", "errorMessage", fmt.Sprintf("steps must be a positive integer, %s", migrateUsage))
+		}
+	}
+
+	db, err := sql.Open("postgres", connString)
+	if err != nil {
+		rootLogger.Fatal("DB-CONNECTION-FAILED", "errorMessage", fmt.Sprintf("Could not connect to database: %s", err))
+	}
+	defer db.Close()
+
+	var migrations []postgres.Migration
+	var event string
+	switch direction {
+	case "up":
+		migrations, err = postgres.MigrateUp(db, steps)
+		event = "MIGRATION-APPLIED"
+	case "down":
+		migrations, err = postgres.MigrateDown(db, steps)
+		event = "MIGRATION-REVERTED"
+	case "version":
+	default:
+		rootLogger.Fatal("MIGRATION-FAILED", "errorMessage", migrateUsage)
+	}
+
+	// Migrations that completed before a failure are still logged as they have been committed
+	for _, migration := range migrations {
+		rootLogger.Info(event, "version", migration.Version, "name", migration.Name)
+	}
+	if err != nil {
+		rootLogger.Fatal("MIGRATION-FAILED", "errorMessage", err.Error())
+	}
+
+	version, err := postgres.GetSchemaVersion(db)
+	if err != nil {
+		rootLogger.Fatal("MIGRATION-FAILED", "errorMessage", fmt.Sprintf("Could not fetch schema version: %s", err))
+	}
+	rootLogger.Info("SCHEMA-VERSION", "version", version)
+}
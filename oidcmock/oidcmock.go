@@ -0,0 +1,224 @@
+// A minimal OpenID Connect identity provider for testing SSO locally, without a real IdP
+// Every authorization request is approved immediately on behalf of a user with the configured claims
+package oidcmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const keyId = "oidcmock"
+
+type authorizationRequest struct {
+	clientId      string
+	redirectUri   string
+	nonce         string
+	codeChallenge string
+}
+
+type Server struct {
+	*httptest.Server
+	ClientId     string
+	ClientSecret string
+
+	mu       sync.Mutex
+	claims   map[string]any
+	requests map[string]authorizationRequest // Keyed by authorization code
+	key      *rsa.PrivateKey
+}
+
+// Starts an identity provider with a single client. Close it once it is no longer needed
+func NewServer(clientId string, clientSecret string) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		claims:       map[string]any{},
+		requests:     map[string]authorizationRequest{},
+		key:          key,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", server.handleDiscovery)
+	mux.HandleFunc("/authorize", server.handleAuthorize)
+	mux.HandleFunc("/token", server.handleToken)
+	mux.HandleFunc("/keys", server.handleKeys)
+	server.Server = httptest.NewServer(mux)
+
+	return server, nil
+}
+
+// Sets the claims (e.g. email) of the user who is signed in. "sub" defaults to the email if it is not set
+func (server *Server) SetClaims(claims map[string]any) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.claims = claims
+}
+
+func (server *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]any{
+		"issuer":                                server.URL,
+		"authorization_endpoint":                server.URL + "/authorize",
+		"token_endpoint":                        server.URL + "/token",
+		"jwks_uri":                              server.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// Redirects back to the client with an authorization code, as if the user had signed in
+func (server *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != server.ClientId || query.Get("response_type") != "code" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirectUri, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectUri.String() == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+	server.mu.Lock()
+	server.requests[code] = authorizationRequest{
+		clientId:      query.Get("client_id"),
+		redirectUri:   redirectUri.String(),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	server.mu.Unlock()
+
+	values := redirectUri.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectUri.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirectUri.String(), http.StatusFound)
+}
+
+// Exchanges an authorization code for a signed ID token. Codes can only be exchanged once
+func (server *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientId != server.ClientId || clientSecret != server.ClientSecret {
+		writeJson(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	server.mu.Lock()
+	code := r.PostForm.Get("code")
+	request, ok := server.requests[code]
+	delete(server.requests, code)
+	claims := map[string]any{}
+	for claim, value := range server.claims {
+		claims[claim] = value
+	}
+	server.mu.Unlock()
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != request.redirectUri {
+		writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	// PKCE: the verifier must hash to the challenge sent in the authorization request
+	if request.codeChallenge != "" {
+		hash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(hash[:]) != request.codeChallenge {
+			writeJson(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	}
+
+	now := time.Now()
+	claims["iss"] = server.URL
+	claims["aud"] = request.clientId
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(5 * time.Minute).Unix()
+	if request.nonce != "" {
+		claims["nonce"] = request.nonce
+	}
+	if _, ok := claims["sub"]; !ok {
+		claims["sub"] = claims["email"]
+	}
+
+	idToken, err := server.sign(claims)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (server *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{Key: &server.key.PublicKey, KeyID: keyId, Algorithm: string(jose.RS256), Use: "sig"},
+		},
+	})
+}
+
+func (server *Server) sign(claims map[string]any) (string, error) {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: server.key, KeyID: keyId}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return signature.CompactSerialize()
+}
+
+func randomString() string {
+	randomBytes := make([]byte, 16)
+	rand.Read(randomBytes)
+	return base64.RawURLEncoding.EncodeToString(randomBytes)
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,297 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Tokens are formatted as hris_<tenantId>_<secret>, so that the tenant can be determined before the token is looked up
+const apiTokenPrefix = "hris_"
+
+// A token with the read scope can make GET requests, & a token with the write scope can make all other requests
+// Scopes only narrow what a token can do. The token's owner must still be authorised by casbin
+const (
+	apiTokenScopeRead  = "read"
+	apiTokenScopeWrite = "write"
+)
+
+// Only the hash of a token is stored. A fast hash is sufficient because the token is too random to be brute-forced
+func hashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func generateApiToken(tenantId string) (string, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", httperror.NewInternalServerError(err)
+	}
+
+	return apiTokenPrefix + tenantId + "_" + base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
+
+// Returns the user that owns the bearer token in the Authorization header. Service accounts are returned as a user with their id
+// Returns ErrUserUnauthenticated if the token is invalid or has expired
+func authenticateApiToken(r *http.Request, store storage.Storage, authorizationHeader string) (storage.User, storage.ApiToken, error) {
+	reqLogger := getRequestLogger(r)
+
+	scheme, token, _ := strings.Cut(authorizationHeader, " ")
+	tenantId, _, _ := strings.Cut(strings.TrimPrefix(token, apiTokenPrefix), "_")
+	if !strings.EqualFold(scheme, "Bearer") || !strings.HasPrefix(token, apiTokenPrefix) || uuid.Validate(tenantId) != nil {
+		reqLogger.Warn("INVALID-API-TOKEN-USED", "reason", "MALFORMED-TOKEN")
+		return storage.User{}, storage.ApiToken{}, ErrUserUnauthenticated
+	}
+
+	apiTokens, err := store.GetApiTokens(storage.ApiToken{TenantId: tenantId, TokenHash: hashApiToken(token)})
+	if err != nil {
+		return storage.User{}, storage.ApiToken{}, err
+	}
+	if len(apiTokens) == 0 {
+		reqLogger.Warn("INVALID-API-TOKEN-USED", "reason", "UNKNOWN-TOKEN", "tenantId", tenantId)
+		return storage.User{}, storage.ApiToken{}, ErrUserUnauthenticated
+	}
+
+	apiToken := apiTokens[0]
+	if !apiToken.ExpiresAt.IsZero() && apiToken.ExpiresAt.Before(time.Now()) {
+		reqLogger.Warn("INVALID-API-TOKEN-USED", "reason", "EXPIRED-TOKEN", "tenantId", tenantId, "apiTokenId", apiToken.Id)
+		return storage.User{}, storage.ApiToken{}, ErrUserUnauthenticated
+	}
+
+	if apiToken.ServiceAccountId != "" {
+		return storage.User{Id: apiToken.ServiceAccountId, TenantId: apiToken.TenantId}, apiToken, nil
+	}
+
+	users, _, err := store.GetUsers(storage.User{TenantId: apiToken.TenantId, Id: apiToken.UserId}, storage.PageRequest{})
+	if err != nil {
+		return storage.User{}, storage.ApiToken{}, err
+	}
+	if len(users) == 0 {
+		return storage.User{}, storage.ApiToken{}, ErrUserUnauthenticated
+	}
+
+	user := storage.User{
+		Id:       users[0].Id,
+		TenantId: users[0].TenantId,
+		Email:    users[0].Email,
+	}
+	return user, apiToken, nil
+}
+
+// Returns the token that the request was authenticated with, if any
+func getApiToken(r *http.Request) (storage.ApiToken, bool) {
+	apiToken, ok := r.Context().Value(apiTokenKey).(storage.ApiToken)
+	return apiToken, ok
+}
+
+func addApiTokenToContext(r *http.Request, apiToken storage.ApiToken) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), apiTokenKey, apiToken))
+}
+
+func apiTokenAllowsMethod(apiToken storage.ApiToken, method string) bool {
+	requiredScope := apiTokenScopeWrite
+	if method == http.MethodGet || method == http.MethodHead {
+		requiredScope = apiTokenScopeRead
+	}
+
+	for _, scope := range apiToken.Scopes {
+		if scope == requiredScope {
+			return true
+		}
+	}
+	return false
+}
+
+func (router *Router) handleCreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Name string
+	}
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+	vars := mux.Vars(r)
+
+	type Input struct {
+		Id       string `validate:"required,notBlank,uuid" name:"service account id"`
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Name     string `validate:"required,notBlank,max=100" name:"service account name"`
+	}
+	input := Input{
+		Id:       vars["serviceAccountId"],
+		TenantId: vars["tenantId"],
+		Name:     reqBody.Name,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	serviceAccount := storage.ServiceAccount{
+		Id:       input.Id,
+		TenantId: input.TenantId,
+		Name:     input.Name,
+	}
+	err = router.storageFor(r).CreateServiceAccount(serviceAccount)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("SERVICE-ACCOUNT-CREATED", "serviceAccountId", serviceAccount.Id, "tenantId", serviceAccount.TenantId)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Issues a token to a user (i.e. a personal token) or a service account, depending on the route
+// The token is only returned in this response
+func (router *Router) handleCreateApiToken(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Name      string
+		Scopes    []string
+		ExpiresAt *time.Time
+	}
+
+	type responseBody struct {
+		Token     string     `json:"token"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+	vars := mux.Vars(r)
+
+	type Input struct {
+		Id               string     `validate:"required,notBlank,uuid" name:"API token id"`
+		TenantId         string     `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId           string     `validate:"required_without=ServiceAccountId,omitempty,uuid" name:"user id"`
+		ServiceAccountId string     `validate:"required_without=UserId,omitempty,uuid" name:"service account id"`
+		Name             string     `validate:"required,notBlank,max=100" name:"API token name"`
+		Scopes           []string   `validate:"required,notBlank,dive,oneof=read write" name:"scopes"`
+		ExpiresAt        *time.Time `validate:"omitempty,gt" name:"expiry"`
+	}
+	input := Input{
+		Id:               vars["apiTokenId"],
+		TenantId:         vars["tenantId"],
+		UserId:           vars["userId"],
+		ServiceAccountId: vars["serviceAccountId"],
+		Name:             reqBody.Name,
+		Scopes:           reqBody.Scopes,
+		ExpiresAt:        reqBody.ExpiresAt,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// Tokens cannot be used to issue other tokens, so a leaked token cannot be used to keep access after it is revoked
+	if _, ok := getApiToken(r); ok {
+		sendToErrorHandlingMiddleware(ErrUserUnauthorised, r)
+		return
+	}
+
+	token, err := generateApiToken(input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	apiToken := storage.ApiToken{
+		Id:               input.Id,
+		TenantId:         input.TenantId,
+		UserId:           input.UserId,
+		ServiceAccountId: input.ServiceAccountId,
+		Name:             input.Name,
+		TokenHash:        hashApiToken(token),
+		Scopes:           input.Scopes,
+	}
+	if input.ExpiresAt != nil {
+		apiToken.ExpiresAt = *input.ExpiresAt
+	}
+	err = router.storageFor(r).CreateApiToken(apiToken)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("API-TOKEN-CREATED", "apiTokenId", apiToken.Id, "tenantId", apiToken.TenantId, "ownerUserId", apiToken.UserId,
+		"ownerServiceAccountId", apiToken.ServiceAccountId, "scopes", apiToken.Scopes, "createdBy", getAuthenticatedUser(r).Id)
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(responseBody{
+		Token:     token,
+		ExpiresAt: input.ExpiresAt,
+	})
+}
+
+// Revokes a user's or service account's token, depending on the route. The token cannot be used from the next request onwards
+func (router *Router) handleRevokeApiToken(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		Id               string `validate:"required,notBlank,uuid" name:"API token id"`
+		TenantId         string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId           string `validate:"required_without=ServiceAccountId,omitempty,uuid" name:"user id"`
+		ServiceAccountId string `validate:"required_without=UserId,omitempty,uuid" name:"service account id"`
+	}
+	input := Input{
+		Id:               vars["apiTokenId"],
+		TenantId:         vars["tenantId"],
+		UserId:           vars["userId"],
+		ServiceAccountId: vars["serviceAccountId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// The filter includes the owner, so that a token cannot be revoked through another user's route
+	filter := storage.ApiToken{
+		Id:               input.Id,
+		TenantId:         input.TenantId,
+		UserId:           input.UserId,
+		ServiceAccountId: input.ServiceAccountId,
+	}
+	deleted, err := router.storageFor(r).DeleteApiTokens(filter)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if deleted == 0 {
+		sendToErrorHandlingMiddleware(Err404NotFound, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("API-TOKEN-REVOKED", "apiTokenId", input.Id, "tenantId", input.TenantId, "revokedBy", getAuthenticatedUser(r).Id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+const testServiceAccountId = "6b1c1b8e-3f63-4c1c-9a3b-0f5d0c7f4f10"
+const testApiTokenId = "0d3c5b9a-1e2f-4a5b-8c7d-6e5f4a3b2c1d"
+
+// Creates a service account with the root role admin's rights
+func (s *IntegrationTestSuite) createTestServiceAccount() {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]string{"Name": "Payroll sync"})
+
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/service-accounts/%s", s.defaultTenant.Id, testServiceAccountId), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 201)
+
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, 'ROOT_ROLE_ADMIN', $2)"
+	_, err = s.dbRootConn.Exec(query, testServiceAccountId, s.defaultTenant.Id)
+	if err != nil {
+		log.Fatalf("Could not give the service account admin rights: %s", err)
+	}
+	err = s.router.authEnforcer.LoadPolicy()
+	if err != nil {
+		log.Fatalf("Could not reload enforcer to give the service account admin rights: %s", err)
+	}
+}
+
+// Issues a token through the given owner's route (e.g. /users/{userId}) as the default user & returns the token
+func (s *IntegrationTestSuite) createTestApiToken(ownerPath string, reqBody map[string]any) string {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/%s/api-tokens/%s", s.defaultTenant.Id, ownerPath, testApiTokenId), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 201)
+
+	var resBody struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(w.Body).Decode(&resBody)
+
+	return resBody.Token
+}
+
+func (s *IntegrationTestSuite) newApiTokenRequest(method string, path string, token string) *http.Request {
+	r, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	return r
+}
+
+func (s *IntegrationTestSuite) TestServiceAccountApiToken() {
+	s.createTestServiceAccount()
+	token := s.createTestApiToken("service-accounts/"+testServiceAccountId, map[string]any{"Name": "Nightly export", "Scopes": []string{"read"}})
+
+	s.expectSelectQueryToReturnOneRow("api_token", map[string]any{"id": testApiTokenId, "service_account_id": testServiceAccountId,
+		"user_account_id": "", "expires_at": ""})
+	s.expectSelectQueryToReturnNoRows("api_token", map[string]any{"token_hash": token})
+
+	// The service account is authorised by casbin in the same way as a user
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newApiTokenRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), token))
+	s.expectHttpStatus(w, 200)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`, fmt.Sprintf(`"userId":"%s"`, testServiceAccountId),
+		fmt.Sprintf(`"apiTokenId":"%s"`, testApiTokenId))
+
+	// The token only has the read scope
+	s.logOutput.Reset()
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newApiTokenRequest("DELETE", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), token))
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "USER-UNAUTHORISED")
+
+	reader = bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"API-TOKEN-SCOPE-INSUFFICIENT"`, `"method":"DELETE"`)
+}
+
+func (s *IntegrationTestSuite) TestPersonalApiToken() {
+	expiresAt := time.Now().Add(time.Hour)
+	token := s.createTestApiToken("users/"+s.defaultUser.Id, map[string]any{"Name": "BI export", "Scopes": []string{"read", "write"},
+		"ExpiresAt": expiresAt})
+
+	s.expectSelectQueryToReturnOneRow("api_token", map[string]any{"id": testApiTokenId, "user_account_id": s.defaultUser.Id, "service_account_id": ""})
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newApiTokenRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), token))
+	s.expectHttpStatus(w, 200)
+
+	// Tokens cannot be used to issue other tokens
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]any{"Name": "Another token", "Scopes": []string{"read"}})
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/users/%s/api-tokens/%s", s.defaultTenant.Id, s.defaultUser.Id,
+		"c7a0a3f2-5d0e-4a0a-8a3e-2d7b6f2b7c11"), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "USER-UNAUTHORISED")
+	s.expectSelectQueryToReturnNoRows("api_token", map[string]any{"id": "c7a0a3f2-5d0e-4a0a-8a3e-2d7b6f2b7c11"})
+}
+
+func (s *IntegrationTestSuite) TestApiTokenShouldBeValid() {
+	token := s.createTestApiToken("users/"+s.defaultUser.Id, map[string]any{"Name": "BI export", "Scopes": []string{"read"}})
+
+	expire := func() {
+		_, err := s.dbRootConn.Exec("UPDATE api_token SET expires_at = now() - interval '1 minute' WHERE id = $1", testApiTokenId)
+		if err != nil {
+			log.Fatalf("Could not expire the API token: %s", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		setUp      func()
+		wantReason string
+	}{
+		{"Should fail because the token is malformed", "not-a-token", func() {}, "MALFORMED-TOKEN"},
+		{"Should fail because the token does not exist", token + "x", func() {}, "UNKNOWN-TOKEN"},
+		{"Should fail because the token has expired", token, expire, "EXPIRED-TOKEN"},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			test.setUp()
+
+			s.logOutput.Reset()
+			w := httptest.NewRecorder()
+			path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id)
+			s.router.ServeHTTP(w, s.newApiTokenRequest("GET", path, test.token))
+
+			s.expectHttpStatus(w, 401)
+			s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+			reader := bufio.NewReader(s.logOutput)
+			s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"INVALID-API-TOKEN-USED"`, fmt.Sprintf(`"reason":"%s"`, test.wantReason))
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestRevokeApiToken() {
+	s.createTestServiceAccount()
+	token := s.createTestApiToken("service-accounts/"+testServiceAccountId, map[string]any{"Name": "Nightly export", "Scopes": []string{"read"}})
+
+	// The token cannot be revoked through another owner's route
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/api-tokens/%s", s.defaultTenant.Id, s.defaultUser.Id, testApiTokenId)
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 404)
+
+	path = fmt.Sprintf("/api/tenants/%s/service-accounts/%s/api-tokens/%s", s.defaultTenant.Id, testServiceAccountId, testApiTokenId)
+	r, err = http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 204)
+	s.expectSelectQueryToReturnNoRows("api_token", map[string]any{"id": testApiTokenId})
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newApiTokenRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), token))
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+}
+
+func (s *IntegrationTestSuite) TestCreateApiTokenShouldValidateInput() {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]any{"Name": "BI export", "Scopes": []string{"admin"},
+		"ExpiresAt": time.Now().Add(-time.Hour)})
+
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/users/%s/api-tokens/%s", s.defaultTenant.Id, s.defaultUser.Id, testApiTokenId), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INPUT-VALIDATION-ERROR")
+	s.expectSelectQueryToReturnNoRows("api_token", map[string]any{"id": testApiTokenId})
+}
@@ -0,0 +1,179 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/gorilla/mux"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+)
+
+type policyResponseBody struct {
+	Subject  string `json:"subject"`
+	TenantId string `json:"tenantId"`
+	Path     string `json:"path"`
+	Method   string `json:"method"`
+}
+
+// Explains why a subject is (not) allowed to access a resource, without making the request. This way, the rule that is
+// missing when a user is unauthorised can be found
+func (router *Router) handleExplainAuthorizationDecision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Subject  string `validate:"required,notBlank" name:"subject"`
+		Path     string `validate:"required,notBlank" name:"resource path"`
+		Method   string `validate:"required,notBlank,oneof=POST GET PUT DELETE" name:"resource method"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		Subject:  query.Get("subject"),
+		Path:     query.Get("path"),
+		Method:   query.Get("method"),
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// The matched policy is returned in the order of the model's policy definition, i.e. subject, domain, path & method
+	authorized, explanation, err := router.authEnforcer.EnforceEx(input.Subject, input.TenantId, input.Path, input.Method)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	type responseBody struct {
+		Subject    string              `json:"subject"`
+		TenantId   string              `json:"tenantId"`
+		Path       string              `json:"path"`
+		Method     string              `json:"method"`
+		Authorized bool                `json:"authorized"`
+		Policy     *policyResponseBody `json:"policy"`
+		RoleChain  []string            `json:"roleChain"`
+	}
+	resBody := responseBody{
+		Subject:    input.Subject,
+		TenantId:   input.TenantId,
+		Path:       input.Path,
+		Method:     input.Method,
+		Authorized: authorized,
+		RoleChain:  []string{},
+	}
+	if authorized && len(explanation) == 4 {
+		resBody.Policy = &policyResponseBody{explanation[0], explanation[1], explanation[2], explanation[3]}
+
+		resBody.RoleChain, err = findRoleChain(router.authEnforcer, input.Subject, explanation[0], input.TenantId)
+		if err != nil {
+			sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+			return
+		}
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("AUTHORIZATION-DECISION-EXPLAINED", "tenantId", input.TenantId, "subject", input.Subject, "resource", input.Path, "method", input.Method, "authorized", authorized)
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resBody)
+}
+
+// Returns the roles that lead from the subject to the role, e.g. [userId ROLE_A ROLE_B] if the user has ROLE_A, which
+// inherits ROLE_B. The chain is the shortest one, as the roles are searched breadth-first
+func findRoleChain(authEnforcer casbin.IEnforcer, subject string, role string, tenantId string) ([]string, error) {
+	roleManager := authEnforcer.GetRoleManager()
+
+	inheritedFrom := map[string]string{subject: ""}
+	queue := []string{subject}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		if name == role {
+			chain := []string{}
+			for ; name != ""; name = inheritedFrom[name] {
+				chain = append([]string{name}, chain...)
+			}
+			return chain, nil
+		}
+
+		roles, err := roleManager.GetRoles(name, tenantId)
+		if err != nil {
+			return nil, err
+		}
+		for _, inheritedRole := range roles {
+			if _, ok := inheritedFrom[inheritedRole]; !ok {
+				inheritedFrom[inheritedRole] = name
+				queue = append(queue, inheritedRole)
+			}
+		}
+	}
+
+	return []string{}, nil
+}
+
+// Lists every resource that the user can access in the tenant, including the resources of the roles that their roles
+// inherit
+func (router *Router) handleGetUserPermissions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		UserId   string `validate:"required,notBlank,uuid" name:"user id"`
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+	}
+	input := Input{
+		UserId:   vars["userId"],
+		TenantId: vars["tenantId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// Includes PUBLIC, which every user is assigned to in every tenant
+	roles, err := router.authEnforcer.GetImplicitRolesForUser(input.UserId, input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	type responseBody struct {
+		Roles       []string             `json:"roles"`
+		Permissions []policyResponseBody `json:"permissions"`
+	}
+	resBody := responseBody{
+		Roles:       []string{},
+		Permissions: []policyResponseBody{},
+	}
+	resBody.Roles = append(resBody.Roles, roles...)
+
+	// GetImplicitPermissionsForUser only returns the policies of the exact tenant, whereas the model also applies policies
+	// whose domain matches the tenant (e.g. the PUBLIC policies of the * domain), so the policies are matched the same way
+	listed := map[policyResponseBody]bool{}
+	for _, subject := range append([]string{input.UserId}, roles...) {
+		for _, policy := range router.authEnforcer.GetFilteredPolicy(0, subject) {
+			permission := policyResponseBody{policy[0], policy[1], policy[2], policy[3]}
+			if !util.KeyMatch(input.TenantId, permission.TenantId) || listed[permission] {
+				continue
+			}
+			listed[permission] = true
+			resBody.Permissions = append(resBody.Permissions, permission)
+		}
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("USER-PERMISSIONS-RETRIEVED", "tenantId", input.TenantId, "userId", input.UserId, "count", len(resBody.Permissions))
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resBody)
+}
@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/util"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+func TestFindRoleChain(t *testing.T) {
+	authEnforcer, err := casbin.NewEnforcer("../auth_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authEnforcer.AddNamedMatchingFunc("g", "KeyMatch2", util.KeyMatch2)
+	authEnforcer.AddNamedDomainMatchingFunc("g", "KeyMatch2", util.KeyMatch2)
+
+	authEnforcer.AddGroupingPolicies([][]string{
+		{"*", "PUBLIC", "*"},
+		{"user", "TENANT_ROLE_ADMIN", "tenant"},
+		{"TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR", "tenant"},
+		{"user", "TENANT_ROLE_AUDITOR", "otherTenant"},
+		{"TENANT_ROLE_AUDITOR", "TENANT_ROLE_VIEWER", "tenant"},
+	})
+
+	tests := []struct {
+		role     string
+		tenantId string
+		want     []string
+	}{
+		{"user", "tenant", []string{"user"}},
+		{"TENANT_ROLE_ADMIN", "tenant", []string{"user", "TENANT_ROLE_ADMIN"}},
+		{"TENANT_ROLE_VIEWER", "tenant", []string{"user", "TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR", "TENANT_ROLE_VIEWER"}},
+		{"PUBLIC", "tenant", []string{"user", "PUBLIC"}},
+		{"TENANT_ROLE_AUDITOR", "otherTenant", []string{"user", "TENANT_ROLE_AUDITOR"}},
+		{"TENANT_ROLE_ADMIN", "otherTenant", []string{}},
+	}
+
+	for _, test := range tests {
+		got, err := findRoleChain(authEnforcer, "user", test.role, test.tenantId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("findRoleChain(%s, %s) = %v, want %v", test.role, test.tenantId, got, test.want)
+		}
+	}
+}
+
+func (s *IntegrationTestSuite) TestExplainAuthorizationDecision() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, $2, $3)"
+	_, err := s.dbRootConn.Exec(query, "TENANT_ROLE_HR", seedPolicies.Subject, s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = s.dbRootConn.Exec(query, s.defaultSupervisor.Id, "TENANT_ROLE_HR", s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = s.router.authEnforcer.LoadPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	userPath := fmt.Sprintf("/api/tenants/%s/users/%s", s.defaultTenant.Id, s.defaultSupervisor.Id)
+	tests := []struct {
+		name           string
+		subject        string
+		path           string
+		method         string
+		wantStatus     int
+		wantAuthorized bool
+		wantPolicy     *policyResponseBody
+		wantRoleChain  []string
+	}{
+		{"Should be authorised by an inherited role", s.defaultSupervisor.Id, userPath, "POST", 200, true,
+			&policyResponseBody{seedPolicies.Subject, s.defaultTenant.Id, "/api/tenants/{tenantId}/users/{userId}", "POST"},
+			[]string{s.defaultSupervisor.Id, "TENANT_ROLE_HR", seedPolicies.Subject}},
+		{"Should be authorised by a public policy", s.defaultSupervisor.Id, "/api/session", "POST", 200, true,
+			&policyResponseBody{"PUBLIC", "*", "/api/session", "POST"},
+			[]string{s.defaultSupervisor.Id, "PUBLIC"}},
+		{"Should be unauthorised without a matching policy", s.defaultSupervisor.Id, userPath, "DELETE", 200, false, nil, []string{}},
+		{"Should be invalid because the method is not supported", s.defaultSupervisor.Id, userPath, "PATCH", 400, false, nil, nil},
+		{"Should be invalid because the subject was not provided", "", userPath, "POST", 400, false, nil, nil},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			query := url.Values{"subject": {test.subject}, "path": {test.path}, "method": {test.method}}
+			path := fmt.Sprintf("/api/tenants/%s/authorization-decision?%s", s.defaultTenant.Id, query.Encode())
+			r, err := http.NewRequest("GET", path, nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			s.logOutput.Reset()
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, test.wantStatus)
+			if test.wantStatus != 200 {
+				s.expectErrorCode(w, "INPUT-VALIDATION-ERROR")
+				return
+			}
+
+			var resBody struct {
+				Authorized bool
+				Policy     *policyResponseBody
+				RoleChain  []string
+			}
+			json.NewDecoder(w.Body).Decode(&resBody)
+			s.Equal(test.wantAuthorized, resBody.Authorized)
+			s.Equal(test.wantPolicy, resBody.Policy)
+			s.Equal(test.wantRoleChain, resBody.RoleChain)
+
+			reader := bufio.NewReader(s.logOutput)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-DECISION-EXPLAINED"`, fmt.Sprintf(`"authorized":%t`, test.wantAuthorized))
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestGetUserPermissions() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, $2, $3)"
+	_, err := s.dbRootConn.Exec(query, "TENANT_ROLE_HR", seedPolicies.Subject, s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = s.dbRootConn.Exec(query, s.defaultSupervisor.Id, "TENANT_ROLE_HR", s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = s.router.authEnforcer.LoadPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/permissions", s.defaultTenant.Id, s.defaultSupervisor.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody struct {
+		Roles       []string
+		Permissions []policyResponseBody
+	}
+	json.NewDecoder(w.Body).Decode(&resBody)
+	s.ElementsMatch([]string{"TENANT_ROLE_HR", "PUBLIC", seedPolicies.Subject}, resBody.Roles)
+	s.Contains(resBody.Permissions, policyResponseBody{seedPolicies.Subject, s.defaultTenant.Id, "/api/tenants/{tenantId}/users/{userId}", "POST"})
+	// Policies of the * domain apply to every tenant
+	s.Contains(resBody.Permissions, policyResponseBody{"PUBLIC", "*", "/api/session", "POST"})
+	for _, permission := range resBody.Permissions {
+		s.NotEqual(s.defaultRoleAssignment.Role, permission.Subject, "The permissions of roles that the user does not have should not be listed")
+	}
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-PERMISSIONS-RETRIEVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
@@ -0,0 +1,67 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// A dependency that must be reachable for the server to handle requests, e.g. the database
+type ReadinessCheck func(ctx context.Context) error
+
+// Time that all readiness checks have to complete, so that a hanging dependency does not hang the probe
+const readinessTimeout = 3 * time.Second
+
+// Adds a dependency to /readyz. The storage & file storage are always checked
+func (router *Router) AddReadinessCheck(name string, check ReadinessCheck) {
+	router.readinessChecks[name] = check
+}
+
+// Liveness probe. Dependencies are not checked, as restarting the server would not fix an unreachable dependency
+func (router *Router) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Readiness probe. Returns 503 if any dependency cannot be reached, so that the load balancer stops sending requests to the server
+// The reason for a failure is only logged, as the endpoint is public
+func (router *Router) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	type responseBody struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(router.readinessChecks))
+	for name := range router.readinessChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reqLogger := getRequestLogger(r)
+	resBody := responseBody{Status: "ok", Checks: map[string]string{}}
+	for _, name := range names {
+		err := router.readinessChecks[name](ctx)
+		if err != nil {
+			reqLogger.Warn("READINESS-CHECK-FAILED", "check", name, "errorMessage", err.Error())
+			resBody.Checks[name] = "unavailable"
+			resBody.Status = "unavailable"
+		} else {
+			resBody.Checks[name] = "ok"
+		}
+	}
+
+	status := http.StatusOK
+	if resBody.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resBody)
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Probes must not require a session or a casbin policy
+func (s *IntegrationTestSuite) TestHealthz() {
+	r, err := http.NewRequest("GET", "/healthz", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+}
+
+func (s *IntegrationTestSuite) TestReadyz() {
+	r, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody struct {
+		Status string
+		Checks map[string]string
+	}
+	json.NewDecoder(w.Body).Decode(&resBody)
+	s.Equal("ok", resBody.Status)
+	s.Equal(map[string]string{"postgres": "ok", "fileStorage": "ok", "authorizationAdapter": "ok"}, resBody.Checks)
+}
+
+func (s *IntegrationTestSuite) TestReadyzShouldFailIfDependencyIsUnavailable() {
+	s.router.AddReadinessCheck("unavailable", func(ctx context.Context) error { return errors.New("connection refused") })
+	defer delete(s.router.readinessChecks, "unavailable")
+
+	r, err := http.NewRequest("GET", "/readyz", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 503)
+
+	var resBody struct {
+		Status string
+		Checks map[string]string
+	}
+	json.NewDecoder(w.Body).Decode(&resBody)
+	s.Equal("unavailable", resBody.Status)
+	s.Equal("unavailable", resBody.Checks["unavailable"])
+	s.Equal("ok", resBody.Checks["postgres"])
+	s.NotContains(w.Body.String(), "connection refused", "The reason should only be logged")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"READINESS-CHECK-FAILED"`, `"check":"unavailable"`, `"errorMessage":"connection refused"`)
+}
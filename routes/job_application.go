@@ -94,7 +94,7 @@ func (router *Router) handleCreateJobApplication(w http.ResponseWriter, r *http.
 		Id:        input.JobRequisitionId,
 		TenantId:  input.TenantId,
 	}
-	jobRequisitions, err := router.storage.GetJobRequisitions(jobReqfilter)
+	jobRequisitions, _, err := router.storageFor(r).GetJobRequisitions(jobReqfilter, storage.PageRequest{})
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -109,7 +109,7 @@ func (router *Router) handleCreateJobApplication(w http.ResponseWriter, r *http.
 		return
 	}
 
-	resumeS3Url, err := router.fileStorage.UploadResume(file, input.Id, input.FirstName, input.LastName, input.FileExtension)
+	resumeS3Url, err := router.fileStorageFor(r).UploadResume(file, input.Id, input.FirstName, input.LastName, input.FileExtension)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -129,7 +129,7 @@ func (router *Router) handleCreateJobApplication(w http.ResponseWriter, r *http.
 		Email:            input.Email,
 		ResumeS3Url:      resumeS3Url,
 	}
-	err = router.storage.CreateJobApplication(jobApplication)
+	err = router.storageFor(r).CreateJobApplication(jobApplication)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -181,7 +181,7 @@ func (router *Router) handleSetRecruiterDecision(w http.ResponseWriter, r *http.
 		TenantId:  input.TenantId,
 		Recruiter: input.Recruiter,
 	}
-	jobRequisitions, err := router.storage.GetJobRequisitions(jobReqfilter)
+	jobRequisitions, _, err := router.storageFor(r).GetJobRequisitions(jobReqfilter, storage.PageRequest{})
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -206,12 +206,14 @@ func (router *Router) handleSetRecruiterDecision(w http.ResponseWriter, r *http.
 		TenantId:         input.TenantId,
 		JobRequisitionId: input.JobRequisitionId,
 	}
-	err = router.storage.UpdateJobApplication(newValues, filter)
+	err = router.storageFor(r).UpdateJobApplication(newValues, filter)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
 
+	router.metrics.jobApplicationDecisions.WithLabelValues(input.TenantId, "recruiter", input.RecruiterDecision).Inc()
+
 	reqLogger := getRequestLogger(r)
 	if input.RecruiterDecision == "SHORTLISTED" {
 		reqLogger.Info("JOB-APPLICATION-RECRUITER-SHORTLISTED", "jobApplicationId", input.Id, "tenantId", input.TenantId, "recruiter", input.Recruiter)
@@ -262,7 +264,7 @@ func (router *Router) handleRecruiterSetInterviewDate(w http.ResponseWriter, r *
 		TenantId:  input.TenantId,
 		Recruiter: input.Recruiter,
 	}
-	jobRequisitions, err := router.storage.GetJobRequisitions(jobReqfilter)
+	jobRequisitions, _, err := router.storageFor(r).GetJobRequisitions(jobReqfilter, storage.PageRequest{})
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -287,7 +289,7 @@ func (router *Router) handleRecruiterSetInterviewDate(w http.ResponseWriter, r *
 		TenantId:         input.TenantId,
 		JobRequisitionId: input.JobRequisitionId,
 	}
-	err = router.storage.UpdateJobApplication(newValues, filter)
+	err = router.storageFor(r).UpdateJobApplication(newValues, filter)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -345,7 +347,7 @@ func (router *Router) handleSetHiringManagerDecision(w http.ResponseWriter, r *h
 		TenantId:  input.TenantId,
 		Requestor: input.Requestor,
 	}
-	jobRequisitions, err := router.storage.GetJobRequisitions(jobReqfilter)
+	jobRequisitions, _, err := router.storageFor(r).GetJobRequisitions(jobReqfilter, storage.PageRequest{})
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -372,12 +374,14 @@ func (router *Router) handleSetHiringManagerDecision(w http.ResponseWriter, r *h
 		TenantId:         input.TenantId,
 		JobRequisitionId: input.JobRequisitionId,
 	}
-	err = router.storage.UpdateJobApplication(newValues, filter)
+	err = router.storageFor(r).UpdateJobApplication(newValues, filter)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
 
+	router.metrics.jobApplicationDecisions.WithLabelValues(input.TenantId, "hiring_manager", input.HiringManagerDecision).Inc()
+
 	reqLogger := getRequestLogger(r)
 	if input.HiringManagerDecision == "OFFERED" {
 		reqLogger.Info("JOB-APPLICATION-HIRING-MANAGER-OFFERED", "jobApplicationId", input.Id, "tenantId", input.TenantId, "hiringManager", input.Requestor)
@@ -395,9 +399,9 @@ func (router *Router) handleSetApplicantDecision(w http.ResponseWriter, r *http.
 		ApplicantDecision string
 	}
 
+	// The new hire enrolls in TOTP themselves, so only the generated password is returned
 	type responseBody struct {
-		Password      string `json:"password"`
-		TotpSecretKey string `json:"totpSecretKey"`
+		Password string `json:"password"`
 	}
 
 	var reqBody requestBody
@@ -436,7 +440,7 @@ func (router *Router) handleSetApplicantDecision(w http.ResponseWriter, r *http.
 		TenantId:  input.TenantId,
 		Recruiter: input.Recruiter,
 	}
-	jobRequisitions, err := router.storage.GetJobRequisitions(jobReqfilter)
+	jobRequisitions, _, err := router.storageFor(r).GetJobRequisitions(jobReqfilter, storage.PageRequest{})
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -453,7 +457,7 @@ func (router *Router) handleSetApplicantDecision(w http.ResponseWriter, r *http.
 
 	if input.ApplicantDecision == "ACCEPTED" {
 		// Retrieve the tenant to get its name, which is used in the email domain
-		tenants, err := router.storage.GetTenants(storage.Tenant{Id: input.TenantId})
+		tenants, _, err := router.storageFor(r).GetTenants(storage.Tenant{Id: input.TenantId}, storage.PageRequest{})
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
@@ -466,7 +470,7 @@ func (router *Router) handleSetApplicantDecision(w http.ResponseWriter, r *http.
 			TenantId:         input.TenantId,
 			JobRequisitionId: input.JobRequisitionId,
 		}
-		jobApplications, err := router.storage.GetJobApplications(jobAppFilter)
+		jobApplications, _, err := router.storageFor(r).GetJobApplications(jobAppFilter, storage.PageRequest{})
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
@@ -480,34 +484,41 @@ func (router *Router) handleSetApplicantDecision(w http.ResponseWriter, r *http.
 		lastName := strings.ReplaceAll(strings.ToLower(jobApplications[0].LastName), " ", "_")
 		emailDomain := strings.ReplaceAll(strings.ToLower(tenants[0].Name), " ", "")
 		email := fmt.Sprintf("%s_%s@%s.com", firstName, lastName, emailDomain)
-		password, hashedPassword, totp_secret_key, err := generateDefaultCredentials(email)
+		password, hashedPassword, err := generateDefaultPassword()
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
 		}
 
 		newUser := storage.User{
-			Id:            uuid.New().String(),
-			TenantId:      input.TenantId,
-			Email:         email,
-			Password:      hashedPassword,
-			TotpSecretKey: totp_secret_key,
+			Id:                 uuid.New().String(),
+			TenantId:           input.TenantId,
+			Email:              email,
+			Password:           hashedPassword,
+			MustChangePassword: true,
 		}
 
-		err = router.storage.OnboardNewHire(jobApplications[0], newUser)
+		err = router.storageFor(r).OnboardNewHire(jobApplications[0], newUser, employeePolicyTemplate.instantiate(newUser.Id, newUser.TenantId))
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
 		}
 
+		router.metrics.jobApplicationDecisions.WithLabelValues(input.TenantId, "applicant", input.ApplicantDecision).Inc()
+
 		reqLogger := getRequestLogger(r)		
 		reqLogger.Info("JOB-APPLICATION-APPLICANT-ACCEPTED", "jobApplicationId", input.Id, "tenantId", input.TenantId, "recruiter", input.Recruiter)
 
+		err = router.reloadAuthorizationPolicy(r)
+		if err != nil {
+			sendToErrorHandlingMiddleware(err, r)
+			return
+		}
+
 		w.WriteHeader(http.StatusNoContent)
 		w.Header().Add("content-type", "application/json")
 		resBody := responseBody{
-			Password:      password,
-			TotpSecretKey: totp_secret_key,
+			Password: password,
 		}
 		json.NewEncoder(w).Encode(resBody)
 
@@ -522,12 +533,14 @@ func (router *Router) handleSetApplicantDecision(w http.ResponseWriter, r *http.
 			TenantId:         input.TenantId,
 			JobRequisitionId: input.JobRequisitionId,
 		}
-		err = router.storage.UpdateJobApplication(newValues, filter)
+		err = router.storageFor(r).UpdateJobApplication(newValues, filter)
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
 		}
 
+		router.metrics.jobApplicationDecisions.WithLabelValues(input.TenantId, "applicant", input.ApplicantDecision).Inc()
+
 		reqLogger := getRequestLogger(r)		
 		reqLogger.Info("JOB-APPLICATION-APPLICANT-REJECTED", "jobApplicationId", input.Id, "tenantId", input.TenantId, "recruiter", input.Recruiter)
 
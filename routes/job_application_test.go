@@ -13,6 +13,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
 	"multi-tenant-HR-information-system-backend/storage"
 	"multi-tenant-HR-information-system-backend/storage/s3mock"
 )
@@ -616,6 +618,9 @@ func (s *IntegrationTestSuite) TestRecruiterShortlistJobApplication() {
 	}
 	s.addSessionCookieToRequest(r, s.defaultRecruiter.Id, s.defaultRecruiter.TenantId, s.defaultRecruiter.Email)
 
+	shortlisted := s.router.metrics.jobApplicationDecisions.WithLabelValues(input.TenantId, "recruiter", "SHORTLISTED")
+	shortlistedBefore := testutil.ToFloat64(shortlisted)
+
 	w := httptest.NewRecorder()
 	s.router.ServeHTTP(w, r)
 
@@ -631,6 +636,8 @@ func (s *IntegrationTestSuite) TestRecruiterShortlistJobApplication() {
 		},
 	)
 
+	s.Equal(shortlistedBefore+1, testutil.ToFloat64(shortlisted), "The shortlist should have been counted")
+
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-APPLICATION-RECRUITER-SHORTLISTED"`)
@@ -1586,6 +1593,7 @@ func (s *IntegrationTestSuite) TestRecruiterSetApplicantAcceptance() {
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-APPLICATION-APPLICANT-ACCEPTED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"slices"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -40,7 +41,7 @@ func (router *Router) handleCreateJobRequisition(w http.ResponseWriter, r *http.
 		JobRequirements       string   `validate:"required,notBlank" name:"job requirements"`
 		Requestor             string   `validate:"required,notBlank,uuid" name:"requestor id"`
 		Supervisor            string   `validate:"required,notBlank,uuid" name:"supervisor id"`
-		HrApprover            string   `validate:"required,notBlank,uuid" name:"HR approver id"`
+		HrApprover            string   `validate:"required,notBlank,uuid,nefield=Requestor" name:"HR approver id"`
 	}
 	input := Input{
 		Id:              vars["jobRequisitionId"],
@@ -64,7 +65,7 @@ func (router *Router) handleCreateJobRequisition(w http.ResponseWriter, r *http.
 
 	// Verify that the supervisor provided is indeed the user's supervisor
 	user := getAuthenticatedUser(r)
-	supervisors, err := router.storage.GetUserSupervisors(user.Id, user.TenantId)
+	supervisors, err := router.storageFor(r).GetUserSupervisors(user.Id, user.TenantId)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -75,6 +76,18 @@ func (router *Router) handleCreateJobRequisition(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// Verify that the HR approver provided holds the HR approver role before granting them the HR approver policies
+	hrApproverRoles, err := router.authEnforcer.GetImplicitRolesForUser(input.HrApprover, input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	hrApproverIsValid := slices.Contains(hrApproverRoles, hrApproverRole)
+	if !hrApproverIsValid {
+		sendToErrorHandlingMiddleware(ErrInvalidHrApprover, r)
+		return
+	}
+
 	jobRequisition := storage.JobRequisition{
 		Id:              input.Id,
 		TenantId:        input.TenantId,
@@ -88,7 +101,8 @@ func (router *Router) handleCreateJobRequisition(w http.ResponseWriter, r *http.
 		Supervisor:      input.Supervisor,
 		HrApprover:      input.HrApprover,
 	}
-	err = router.storage.CreateJobRequisition(jobRequisition)
+	hrApproverPolicies := hrApproverPolicyTemplate.instantiate(input.HrApprover, input.TenantId)
+	err = router.storageFor(r).CreateJobRequisition(jobRequisition, hrApproverPolicies)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -97,6 +111,13 @@ func (router *Router) handleCreateJobRequisition(w http.ResponseWriter, r *http.
 	reqLogger := getRequestLogger(r)
 	reqLogger.Info("JOB-REQUISITION-CREATED", "jobRequisitionId", input.Id, "tenantId", input.TenantId)
 
+	// The HR approver can only see & approve the job requisition once their policies are loaded
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
 	// TODO: Notify approver by email
 
 	w.WriteHeader(http.StatusCreated)
@@ -142,7 +163,7 @@ func (router *Router) handleSupervisorApproveJobRequisition(w http.ResponseWrite
 	// Validate credentials. Credentials are revalidated because approval is akin to signing off on something
 	// This guards against the abuse of a logged in yet unattended computer
 	user := getAuthenticatedUser(r)
-	valid, err := router.validateCredentials(user.Email, user.TenantId, input.Password, input.Totp)
+	valid, err := router.validateCredentials(r, user.Email, user.TenantId, input.Password, input.Totp)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -154,7 +175,7 @@ func (router *Router) handleSupervisorApproveJobRequisition(w http.ResponseWrite
 
 	// Verify that the user is still the supervisor of the requestor.
 	// The user might have been fired/promoted/re-assigned since the job requisition's creation
-	jobRequisitions, err := router.storage.GetJobRequisitions(storage.JobRequisition{Id: input.Id, TenantId: input.TenantId})
+	jobRequisitions, _, err := router.storageFor(r).GetJobRequisitions(storage.JobRequisition{Id: input.Id, TenantId: input.TenantId}, storage.PageRequest{})
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -163,7 +184,7 @@ func (router *Router) handleSupervisorApproveJobRequisition(w http.ResponseWrite
 		sendToErrorHandlingMiddleware(Err404NotFound, r)
 		return
 	}
-	supervisors, err := router.storage.GetUserSupervisors(jobRequisitions[0].Requestor, jobRequisitions[0].TenantId)
+	supervisors, err := router.storageFor(r).GetUserSupervisors(jobRequisitions[0].Requestor, jobRequisitions[0].TenantId)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -182,12 +203,14 @@ func (router *Router) handleSupervisorApproveJobRequisition(w http.ResponseWrite
 		TenantId:   input.TenantId,
 		Supervisor: input.Supervisor,
 	}
-	err = router.storage.UpdateJobRequisition(newValues, filter)
+	err = router.storageFor(r).UpdateJobRequisition(newValues, filter)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
 
+	router.metrics.jobRequisitionDecisions.WithLabelValues(input.TenantId, "supervisor", input.SupervisorDecision).Inc()
+
 	reqLogger := getRequestLogger(r)
 	if input.SupervisorDecision == "APPROVED" {
 		reqLogger.Info("JOB-REQUISITION-SUPERVISOR-APPROVED", "jobRequisitionId", input.Id, "tenantId", input.TenantId, "supervisor", input.Supervisor)
@@ -243,7 +266,7 @@ func (router *Router) handleHrApproveJobRequisition(w http.ResponseWriter, r *ht
 	// Validate credentials. Credentials are revalidated because approval is akin to signing off on something
 	// This guards against the abuse of a logged in yet unattended computer
 	user := getAuthenticatedUser(r)
-	valid, err := router.validateCredentials(user.Email, user.TenantId, input.Password, input.Totp)
+	valid, err := router.validateCredentials(r, user.Email, user.TenantId, input.Password, input.Totp)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -254,7 +277,8 @@ func (router *Router) handleHrApproveJobRequisition(w http.ResponseWriter, r *ht
 	}
 
 	if input.HrApproverDecision == "APPROVED" {
-		err = router.storage.HrApproveJobRequisition(input.Id, input.TenantId, input.HrApprover, input.Recruiter)
+		recruiterPolicies := recruiterPolicyTemplate.instantiate(input.Recruiter, input.TenantId)
+		err = router.storageFor(r).HrApproveJobRequisition(input.Id, input.TenantId, input.HrApprover, input.Recruiter, recruiterPolicies)
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
@@ -269,16 +293,25 @@ func (router *Router) handleHrApproveJobRequisition(w http.ResponseWriter, r *ht
 			TenantId:   input.TenantId,
 			HrApprover: input.HrApprover,
 		}
-		err = router.storage.UpdateJobRequisition(newValues, filter)
+		err = router.storageFor(r).UpdateJobRequisition(newValues, filter)
 		if err != nil {
 			sendToErrorHandlingMiddleware(err, r)
 			return
 		}
 	}
 
+	router.metrics.jobRequisitionDecisions.WithLabelValues(input.TenantId, "hr_approver", input.HrApproverDecision).Inc()
+
 	reqLogger := getRequestLogger(r)
 	if input.HrApproverDecision == "APPROVED" {
 		reqLogger.Info("JOB-REQUISITION-HR-APPROVED", "jobRequisitionId", input.Id, "tenantId", input.TenantId, "hrApprover", input.HrApprover)
+
+		// The recruiter can only work on the job requisition once their policies are loaded
+		err = router.reloadAuthorizationPolicy(r)
+		if err != nil {
+			sendToErrorHandlingMiddleware(err, r)
+			return
+		}
 	} else if input.HrApproverDecision == "REJECTED" {
 		reqLogger.Info("JOB-REQUISITION-HR-REJECTED", "jobRequisitionId", input.Id, "tenantId", input.TenantId, "hrApprover", input.HrApprover)
 	}
@@ -287,3 +320,166 @@ func (router *Router) handleHrApproveJobRequisition(w http.ResponseWriter, r *ht
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+type jobRequisitionResponseBody struct {
+	Id                    string   `json:"id"`
+	TenantId              string   `json:"tenantId"`
+	PositionId            string   `json:"positionId"`
+	Title                 string   `json:"title"`
+	DepartmentId          string   `json:"departmentId"`
+	SupervisorPositionIds []string `json:"supervisorPositionIds"`
+	JobDescription        string   `json:"jobDescription"`
+	JobRequirements       string   `json:"jobRequirements"`
+	Requestor             string   `json:"requestor"`
+	Supervisor            string   `json:"supervisor"`
+	SupervisorDecision    string   `json:"supervisorDecision"`
+	HrApprover            string   `json:"hrApprover"`
+	HrApproverDecision    string   `json:"hrApproverDecision"`
+	Recruiter             string   `json:"recruiter"`
+	FilledBy              string   `json:"filledBy"`
+	FilledAt              string   `json:"filledAt"`
+	CreatedAt             string   `json:"createdAt"`
+	UpdatedAt             string   `json:"updatedAt"`
+}
+
+// A single page of job requisitions. NextCursor is empty if there are no more pages
+type jobRequisitionsResponseBody struct {
+	JobRequisitions []jobRequisitionResponseBody `json:"jobRequisitions"`
+	NextCursor      string                       `json:"nextCursor"`
+}
+
+func newJobRequisitionResponseBody(jobRequisition storage.JobRequisition) jobRequisitionResponseBody {
+	filledAt := ""
+	if !jobRequisition.FilledAt.IsZero() {
+		filledAt = jobRequisition.FilledAt.Format(time.RFC3339)
+	}
+
+	return jobRequisitionResponseBody{
+		Id:                    jobRequisition.Id,
+		TenantId:              jobRequisition.TenantId,
+		PositionId:            jobRequisition.PositionId,
+		Title:                 jobRequisition.Title,
+		DepartmentId:          jobRequisition.DepartmentId,
+		SupervisorPositionIds: jobRequisition.SupervisorPositionIds,
+		JobDescription:        jobRequisition.JobDescription,
+		JobRequirements:       jobRequisition.JobRequirements,
+		Requestor:             jobRequisition.Requestor,
+		Supervisor:            jobRequisition.Supervisor,
+		SupervisorDecision:    jobRequisition.SupervisorDecision,
+		HrApprover:            jobRequisition.HrApprover,
+		HrApproverDecision:    jobRequisition.HrApproverDecision,
+		Recruiter:             jobRequisition.Recruiter,
+		FilledBy:              jobRequisition.FilledBy,
+		FilledAt:              filledAt,
+		CreatedAt:             jobRequisition.CreatedAt,
+		UpdatedAt:             jobRequisition.UpdatedAt,
+	}
+}
+
+// The roles a user can hold in relation to a job requisition
+const (
+	jobRequisitionRoleRequestor  = "requestor"
+	jobRequisitionRoleSupervisor = "supervisor"
+	jobRequisitionRoleHrApprover = "hr approver"
+	jobRequisitionRoleRecruiter  = "recruiter"
+)
+
+func (router *Router) handleGetJobRequisitionsAsRequestor(w http.ResponseWriter, r *http.Request) {
+	router.getJobRequisitionsByRole(w, r, jobRequisitionRoleRequestor)
+}
+
+func (router *Router) handleGetJobRequisitionsAsSupervisor(w http.ResponseWriter, r *http.Request) {
+	router.getJobRequisitionsByRole(w, r, jobRequisitionRoleSupervisor)
+}
+
+func (router *Router) handleGetJobRequisitionsAsHrApprover(w http.ResponseWriter, r *http.Request) {
+	router.getJobRequisitionsByRole(w, r, jobRequisitionRoleHrApprover)
+}
+
+func (router *Router) handleGetJobRequisitionsAsRecruiter(w http.ResponseWriter, r *http.Request) {
+	router.getJobRequisitionsByRole(w, r, jobRequisitionRoleRecruiter)
+}
+
+// Fetches the job requisitions that the user holds the given role in & writes them to the response as JSON
+// If a job requisition id is provided in the path, only that job requisition is returned
+func (router *Router) getJobRequisitionsByRole(w http.ResponseWriter, r *http.Request, role string) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId         string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId           string `validate:"required,notBlank,uuid" name:"user id"`
+		JobRequisitionId string `validate:"omitempty,notBlank,uuid" name:"job requisition id"`
+	}
+	input := Input{
+		TenantId:         vars["tenantId"],
+		UserId:           vars["userId"],
+		JobRequisitionId: vars["jobRequisitionId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// The filter must always include the user's role so that users can only view the job requisitions they are assigned to
+	filter := storage.JobRequisition{
+		Id:       input.JobRequisitionId,
+		TenantId: input.TenantId,
+	}
+	switch role {
+	case jobRequisitionRoleRequestor:
+		filter.Requestor = input.UserId
+	case jobRequisitionRoleSupervisor:
+		filter.Supervisor = input.UserId
+	case jobRequisitionRoleHrApprover:
+		filter.HrApprover = input.UserId
+	case jobRequisitionRoleRecruiter:
+		filter.Recruiter = input.UserId
+	}
+
+	// Pagination only applies when listing job requisitions
+	page := storage.PageRequest{}
+	if input.JobRequisitionId == "" {
+		page, err = router.getPageRequest(r)
+		if err != nil {
+			sendToErrorHandlingMiddleware(err, r)
+			return
+		}
+	}
+
+	jobRequisitions, nextCursor, err := router.storageFor(r).GetJobRequisitions(filter, page)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	if input.JobRequisitionId != "" {
+		if len(jobRequisitions) == 0 {
+			sendToErrorHandlingMiddleware(Err404NotFound, r)
+			return
+		}
+
+		reqLogger.Info("JOB-REQUISITION-RETRIEVED", "jobRequisitionId", input.JobRequisitionId, "tenantId", input.TenantId, "role", role)
+
+		w.Header().Add("content-type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newJobRequisitionResponseBody(jobRequisitions[0]))
+		return
+	}
+
+	resBody := jobRequisitionsResponseBody{
+		JobRequisitions: []jobRequisitionResponseBody{},
+		NextCursor:      nextCursor,
+	}
+	for _, jobRequisition := range jobRequisitions {
+		resBody.JobRequisitions = append(resBody.JobRequisitions, newJobRequisitionResponseBody(jobRequisition))
+	}
+
+	reqLogger.Info("JOB-REQUISITIONS-RETRIEVED", "tenantId", input.TenantId, "userId", input.UserId, "role", role, "count", len(resBody.JobRequisitions))
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resBody)
+}
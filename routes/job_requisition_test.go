@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/pquerna/otp/totp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"multi-tenant-HR-information-system-backend/storage"
 )
@@ -79,9 +80,18 @@ func (s *IntegrationTestSuite) TestCreateJobRequisition() {
 		},
 	)
 
+	// The HR approver is given the HR approver policies when they are chosen
+	for _, resource := range hrApproverPolicyTemplate.instantiate(want.HrApprover, want.TenantId).Resources {
+		s.expectSelectQueryToReturnOneRow(
+			"casbin_rule",
+			map[string]any{"Ptype": "p", "V0": want.HrApprover, "V1": want.TenantId, "V2": resource.Path, "V3": resource.Method},
+		)
+	}
+
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITION-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
@@ -157,6 +167,156 @@ func (s *IntegrationTestSuite) TestCreateJobRequisitionShouldValidateSupervisor(
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
+func (s *IntegrationTestSuite) TestCreateJobRequisitionShouldValidateHrApprover() {
+	want := storage.JobRequisition{
+		Id:                    "cb180c6e-af87-4a97-9dcf-bcbe503414a7",
+		TenantId:              s.defaultTenant.Id,
+		PositionId:            "979e87ea-63f8-4cc1-8fa7-3555ffc41a0a",
+		Title:                 "Database Administrator",
+		DepartmentId:          s.defaultDepartment.Id,
+		SupervisorPositionIds: []string{s.defaultSupervisorPosition.Id},
+		JobDescription:        "Manages databases of HRIS software",
+		JobRequirements:       "100 years of experience using postgres",
+		Requestor:             s.defaultUser.Id,
+		Supervisor:            s.defaultSupervisor.Id,
+		HrApprover:            s.defaultRecruiter.Id,
+	}
+
+	type requestBody struct {
+		PositionId            string
+		Title                 string
+		DepartmentId          string
+		SupervisorPositionIds []string
+		JobDescription        string
+		JobRequirements       string
+		Supervisor            string
+		HrApprover            string
+	}
+	reqBody := requestBody{
+		PositionId:            want.PositionId,
+		Title:                 want.Title,
+		DepartmentId:          want.DepartmentId,
+		SupervisorPositionIds: want.SupervisorPositionIds,
+		JobDescription:        want.JobDescription,
+		JobRequirements:       want.JobRequirements,
+		Supervisor:            want.Supervisor,
+		HrApprover:            want.HrApprover,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor/%s", want.TenantId, want.Requestor, want.Id)
+	r, err := http.NewRequest("POST", path, bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-HR-APPROVER-ERROR")
+
+	s.expectSelectQueryToReturnNoRows(
+		"job_requisition",
+		map[string]any{
+			"id":        want.Id,
+			"tenant_id": want.TenantId,
+		},
+	)
+	s.expectSelectQueryToReturnNoRows(
+		"casbin_rule",
+		map[string]any{
+			"Ptype": "p",
+			"V0":    want.HrApprover,
+			"V1":    want.TenantId,
+			"V2":    fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-hr-approver", want.TenantId, want.HrApprover),
+			"V3":    "GET",
+		},
+	)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"INVALID-HR-APPROVER-ERROR"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestCreateJobRequisitionShouldRejectRequestorAsHrApprover() {
+	want := storage.JobRequisition{
+		Id:                    "cb180c6e-af87-4a97-9dcf-bcbe503414a7",
+		TenantId:              s.defaultTenant.Id,
+		PositionId:            "979e87ea-63f8-4cc1-8fa7-3555ffc41a0a",
+		Title:                 "Database Administrator",
+		DepartmentId:          s.defaultDepartment.Id,
+		SupervisorPositionIds: []string{s.defaultSupervisorPosition.Id},
+		JobDescription:        "Manages databases of HRIS software",
+		JobRequirements:       "100 years of experience using postgres",
+		Requestor:             s.defaultUser.Id,
+		Supervisor:            s.defaultSupervisor.Id,
+		HrApprover:            s.defaultUser.Id,
+	}
+
+	type requestBody struct {
+		PositionId            string
+		Title                 string
+		DepartmentId          string
+		SupervisorPositionIds []string
+		JobDescription        string
+		JobRequirements       string
+		Supervisor            string
+		HrApprover            string
+	}
+	reqBody := requestBody{
+		PositionId:            want.PositionId,
+		Title:                 want.Title,
+		DepartmentId:          want.DepartmentId,
+		SupervisorPositionIds: want.SupervisorPositionIds,
+		JobDescription:        want.JobDescription,
+		JobRequirements:       want.JobRequirements,
+		Supervisor:            want.Supervisor,
+		HrApprover:            want.HrApprover,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor/%s", want.TenantId, want.Requestor, want.Id)
+	r, err := http.NewRequest("POST", path, bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INPUT-VALIDATION-ERROR")
+
+	s.expectSelectQueryToReturnNoRows(
+		"job_requisition",
+		map[string]any{
+			"id":        want.Id,
+			"tenant_id": want.TenantId,
+		},
+	)
+	s.expectSelectQueryToReturnNoRows(
+		"casbin_rule",
+		map[string]any{
+			"Ptype": "p",
+			"V0":    want.HrApprover,
+			"V1":    want.TenantId,
+			"V2":    fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-hr-approver", want.TenantId, want.HrApprover),
+			"V3":    "GET",
+		},
+	)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"INPUT-VALIDATION-ERROR"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
 func (s *IntegrationTestSuite) TestCreateJobRequisitionShouldValidateInput() {
 	want := storage.JobRequisition{
 		Id:                    "cb180c6e-af87-4a97-9dcf-bcbe503414a7",
@@ -329,6 +489,9 @@ func (s *IntegrationTestSuite) TestSupervisorApproveJobRequisition() {
 	}
 	s.addSessionCookieToRequest(r, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
 
+	approved := s.router.metrics.jobRequisitionDecisions.WithLabelValues(want.TenantId, "supervisor", "APPROVED")
+	approvedBefore := testutil.ToFloat64(approved)
+
 	w := httptest.NewRecorder()
 	s.router.ServeHTTP(w, r)
 
@@ -344,6 +507,8 @@ func (s *IntegrationTestSuite) TestSupervisorApproveJobRequisition() {
 		},
 	)
 
+	s.Equal(approvedBefore+1, testutil.ToFloat64(approved), "The approval should have been counted")
+
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITION-SUPERVISOR-APPROVED"`)
@@ -498,6 +663,7 @@ func (s *IntegrationTestSuite) TestSupervisorApproveJobRequisitionShouldValidate
 
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"reason":"INVALID-PASSWORD"`)
 	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHENTICATED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
@@ -736,9 +902,18 @@ func (s *IntegrationTestSuite) TestHrApproveJobRequisitionForNewPosition() {
 		)
 	}
 
+	// The recruiter is given the recruiter policies when they are assigned
+	for _, resource := range recruiterPolicyTemplate.instantiate(want.Recruiter, want.TenantId).Resources {
+		s.expectSelectQueryToReturnOneRow(
+			"casbin_rule",
+			map[string]any{"Ptype": "p", "V0": want.Recruiter, "V1": want.TenantId, "V2": resource.Path, "V3": resource.Method},
+		)
+	}
+
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITION-HR-APPROVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
@@ -823,6 +998,7 @@ func (s *IntegrationTestSuite) TestHrApproveJobRequisitionForExistingPosition()
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITION-HR-APPROVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
@@ -1140,6 +1316,7 @@ func (s *IntegrationTestSuite) TestHrApproveJobRequisitionShouldValidateCredenti
 
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"reason":"INVALID-PASSWORD"`)
 	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHENTICATED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
@@ -1219,3 +1396,195 @@ func (s *IntegrationTestSuite) TestHrApproveJobRequisitionShouldPreventIdExploit
 	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"RESOURCE-NOT-FOUND-ERROR"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionsAsRequestor() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor", s.defaultTenant.Id, s.defaultUser.Id)
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody jobRequisitionsResponseBody
+	err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err, "Response body should be a page of job requisitions")
+	s.Equal(2, len(resBody.JobRequisitions), "Both job requisitions created by the requestor should be returned")
+	for _, jobRequisition := range resBody.JobRequisitions {
+		s.Equal(s.defaultUser.Id, jobRequisition.Requestor)
+	}
+	s.Equal("", resBody.NextCursor, "There should not be a next page")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITIONS-RETRIEVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionsAsRecruiter() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-recruiter", s.defaultTenant.Id, s.defaultRecruiter.Id)
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultRecruiter.Id, s.defaultRecruiter.TenantId, s.defaultRecruiter.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody jobRequisitionsResponseBody
+	err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err, "Response body should be a page of job requisitions")
+	s.Equal(1, len(resBody.JobRequisitions), "Only the job requisition the recruiter is assigned to should be returned")
+	if len(resBody.JobRequisitions) == 1 {
+		s.Equal(s.defaultApprovedJobRequisition.Id, resBody.JobRequisitions[0].Id)
+	}
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITIONS-RETRIEVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionsAsRequestorWithPagination() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor?limit=1&sortOrder=desc", s.defaultTenant.Id, s.defaultUser.Id)
+	seenIds := map[string]bool{}
+
+	for i := 0; i < 2; i++ {
+		r, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, r)
+
+		s.expectHttpStatus(w, 200)
+
+		var resBody jobRequisitionsResponseBody
+		err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+		s.Equal(nil, err, "Response body should be a page of job requisitions")
+		s.Equal(1, len(resBody.JobRequisitions), "Only 1 job requisition should be returned per page")
+		for _, jobRequisition := range resBody.JobRequisitions {
+			s.False(seenIds[jobRequisition.Id], "Pages should not overlap")
+			seenIds[jobRequisition.Id] = true
+		}
+
+		if i == 0 {
+			s.NotEqual("", resBody.NextCursor, "The first page should have a next cursor")
+		} else {
+			s.Equal("", resBody.NextCursor, "The last page should not have a next cursor")
+		}
+		path = fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor?limit=1&sortOrder=desc&cursor=%s", s.defaultTenant.Id, s.defaultUser.Id, resBody.NextCursor)
+	}
+}
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionsShouldValidatePagination() {
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"Invalid cursor", "cursor=abc", "INVALID-CURSOR-ERROR"},
+		{"Invalid sort field", "sortBy=password", "INVALID-SORT-FIELD-ERROR"},
+		{"Invalid sort order", "sortOrder=up", "INPUT-VALIDATION-ERROR"},
+		{"Negative limit", "limit=-1", "INPUT-VALIDATION-ERROR"},
+		{"Zero limit", "limit=0", "INPUT-VALIDATION-ERROR"},
+		{"Limit above the maximum", "limit=101", "INPUT-VALIDATION-ERROR"},
+		{"Limit too large to parse", "limit=99999999999999999999", "INPUT-VALIDATION-ERROR"},
+		{"Offset with cursor", "offset=1&cursor=abc", "INPUT-VALIDATION-ERROR"},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor?%s", s.defaultTenant.Id, s.defaultUser.Id, test.query)
+			r, err := http.NewRequest("GET", path, nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, 400)
+			s.expectErrorCode(w, test.code)
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionAsSupervisor() {
+	want := s.defaultJobRequisition
+
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-supervisor/%s", want.TenantId, want.Supervisor, want.Id)
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody jobRequisitionResponseBody
+	err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err, "Response body should be a job requisition")
+	s.Equal(want.Id, resBody.Id)
+	s.Equal(want.Supervisor, resBody.Supervisor)
+	s.Equal("PENDING", resBody.SupervisorDecision)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"JOB-REQUISITION-RETRIEVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionAsHrApproverShouldValidateIdExistence() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-hr-approver/%s", s.defaultTenant.Id, s.defaultHrApprover.Id, "caaa7845-9601-4528-bd60-7cdae6cf298a")
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultHrApprover.Id, s.defaultHrApprover.TenantId, s.defaultHrApprover.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"RESOURCE-NOT-FOUND-ERROR"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestGetJobRequisitionsShouldPreventIdExploit() {
+	// The supervisor attempts to view the requestor's job requisitions
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor", s.defaultTenant.Id, s.defaultUser.Id)
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "USER-UNAUTHORISED")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"AUTHORIZATION-DENIED"`, s.defaultSupervisor.Id, path)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
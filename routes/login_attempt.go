@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+)
+
+// Failed logins are counted per tenant & email. Once MaxFailedAttempts is reached, each further failure locks logins for
+// BaseLockoutDuration, which doubles with every failure up to MaxLockoutDuration. The count is cleared by a successful login
+type LockoutPolicy struct {
+	MaxFailedAttempts   int
+	BaseLockoutDuration time.Duration
+	MaxLockoutDuration  time.Duration
+}
+
+// Returns how long logins should be locked for after the given number of failed attempts
+func (policy LockoutPolicy) lockoutDuration(failedCount int) time.Duration {
+	if policy.MaxFailedAttempts <= 0 || failedCount < policy.MaxFailedAttempts {
+		return 0
+	}
+
+	duration := policy.BaseLockoutDuration
+	for i := policy.MaxFailedAttempts; i < failedCount && duration < policy.MaxLockoutDuration; i++ {
+		duration *= 2
+	}
+
+	return min(duration, policy.MaxLockoutDuration)
+}
+
+// Returns ErrLoginLocked if logins for the tenant & email are locked
+// Attempts are rejected before the password is checked, so that the password cannot be brute-forced during the lockout
+func (router *Router) checkLoginLockout(r *http.Request, tenantId string, email string) error {
+	loginAttempt, err := router.storageFor(r).GetLoginAttempt(tenantId, email)
+	if err != nil {
+		return err
+	}
+
+	if time.Now().Before(loginAttempt.LockedUntil) {
+		reqLogger := getRequestLogger(r)
+		reqLogger.Warn("LOCKED-LOGIN-ATTEMPTED", "tenantId", tenantId, "email", email, "lockedUntil", loginAttempt.LockedUntil)
+		return ErrLoginLocked
+	}
+
+	return nil
+}
+
+// Failures & lockouts are logged with the request logger, which includes the client IP
+func (router *Router) recordFailedLogin(r *http.Request, tenantId string, email string, reason string) error {
+	loginAttempt, err := router.storageFor(r).RecordFailedLogin(tenantId, email)
+	if err != nil {
+		return err
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Warn("LOGIN-FAILED", "tenantId", tenantId, "email", email, "reason", reason, "failedCount", loginAttempt.FailedCount)
+
+	duration := router.lockoutPolicy.lockoutDuration(loginAttempt.FailedCount)
+	if duration == 0 {
+		return nil
+	}
+
+	lockedUntil := time.Now().Add(duration)
+	err = router.storageFor(r).LockLogin(tenantId, email, lockedUntil)
+	if err != nil {
+		return err
+	}
+	reqLogger.Warn("LOGIN-LOCKED-OUT", "tenantId", tenantId, "email", email, "failedCount", loginAttempt.FailedCount,
+		"lockedUntil", lockedUntil)
+
+	return nil
+}
+
+func (router *Router) resetFailedLogins(r *http.Request, tenantId string, email string) error {
+	return router.storageFor(r).DeleteLoginAttempt(tenantId, email)
+}
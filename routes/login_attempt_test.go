@@ -0,0 +1,190 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	policy := LockoutPolicy{
+		MaxFailedAttempts:   3,
+		BaseLockoutDuration: time.Minute,
+		MaxLockoutDuration:  10 * time.Minute,
+	}
+
+	tests := []struct {
+		failedCount int
+		want        time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 8 * time.Minute},
+		{7, 10 * time.Minute},
+		{100, 10 * time.Minute},
+	}
+
+	for _, test := range tests {
+		got := policy.lockoutDuration(test.failedCount)
+		if got != test.want {
+			t.Errorf("lockoutDuration(%d) = %s, want %s", test.failedCount, got, test.want)
+		}
+	}
+
+	if got := (LockoutPolicy{}).lockoutDuration(100); got != 0 {
+		t.Errorf("Logins should never be locked if MaxFailedAttempts is not set, got %s", got)
+	}
+}
+
+func (s *IntegrationTestSuite) sendLoginRequest(password string, otp string) *httptest.ResponseRecorder {
+	reqBody := map[string]string{
+		"TenantId": s.defaultUser.TenantId,
+		"Email":    s.defaultUser.Email,
+		"Password": password,
+		"Totp":     otp,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	return w
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldBeLockedAfterFailedAttempts() {
+	for i := 0; i < s.router.lockoutPolicy.MaxFailedAttempts; i++ {
+		w := s.sendLoginRequest("abcd1234!@#$%", "123456")
+		s.expectHttpStatus(w, 401)
+	}
+
+	s.expectSelectQueryToReturnOneRow(
+		"login_attempt",
+		map[string]any{
+			"tenant_id":    s.defaultUser.TenantId,
+			"email":        s.defaultUser.Email,
+			"failed_count": s.router.lockoutPolicy.MaxFailedAttempts,
+		},
+	)
+
+	// Even the correct credentials are rejected during the lockout
+	code, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+	s.logOutput.Reset()
+	w := s.sendLoginRequest("jU%q837d!QP7", code)
+	s.expectHttpStatus(w, 429)
+	s.expectErrorCode(w, "LOGIN-LOCKED")
+	s.Equal("", w.Header().Get("Set-Cookie"), "Session should not have been created")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOCKED-LOGIN-ATTEMPTED"`, `"clientIp"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-LOCKED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestLoginLockoutShouldBeLogged() {
+	for i := 1; i < s.router.lockoutPolicy.MaxFailedAttempts; i++ {
+		s.sendLoginRequest("abcd1234!@#$%", "123456")
+	}
+
+	s.logOutput.Reset()
+	w := s.sendLoginRequest("abcd1234!@#$%", "123456")
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"clientIp"`, `"reason":"INVALID-PASSWORD"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-LOCKED-OUT"`, `"clientIp"`, `"lockedUntil"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHENTICATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// Lockouts after the lockout has expired should be longer
+	_, err := s.dbRootConn.Exec("UPDATE login_attempt SET locked_until = now() - interval '1 second'")
+	if err != nil {
+		log.Fatalf("Could not expire the lockout: %s", err)
+	}
+	w = s.sendLoginRequest("abcd1234!@#$%", "123456")
+	s.expectHttpStatus(w, 401)
+
+	var lockedFor float64
+	err = s.dbRootConn.QueryRow("SELECT EXTRACT(EPOCH FROM locked_until - now()) FROM login_attempt").Scan(&lockedFor)
+	s.Equal(nil, err)
+	s.Greater(lockedFor, s.router.lockoutPolicy.BaseLockoutDuration.Seconds(), "The lockout duration should have increased")
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldClearFailedAttempts() {
+	w := s.sendLoginRequest("abcd1234!@#$%", "123456")
+	s.expectHttpStatus(w, 401)
+	s.expectSelectQueryToReturnOneRow("login_attempt", map[string]any{"email": s.defaultUser.Email, "failed_count": 1})
+
+	code, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+	w = s.sendLoginRequest("jU%q837d!QP7", code)
+	s.expectHttpStatus(w, 200)
+	s.expectSelectQueryToReturnNoRows("login_attempt", map[string]any{"email": s.defaultUser.Email})
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldRejectReplayedTotp() {
+	code, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+	w := s.sendLoginRequest("jU%q837d!QP7", code)
+	s.expectHttpStatus(w, 200)
+
+	s.logOutput.Reset()
+	w = s.sendLoginRequest("jU%q837d!QP7", code)
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"TOTP-REPLAYED"`, `"clientIp"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"reason":"INVALID-OTP"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHENTICATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestTotpEnrollmentShouldBeLockedOut() {
+	s.setTotpEnrollmentPending(s.defaultUser.Id)
+	key := s.startTotpEnrollment()
+	reqBody := totpEnrollmentRequestBody{
+		TenantId: s.defaultUser.TenantId,
+		Email:    s.defaultUser.Email,
+		Password: "jU%q837d!QP7",
+		Totp:     "123456",
+	}
+
+	// Wrong TOTPs count towards the same lockout as failed logins, so that they cannot be brute-forced with the password
+	s.logOutput.Reset()
+	w := s.sendTotpEnrollmentRequest("PUT", reqBody)
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-TOTP-ERROR")
+	s.expectSelectQueryToReturnOneRow("login_attempt", map[string]any{"email": s.defaultUser.Email, "failed_count": 1})
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"reason":"INVALID-OTP"`)
+
+	for i := 1; i < s.router.lockoutPolicy.MaxFailedAttempts; i++ {
+		s.sendTotpEnrollmentRequest("PUT", reqBody)
+	}
+
+	reqBody.Totp, _ = totp.GenerateCode(key.Secret(), time.Now().UTC())
+	w = s.sendTotpEnrollmentRequest("PUT", reqBody)
+	s.expectHttpStatus(w, 429)
+	s.expectErrorCode(w, "LOGIN-LOCKED")
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultUser.Id, "totp_secret_key": ""})
+}
@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "hris"
+
+// Each router has its own registry, so that routers created by tests do not register the same metrics twice
+type metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration         *prometheus.HistogramVec
+	authorizationDuration   prometheus.Histogram
+	jobRequisitionDecisions *prometheus.CounterVec
+	jobApplicationDecisions *prometheus.CounterVec
+	authorizationReloads    *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+
+		// Routes are labelled by their template (e.g. /api/tenants/{tenantId}), so that ids do not create a series per resource
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle requests, by route template, method & response status",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		authorizationDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "authorization_enforce_duration_seconds",
+			Help:      "Time taken by casbin to decide whether a request is authorised",
+			Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+		}),
+		jobRequisitionDecisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "job_requisition_decisions_total",
+			Help:      "Job requisition approvals & rejections, by tenant & approval stage",
+		}, []string{"tenant_id", "stage", "decision"}),
+		jobApplicationDecisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "job_application_decisions_total",
+			Help:      "Job application decisions (e.g. shortlisted or offered), by tenant & hiring stage",
+		}, []string{"tenant_id", "stage", "decision"}),
+		// Reloads are triggered by a request that changed the policies, or by a notification that another instance changed them
+		authorizationReloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "authorization_policy_reloads_total",
+			Help:      "Reloads of the casbin policy, by trigger & result",
+		}, []string{"trigger", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.authorizationDuration,
+		m.jobRequisitionDecisions,
+		m.jobApplicationDecisions,
+		m.authorizationReloads,
+	)
+
+	return m
+}
+
+// Handlers that do not call WriteHeader respond with 200
+func (m *metrics) observeRequest(route string, method string, status int, duration time.Duration) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	m.requestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func (m *metrics) observeAuthorizationReload(trigger string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.authorizationReloads.WithLabelValues(trigger, result).Inc()
+}
+
+// Adds a collector for a dependency, e.g. the database connection pool
+func (router *Router) RegisterMetricsCollector(collector prometheus.Collector) error {
+	return router.metrics.registry.Register(collector)
+}
+
+// Serves the metrics in the Prometheus exposition format
+// The metrics contain tenant ids, so the handler should be served on an internal address rather than by the router
+func (router *Router) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(router.metrics.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+)
+
+func (s *IntegrationTestSuite) TestMetrics() {
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	r, err = http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	s.router.MetricsHandler().ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	// Requests are labelled by their route template instead of their path
+	s.Contains(w.Body.String(), `hris_http_request_duration_seconds_count{method="GET",route="/api/tenants/{tenantId}/users/{userId}/sessions",status="200"}`)
+	s.NotContains(w.Body.String(), s.defaultUser.Id)
+	s.Contains(w.Body.String(), "hris_authorization_enforce_duration_seconds_count")
+	s.Contains(w.Body.String(), "go_goroutines")
+}
+
+// The metrics are served on a separate address by main, so they must not be reachable through the router
+func (s *IntegrationTestSuite) TestMetricsShouldNotBeServedByRouter() {
+	r, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"multi-tenant-HR-information-system-backend/httperror"
 	"multi-tenant-HR-information-system-backend/storage"
@@ -25,18 +28,33 @@ const (
 	errorKey
 	translatorKey
 	authenticatedUserKey
+	apiTokenKey
 )
 
 // Creates a request-specific logger & adds it to the request context
 func setRequestLogger(rootLogger *tailoredLogger) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var pathTemplate string
+			if route := mux.CurrentRoute(r); route != nil {
+				pathTemplate, _ = route.GetPathTemplate()
+			}
+			ctx, span := startServerSpan(r, pathTemplate)
+			// Recorded here rather than in logRequestCompletion, so that the status of routes without it (e.g. 404s) is set too
+			wr := ResponseWriterRecorder{w, 0}
+			defer func() { endServerSpan(span, wr.status) }()
+
 			requestId := uuid.New().String()
 			requestLogger := rootLogger.With("requestId", requestId, "clientIp", r.RemoteAddr, "url", r.URL.Path, "method", r.Method)
 
-			r = r.WithContext(context.WithValue(r.Context(), requestLoggerKey, requestLogger))
+			// The trace id lets the logs of a request be found from its trace & vice versa
+			if spanContext := span.SpanContext(); spanContext.IsValid() {
+				requestLogger = requestLogger.With("traceId", spanContext.TraceID().String(), "spanId", spanContext.SpanID().String())
+			}
 
-			next.ServeHTTP(w, r)
+			r = r.WithContext(context.WithValue(ctx, requestLoggerKey, requestLogger))
+
+			next.ServeHTTP(&wr, r)
 		})
 	}
 }
@@ -59,28 +77,42 @@ func (wr *ResponseWriterRecorder) WriteHeader(status int) {
 }
 
 // Note: "X-Real-Ip" and "X-Forwarded-For" headers are not used for the clientIp because they can be modified by the client == security risk
-func logRequestCompletion(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		startTime := time.Now()
+func logRequestCompletion(metrics *metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			startTime := time.Now()
 
-		wr := ResponseWriterRecorder{w, 0}
-		next.ServeHTTP(&wr, r)
+			wr := ResponseWriterRecorder{w, 0}
+			next.ServeHTTP(&wr, r)
 
-		duration := time.Since(startTime)
+			duration := time.Since(startTime)
 
-		requestLogger := getRequestLogger(r)
-		requestLogger.Info("REQUEST-COMPLETED", "responseTime", duration.String(), "status", wr.status)
-	})
+			requestLogger := getRequestLogger(r)
+			requestLogger.Info("REQUEST-COMPLETED", "responseTime", duration.String(), "status", wr.status)
+
+			if route := mux.CurrentRoute(r); route != nil {
+				pathTemplate, _ := route.GetPathTemplate()
+				metrics.observeRequest(pathTemplate, r.Method, wr.status, duration)
+			}
+		})
+	}
 }
 
 type ErrorTransport struct {
 	Error error
 }
 
+// Errors are sent as RFC 7807 problem details. The code identifies the problem, as every problem has the same type
+// Inputs that caused the error (e.g. failed validations or unique columns) are listed in errors
 func errorHandling(next http.Handler) http.Handler {
-	type errorResponseBody struct {
-		Code    string `json:"code"`
-		Message string `json:"message"`
+	type problemDetails struct {
+		Type    string                 `json:"type"`
+		Title   string                 `json:"title"`
+		Status  int                    `json:"status"`
+		Detail  string                 `json:"detail"`
+		Code    string                 `json:"code"`
+		Errors  []httperror.FieldError `json:"errors,omitempty"`
+		TraceId string                 `json:"traceId,omitempty"`
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -106,25 +138,56 @@ func errorHandling(next http.Handler) http.Handler {
 			err = httperror.NewInternalServerError(errTransport.Error)
 		}
 
-		var message string
+		body := problemDetails{
+			Type:   "about:blank",
+			Title:  http.StatusText(err.Status),
+			Status: err.Status,
+			Code:   err.Code,
+			Errors: err.Errors,
+		}
+
+		// Messages are sent in the client's language, whereas logs are always in English
+		translator := getTranslator(r)
+		if translator != nil {
+			w.Header().Set("Content-Language", translator.Locale())
+		}
+
 		requestLogger := getRequestLogger(r)
 		if err.Code == "INTERNAL-SERVER-ERROR" {
 			// Do not reveal internal server error stack traces to the client!!
+			// If the request is traced, the trace id is used so that the error can be looked up in the trace too
 			traceId := uuid.New().String()
-			message = fmt.Sprintf("Something went wrong. Trace ID: %s", traceId)
+			span := trace.SpanFromContext(r.Context())
+			if spanContext := span.SpanContext(); spanContext.IsValid() {
+				traceId = spanContext.TraceID().String()
+			}
+			span.RecordError(errTransport.Error) // The span is marked as failed once the status is written
+
+			body.Detail = translateErrorMessage(translator, &httperror.Error{
+				Message: fmt.Sprintf("Something went wrong. Trace ID: %s", traceId),
+				Code:    err.Code,
+				Args:    []string{traceId},
+			})
+			body.TraceId = traceId
 
 			errorMessage, stackTrace, _ := strings.Cut(err.Error(), "\n")
 			requestLogger.Error(err.Code, "errorMessage", errorMessage, "stackTrace", stackTrace, "traceId", traceId)
 		} else {
-			message = err.Error()
+			body.Detail = translateErrorMessage(translator, err)
+
+			// Field errors that repeat the error's message (e.g. the columns of a unique violation) are translated with it
+			body.Errors = make([]httperror.FieldError, len(err.Errors))
+			for i, fieldError := range err.Errors {
+				if fieldError.Message == err.Message {
+					fieldError.Message = body.Detail
+				}
+				body.Errors[i] = fieldError
+			}
+
 			requestLogger.Warn(err.Code, "errorMessage", err.Error())
 		}
 
-		body := errorResponseBody{
-			Code:    err.Code,
-			Message: message,
-		}
-		w.Header().Add("content-type", "application/json")
+		w.Header().Add("content-type", "application/problem+json")
 		w.WriteHeader(err.Status)
 		json.NewEncoder(w).Encode(body)
 	})
@@ -141,10 +204,7 @@ func sendToErrorHandlingMiddleware(err error, r *http.Request) {
 func setTranslator(universalTranslator *ut.UniversalTranslator) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			acceptLanguageHeader := r.Header.Get("Accept-Language")
-			language := strings.Split(acceptLanguageHeader, "-")[0]
-
-			translator, _ := universalTranslator.GetTranslator(language)
+			translator := findTranslator(universalTranslator, r.Header.Get("Accept-Language"))
 
 			r = r.WithContext(context.WithValue(r.Context(), translatorKey, translator))
 
@@ -158,9 +218,29 @@ func getTranslator(r *http.Request) ut.Translator {
 	return translator
 }
 
-func authenticateUser(sessionStore sessions.Store) mux.MiddlewareFunc {
+// Machine clients authenticate with a bearer token instead of a session. Both produce the same user for verifyAuthorization
+// storageFor returns the storage of the request, so that API token lookups are traced like the other storage calls
+func authenticateUser(sessionStore sessions.Store, storageFor func(r *http.Request) storage.Storage) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if authorizationHeader := r.Header.Get("Authorization"); authorizationHeader != "" {
+				user, apiToken, err := authenticateApiToken(r, storageFor(r), authorizationHeader)
+				if err != nil {
+					sendToErrorHandlingMiddleware(err, r)
+					return
+				}
+
+				r = r.WithContext(context.WithValue(r.Context(), authenticatedUserKey, user))
+				r = addApiTokenToContext(r, apiToken)
+
+				reqLogger := getRequestLogger(r)
+				reqLoggerWithUserID := reqLogger.With("userId", user.Id, "tenantId", user.TenantId, "apiTokenId", apiToken.Id)
+				r = r.WithContext(context.WithValue(r.Context(), requestLoggerKey, reqLoggerWithUserID))
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			session, err := sessionStore.Get(r, authSessionName)
 			if err != nil {
 				sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
@@ -208,28 +288,113 @@ func getAuthenticatedUser(r *http.Request) storage.User {
 	return user
 }
 
-func verifyAuthorization(authEnforcer casbin.IEnforcer) mux.MiddlewareFunc {
+func verifyAuthorization(authEnforcer casbin.IEnforcer, metrics *metrics) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			user := r.Context().Value(authenticatedUserKey).(storage.User)
 
 
+			_, span := getTracer().Start(r.Context(), "casbin.Enforce", trace.WithAttributes(
+				attribute.String("enduser.id", user.Id),
+				attribute.String("tenant.id", user.TenantId),
+			))
+			startTime := time.Now()
 			authorized, err := authEnforcer.Enforce(user.Id, user.TenantId, r.URL.Path, r.Method)
+			metrics.authorizationDuration.Observe(time.Since(startTime).Seconds())
+			span.SetAttributes(attribute.Bool("authorized", authorized))
+			endSpan(span, err)
 			if err != nil {
 				sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
 				return
 			}
 
 			if !authorized {
-				fmt.Println(user.Id, user.TenantId, r.URL.Path, r.Method)
+				// The missing rule can be found by explaining the authorization decision of the same subject, resource & method
+				reqLogger := getRequestLogger(r)
+				reqLogger.Warn("AUTHORIZATION-DENIED", "userId", user.Id, "tenantId", user.TenantId, "resource", r.URL.Path, "method", r.Method)
 				sendToErrorHandlingMiddleware(ErrUserUnauthorised, r)
 				return
 			}
 
 			reqLogger := getRequestLogger(r)
+
+			// Tokens may be restricted to a subset of what their owner is authorised to do
+			if apiToken, ok := getApiToken(r); ok && !apiTokenAllowsMethod(apiToken, r.Method) {
+				reqLogger.Warn("API-TOKEN-SCOPE-INSUFFICIENT", "apiTokenId", apiToken.Id, "scopes", apiToken.Scopes, "method", r.Method)
+				sendToErrorHandlingMiddleware(ErrUserUnauthorised, r)
+				return
+			}
+
 			reqLogger.Info("USER-AUTHORISED", "userId", user.Id, "tenantId", user.TenantId, "resource", r.URL.Path, "method", r.Method)
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// The SAML identity provider posts the assertion to the ACS from its own origin
+// This is safe as the assertion must be signed & answer a request id that is only used once, & that was started by the same browser
+var crossOriginPathTemplates = map[string]bool{
+	"/api/tenants/{tenantId}/sso/saml/acs": true,
+}
+
+// Prevents CSRF by rejecting state-changing requests that a browser sent from an untrusted origin
+// Browsers always send the Sec-Fetch-Site or Origin header with such requests, so requests without either (e.g. from curl) are allowed
+// Requests with a bearer token are allowed too, as browsers do not add the Authorization header automatically like they do cookies
+func verifyRequestOrigin(trustedOrigins []string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions || r.Header.Get("Authorization") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if route := mux.CurrentRoute(r); route != nil {
+				if pathTemplate, err := route.GetPathTemplate(); err == nil && crossOriginPathTemplates[pathTemplate] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			secFetchSite := r.Header.Get("Sec-Fetch-Site")
+			if secFetchSite == "same-origin" || secFetchSite == "none" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" && secFetchSite == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if isTrustedOrigin(origin, r.Host, trustedOrigins) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqLogger := getRequestLogger(r)
+			reqLogger.Warn("CROSS-ORIGIN-REQUEST-BLOCKED", "origin", origin, "secFetchSite", secFetchSite)
+			sendToErrorHandlingMiddleware(ErrCrossOriginRequest, r)
+		})
+	}
+}
+
+// The API's own origin is always trusted. Origins are compared by scheme & host (including the port)
+func isTrustedOrigin(origin string, host string, trustedOrigins []string) bool {
+	originUrl, err := url.Parse(origin)
+	if err != nil || originUrl.Host == "" {
+		return false
+	}
+
+	if originUrl.Host == host {
+		return true
+	}
+
+	for _, trustedOrigin := range trustedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(trustedOrigin, "/"), originUrl.Scheme+"://"+originUrl.Host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIsTrustedOrigin(t *testing.T) {
+	trustedOrigins := []string{"https://hr.example.com", "http://localhost:5173/"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://api.example.com", true},
+		{"https://hr.example.com", true},
+		{"HTTPS://HR.EXAMPLE.COM", true},
+		{"http://localhost:5173", true},
+		{"http://hr.example.com", false},
+		{"https://hr.example.com:8443", false},
+		{"https://evil.example.com", false},
+		{"null", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		got := isTrustedOrigin(test.origin, "api.example.com", trustedOrigins)
+		if got != test.want {
+			t.Errorf("isTrustedOrigin(%q) = %v, want %v", test.origin, got, test.want)
+		}
+	}
+}
+
+func (s *IntegrationTestSuite) TestVerifyRequestOrigin() {
+	tests := []struct {
+		name         string
+		headers      map[string]string
+		wantRejected bool
+	}{
+		{"Should allow requests without browser headers", map[string]string{}, false},
+		{"Should allow same-origin requests", map[string]string{"Sec-Fetch-Site": "same-origin"}, false},
+		{"Should allow requests from the API's own origin", map[string]string{"Origin": "http://example.com"}, false},
+		{"Should allow requests from trusted origins", map[string]string{"Origin": "https://hr.example.com", "Sec-Fetch-Site": "same-site"}, false},
+		{"Should reject requests from untrusted origins", map[string]string{"Origin": "https://evil.example.com", "Sec-Fetch-Site": "cross-site"}, true},
+		{"Should reject cross-site requests without an origin", map[string]string{"Sec-Fetch-Site": "cross-site"}, true},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			r, err := http.NewRequest("DELETE", fmt.Sprintf("http://example.com/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultSupervisor.Id), nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for key, value := range test.headers {
+				r.Header.Set(key, value)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			s.logOutput.Reset()
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			if test.wantRejected {
+				s.expectHttpStatus(w, 403)
+				s.expectErrorCode(w, "CROSS-ORIGIN-REQUEST-ERROR")
+
+				reader := bufio.NewReader(s.logOutput)
+				s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"CROSS-ORIGIN-REQUEST-BLOCKED"`)
+			} else {
+				s.expectHttpStatus(w, 200)
+			}
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestVerifyRequestOriginShouldRejectCrossOriginLogin() {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]string{"TenantId": s.defaultUser.TenantId, "Email": s.defaultUser.Email, "Password": "jU%q837d!QP7"})
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Header.Set("Origin", "https://evil.example.com")
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "CROSS-ORIGIN-REQUEST-ERROR")
+	s.Equal("", w.Header().Get("Set-Cookie"), "Session should not have been created")
+}
+
+func (s *IntegrationTestSuite) TestErrorHandlingShouldReturnProblemDetails() {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]any{"Name": "BI export", "Scopes": []string{"read", "admin"}})
+
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/users/%s/api-tokens/%s", s.defaultTenant.Id, s.defaultUser.Id, "not-a-uuid"), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 400)
+	s.Equal("application/problem+json", w.Header().Get("content-type"))
+
+	var body errorResponseBody
+	err = json.NewDecoder(w.Body).Decode(&body)
+	s.Require().Equal(nil, err)
+	s.Equal("about:blank", body.Type)
+	s.Equal("Bad Request", body.Title)
+	s.Equal(400, body.Status)
+	s.Equal("INPUT-VALIDATION-ERROR", body.Code)
+	s.Contains(body.Detail, "There are one or more errors with your input(s)")
+
+	fields := map[string]string{}
+	for _, fieldError := range body.Errors {
+		fields[fieldError.Field] = fieldError.Tag
+		s.NotEmpty(fieldError.Message)
+	}
+	s.Equal(map[string]string{"id": "uuid", "scopes[1]": "oneof"}, fields)
+}
+
+func (s *IntegrationTestSuite) TestErrorHandlingShouldTranslateValidationErrors() {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]any{"Name": "BI export", "Scopes": []string{"read"}})
+
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/users/%s/api-tokens/%s", s.defaultTenant.Id, s.defaultUser.Id, "not-a-uuid"), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Header.Set("Accept-Language", "en;q=0.5, zh-CN")
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 400)
+	s.Equal("zh", w.Header().Get("Content-Language"))
+
+	var body errorResponseBody
+	err = json.NewDecoder(w.Body).Decode(&body)
+	s.Require().Equal(nil, err)
+	s.Equal("INPUT-VALIDATION-ERROR", body.Code)
+	s.True(strings.HasPrefix(body.Detail, "您的输入有一个或多个错误："), "detail should be in Chinese, got %s", body.Detail)
+	s.Require().Len(body.Errors, 1)
+	s.Equal("API token id必须是一个有效的UUID", body.Errors[0].Message)
+}
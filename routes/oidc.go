@@ -0,0 +1,349 @@
+package routes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gorilla/mux"
+	"golang.org/x/oauth2"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// The session that holds the state, nonce & PKCE verifier between the login redirect & the callback
+const oidcSessionName = "oidc"
+
+const oidcSessionMaxAge = 600 // Seconds that the user has to sign in with the identity provider
+
+// How long a tenant's discovered provider is reused before it is discovered again, e.g. to pick up new endpoints
+// The provider's signing keys are refreshed by the provider itself whenever an ID token is signed with an unknown key
+const oidcProviderCacheDuration = time.Hour
+
+// Caches the discovered provider of every tenant, so that logins do not make an extra round trip to the identity provider
+type oidcProviderCache struct {
+	mu        sync.Mutex
+	providers map[string]cachedOidcProvider // By tenant id
+}
+
+type cachedOidcProvider struct {
+	issuer       string
+	provider     *oidc.Provider
+	discoveredAt time.Time
+}
+
+func newOidcProviderCache() *oidcProviderCache {
+	return &oidcProviderCache{providers: map[string]cachedOidcProvider{}}
+}
+
+// Discovers the provider if it is not cached, has expired or the tenant's issuer has changed since it was cached
+func (cache *oidcProviderCache) get(ctx context.Context, tenantId string, issuer string) (*oidc.Provider, error) {
+	cache.mu.Lock()
+	cached, ok := cache.providers[tenantId]
+	cache.mu.Unlock()
+	if ok && cached.issuer == issuer && time.Since(cached.discoveredAt) < oidcProviderCacheDuration {
+		return cached.provider, nil
+	}
+
+	// The provider fetches its signing keys with the context it was created with, so it must outlive the request
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	cache.providers[tenantId] = cachedOidcProvider{issuer: issuer, provider: provider, discoveredAt: time.Now()}
+	cache.mu.Unlock()
+
+	return provider, nil
+}
+
+// Sets (or replaces) the OIDC identity provider that the tenant's users sign in with
+func (router *Router) handleSetOidcConfiguration(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Issuer       string
+		ClientId     string
+		ClientSecret string
+		RedirectUrl  string
+		EmailClaim   string
+	}
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId     string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Issuer       string `validate:"required,notBlank,url" name:"issuer"`
+		ClientId     string `validate:"required,notBlank" name:"client id"`
+		ClientSecret string `validate:"required,notBlank" name:"client secret"`
+		RedirectUrl  string `validate:"required,notBlank,url" name:"redirect url"`
+		EmailClaim   string `validate:"required,notBlank" name:"email claim"`
+	}
+	input := Input{
+		TenantId:     vars["tenantId"],
+		Issuer:       reqBody.Issuer,
+		ClientId:     reqBody.ClientId,
+		ClientSecret: reqBody.ClientSecret,
+		RedirectUrl:  reqBody.RedirectUrl,
+		EmailClaim:   reqBody.EmailClaim,
+	}
+	// Most identity providers put the user's email in the standard "email" claim
+	if input.EmailClaim == "" {
+		input.EmailClaim = "email"
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	config := storage.OidcConfiguration{
+		TenantId:     input.TenantId,
+		Issuer:       input.Issuer,
+		ClientId:     input.ClientId,
+		ClientSecret: input.ClientSecret,
+		RedirectUrl:  input.RedirectUrl,
+		EmailClaim:   input.EmailClaim,
+	}
+	err = router.storageFor(r).SetOidcConfiguration(config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("OIDC-CONFIGURATION-SET", "tenantId", config.TenantId, "issuer", config.Issuer, "clientId", config.ClientId)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Redirects the user to the tenant's identity provider to sign in
+func (router *Router) handleOidcLogin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	config, err := router.storageFor(r).GetOidcConfiguration(input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	_, oauth2Config, err := router.newOidcClient(r.Context(), config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	state, err := generateOidcValue()
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	nonce, err := generateOidcValue()
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	session, err := router.sessionStore.Get(r, oidcSessionName)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	session.Options = router.sessionPolicy.cookieOptions(oidcSessionMaxAge)
+	session.Values["oidcTenantId"] = input.TenantId
+	session.Values["state"] = state
+	session.Values["nonce"] = nonce
+	session.Values["verifier"] = verifier
+
+	err = router.sessionStore.Save(r, w, session)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("OIDC-LOGIN-STARTED", "tenantId", input.TenantId, "issuer", config.Issuer)
+
+	authCodeUrl := oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	http.Redirect(w, r, authCodeUrl, http.StatusFound)
+}
+
+// The identity provider redirects the user back here after they have signed in
+// The ID token's email claim is matched against the emails of the tenant's users, & the same session as handleLogin is created
+func (router *Router) handleOidcCallback(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		State    string `validate:"required,notBlank" name:"state"`
+		Code     string
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		State:    query.Get("state"),
+		Code:     query.Get("code"),
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	session, err := router.sessionStore.Get(r, oidcSessionName)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	tenantId, _ := session.Values["oidcTenantId"].(string)
+	state, _ := session.Values["state"].(string)
+	nonce, _ := session.Values["nonce"].(string)
+	verifier, _ := session.Values["verifier"].(string)
+
+	// The state can only be used once
+	session.Options = router.sessionPolicy.cookieOptions(-1)
+	err = router.sessionStore.Save(r, w, session)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	// A mismatched state means that the callback was not initiated by this user's browser (i.e. CSRF)
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(input.State)) != 1 || tenantId != input.TenantId {
+		sendToErrorHandlingMiddleware(ErrInvalidOidcState, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+
+	// The identity provider returns an error instead of a code if the user could not sign in (e.g. they denied access)
+	if idpError := query.Get("error"); idpError != "" || input.Code == "" {
+		reqLogger.Warn("OIDC-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "IDP-ERROR", "error", idpError,
+			"errorDescription", query.Get("error_description"))
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	config, err := router.storageFor(r).GetOidcConfiguration(input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	provider, oauth2Config, err := router.newOidcClient(r.Context(), config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	token, err := oauth2Config.Exchange(r.Context(), input.Code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		reqLogger.Warn("OIDC-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "CODE-EXCHANGE-FAILED", "errorMessage", err.Error())
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	rawIdToken, _ := token.Extra("id_token").(string)
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: config.ClientId}).Verify(r.Context(), rawIdToken)
+	if err != nil || subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		reqLogger.Warn("OIDC-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "INVALID-ID-TOKEN")
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	var claims map[string]any
+	err = idToken.Claims(&claims)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	email, _ := claims[config.EmailClaim].(string)
+	// Identity providers that let users set their own email mark unverified emails, which must not be trusted
+	// This also applies to custom email claims, as the provider may have copied them from the unverified email
+	// Some providers (e.g. AWS Cognito) send the claim as a string
+	if verified, ok := claims["email_verified"]; ok && verified != true && verified != "true" {
+		email = ""
+	}
+	if email == "" {
+		reqLogger.Warn("OIDC-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "MISSING-EMAIL-CLAIM", "emailClaim", config.EmailClaim)
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	users, _, err := router.storageFor(r).GetUsers(storage.User{TenantId: input.TenantId, Email: email}, storage.PageRequest{})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if len(users) == 0 {
+		reqLogger.Warn("OIDC-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "USER-NOT-FOUND", "email", email)
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	err = router.createSession(w, r, users[0])
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger.Info("USER-AUTHENTICATED", "userId", users[0].Id, "tenantId", users[0].TenantId, "issuer", config.Issuer)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// The provider's endpoints & keys are discovered from the issuer, so only the issuer has to be configured
+func (router *Router) newOidcClient(ctx context.Context, config storage.OidcConfiguration) (*oidc.Provider, *oauth2.Config, error) {
+	provider, err := router.oidcProviders.get(ctx, config.TenantId, config.Issuer)
+	if err != nil {
+		return nil, nil, httperror.NewInternalServerError(err)
+	}
+
+	oauth2Config := &oauth2.Config{
+		ClientID:     config.ClientId,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectUrl,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+
+	return provider, oauth2Config, nil
+}
+
+func generateOidcValue() (string, error) {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", httperror.NewInternalServerError(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
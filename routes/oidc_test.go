@@ -0,0 +1,279 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"multi-tenant-HR-information-system-backend/oidcmock"
+)
+
+// Starts a mock IdP & configures it as the default tenant's identity provider. Close the IdP at the end of the test
+func (s *IntegrationTestSuite) setUpOidc() *oidcmock.Server {
+	idp, err := oidcmock.NewServer("hris", "hris-secret")
+	if err != nil {
+		log.Fatalf("Could not start the mock IdP: %s", err)
+	}
+
+	reqBody := map[string]string{
+		"Issuer":       idp.URL,
+		"ClientId":     idp.ClientId,
+		"ClientSecret": idp.ClientSecret,
+		"RedirectUrl":  fmt.Sprintf("http://localhost:3000/api/tenants/%s/sso/oidc/callback", s.defaultTenant.Id),
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("PUT", fmt.Sprintf("/api/tenants/%s/sso/oidc", s.defaultTenant.Id), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	return idp
+}
+
+// Starts the login, signs in with the IdP & returns the request that the IdP redirects the user's browser to
+func (s *IntegrationTestSuite) signInWithOidc(idp *oidcmock.Server) *http.Request {
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/sso/oidc/login", s.defaultTenant.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 302)
+
+	authorizationUrl := w.Header().Get("Location")
+	s.Contains(authorizationUrl, idp.URL+"/authorize", "The user should be redirected to the IdP")
+
+	// The browser would follow the IdP's redirect back to the callback
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(authorizationUrl)
+	if err != nil {
+		log.Fatalf("Could not sign in with the mock IdP: %s", err)
+	}
+	res.Body.Close()
+
+	callbackUrl, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	callback, err := http.NewRequest("GET", callbackUrl.RequestURI(), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+
+	return callback
+}
+
+func (s *IntegrationTestSuite) TestOidcLogin() {
+	idp := s.setUpOidc()
+	defer idp.Close()
+	idp.SetClaims(map[string]any{"email": s.defaultSupervisor.Email, "email_verified": true})
+
+	s.expectSelectQueryToReturnOneRow("oidc_configuration", map[string]any{"tenant_id": s.defaultTenant.Id, "email_claim": "email"})
+
+	r := s.signInWithOidc(idp)
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	var sessionCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == authSessionName {
+			sessionCookie = cookie
+		}
+	}
+	s.NotNil(sessionCookie, "The same session as a password login should be created")
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"SESSION-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHENTICATED"`, fmt.Sprintf(`"userId":"%s"`, s.defaultSupervisor.Id))
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// The state can only be used once
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-OIDC-STATE-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestOidcLoginWithCustomEmailClaim() {
+	idp := s.setUpOidc()
+	defer idp.Close()
+	idp.SetClaims(map[string]any{"sub": "12345", "upn": s.defaultSupervisor.Email})
+
+	_, err := s.dbRootConn.Exec("UPDATE oidc_configuration SET email_claim = 'upn'")
+	if err != nil {
+		log.Fatalf("Could not set the email claim: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.signInWithOidc(idp))
+	s.expectHttpStatus(w, 200)
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+}
+
+func (s *IntegrationTestSuite) TestOidcLoginWithCustomEmailClaimShouldRequireVerifiedEmail() {
+	idp := s.setUpOidc()
+	defer idp.Close()
+	idp.SetClaims(map[string]any{"sub": "12345", "upn": s.defaultSupervisor.Email, "email_verified": false})
+
+	_, err := s.dbRootConn.Exec("UPDATE oidc_configuration SET email_claim = 'upn'")
+	if err != nil {
+		log.Fatalf("Could not set the email claim: %s", err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.signInWithOidc(idp))
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+}
+
+func (s *IntegrationTestSuite) TestOidcLoginShouldFail() {
+	idp := s.setUpOidc()
+	defer idp.Close()
+
+	tests := []struct {
+		name       string
+		claims     map[string]any
+		wantReason string
+	}{
+		{
+			"Should fail because the user does not exist in the tenant",
+			map[string]any{"email": "unknown@hrisEnterprises.org"},
+			"USER-NOT-FOUND",
+		},
+		{
+			"Should fail because the email has not been verified",
+			map[string]any{"email": s.defaultSupervisor.Email, "email_verified": false},
+			"MISSING-EMAIL-CLAIM",
+		},
+		{
+			"Should fail because the email has not been verified, even if it is sent as a string",
+			map[string]any{"email": s.defaultSupervisor.Email, "email_verified": "false"},
+			"MISSING-EMAIL-CLAIM",
+		},
+		{
+			"Should fail because the email claim is missing",
+			map[string]any{"sub": "12345"},
+			"MISSING-EMAIL-CLAIM",
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			idp.SetClaims(test.claims)
+
+			r := s.signInWithOidc(idp)
+			s.logOutput.Reset()
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, 401)
+			s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+			s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+
+			reader := bufio.NewReader(s.logOutput)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+			s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"OIDC-LOGIN-FAILED"`, fmt.Sprintf(`"reason":"%s"`, test.wantReason))
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestOidcCallbackShouldValidateState() {
+	idp := s.setUpOidc()
+	defer idp.Close()
+	idp.SetClaims(map[string]any{"email": s.defaultSupervisor.Email})
+
+	r := s.signInWithOidc(idp)
+	query := r.URL.Query()
+	query.Set("state", "forged-state")
+	r.URL.RawQuery = query.Encode()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-OIDC-STATE-ERROR")
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+}
+
+func (s *IntegrationTestSuite) TestOidcLoginShouldValidateConfigurationExistence() {
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/sso/oidc/login", s.defaultTenant.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+// Discovery should only be done once per tenant & issuer, rather than on every login
+func TestOidcProviderCache(t *testing.T) {
+	discoveries := map[string]int{}
+	newIssuer := func() *httptest.Server {
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			discoveries[server.URL]++
+			json.NewEncoder(w).Encode(map[string]any{
+				"issuer":                 server.URL,
+				"authorization_endpoint": server.URL + "/authorize",
+				"token_endpoint":         server.URL + "/token",
+				"jwks_uri":               server.URL + "/keys",
+			})
+		}))
+		return server
+	}
+	issuer, otherIssuer := newIssuer(), newIssuer()
+	defer issuer.Close()
+	defer otherIssuer.Close()
+
+	cache := newOidcProviderCache()
+	tenantId := "2ad1dcfc-8867-49f7-87a3-8bd8d1154924"
+	for i := 0; i < 3; i++ {
+		_, err := cache.get(context.Background(), tenantId, issuer.URL)
+		if err != nil {
+			t.Fatalf("get() returned an error: %s", err)
+		}
+	}
+	if discoveries[issuer.URL] != 1 {
+		t.Errorf("the provider should have been discovered once, got %d discoveries", discoveries[issuer.URL])
+	}
+
+	// The tenant's issuer was changed
+	_, err := cache.get(context.Background(), tenantId, otherIssuer.URL)
+	if err != nil {
+		t.Fatalf("get() returned an error: %s", err)
+	}
+	if discoveries[otherIssuer.URL] != 1 {
+		t.Errorf("the new issuer should have been discovered, got %d discoveries", discoveries[otherIssuer.URL])
+	}
+}
@@ -0,0 +1,817 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const openApiVersion = "3.0.3"
+
+// An operation in the OpenAPI document, keyed by its method & route template in apiOperations
+// Request & response schemas are written by hand, as the handlers decode into their own local structs
+type apiOperation struct {
+	summary             string
+	tag                 string
+	public              bool // Does not require a session or API token
+	paginated           bool // Accepts the pagination & sorting query parameters
+	queryParameters     []apiParameter
+	requestBody         map[string]any
+	optionalRequestBody bool   // The request body can be omitted
+	requestContentType  string // Defaults to application/json
+	status              int
+	responseBody        map[string]any
+	responseHeaders     map[string]any
+	responseType        string // Defaults to application/json
+	errorCodes          []string
+}
+
+type apiParameter struct {
+	name        string
+	description string
+	required    bool
+}
+
+// The status of every error code that an operation can document. Codes of storage errors are listed by value, as the routes
+// package does not depend on the postgres package
+var errorCodeStatuses = map[string]int{
+	"INTERNAL-SERVER-ERROR":                     http.StatusInternalServerError,
+	"INPUT-VALIDATION-ERROR":                    http.StatusBadRequest,
+	"RESOURCE-NOT-FOUND-ERROR":                  http.StatusNotFound,
+	"UNIQUE-VIOLATION-ERROR":                    http.StatusConflict,
+	"INVALID-FOREIGN-KEY-ERROR":                 http.StatusBadRequest,
+	"INVALID-SORT-FIELD-ERROR":                  http.StatusBadRequest,
+	"INVALID-SORT-ORDER-ERROR":                  http.StatusBadRequest,
+	"INVALID-CURSOR-ERROR":                      http.StatusBadRequest,
+	"INVALID-PASSWORD-RESET-TOKEN-ERROR":        http.StatusBadRequest,
+	"INVALID-SUBORDINATE-SUPERVISOR-PAIR-ERROR": http.StatusBadRequest,
+	"MISSING-RECRUITER-ASSIGNMENT-ERROR":        http.StatusBadRequest,
+	"MISSING-RECRUITER-SHORTLIST-ERROR":         http.StatusConflict,
+	"MISSING-INTERVIEW-DATE-ERROR":              http.StatusConflict,
+	"MISSING-HIRING-MANAGER-OFFER-ERROR":        http.StatusForbidden,
+	Err404NotFound.Code:                         Err404NotFound.Status,
+	ErrInvalidJSON.Code:                         ErrInvalidJSON.Status,
+	ErrFileTooBig.Code:                          ErrFileTooBig.Status,
+	ErrUserUnauthenticated.Code:                 ErrUserUnauthenticated.Status,
+	ErrUserUnauthorised.Code:                    ErrUserUnauthorised.Status,
+	ErrPasswordChangeRequired.Code:              ErrPasswordChangeRequired.Status,
+	ErrLoginLocked.Code:                         ErrLoginLocked.Status,
+	ErrTotpEnrollmentRequired.Code:              ErrTotpEnrollmentRequired.Status,
+	ErrTotpAlreadyEnrolled.Code:                 ErrTotpAlreadyEnrolled.Status,
+	ErrInvalidTotp.Code:                         ErrInvalidTotp.Status,
+	ErrInvalidTotpEnrollmentToken.Code:          ErrInvalidTotpEnrollmentToken.Status,
+	ErrInvalidOidcState.Code:                    ErrInvalidOidcState.Status,
+	ErrInvalidSamlMetadata.Code:                 ErrInvalidSamlMetadata.Status,
+	ErrInvalidSamlResponse.Code:                 ErrInvalidSamlResponse.Status,
+	ErrCrossOriginRequest.Code:                  ErrCrossOriginRequest.Status,
+	ErrInvalidSupervisor.Code:                   ErrInvalidSupervisor.Status,
+	ErrInvalidHrApprover.Code:                   ErrInvalidHrApprover.Status,
+	ErrMissingSupervisorApproval.Code:           ErrMissingSupervisorApproval.Status,
+	ErrMissingHrApproval.Code:                   ErrMissingHrApproval.Status,
+	ErrJobRequisitionAlreadyFilled.Code:         ErrJobRequisitionAlreadyFilled.Status,
+}
+
+// Schema helpers, so that the operations below read like the handlers' structs
+func objectSchema(required []string, properties map[string]any) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringSchema(description string) map[string]any {
+	schema := map[string]any{"type": "string"}
+	if description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+func formattedStringSchema(format string) map[string]any {
+	return map[string]any{"type": "string", "format": format}
+}
+
+func enumSchema(values ...string) map[string]any {
+	return map[string]any{"type": "string", "enum": values}
+}
+
+func arraySchema(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+func refSchema(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+var (
+	nameRequestBody = objectSchema([]string{"name"}, map[string]any{
+		"name": stringSchema(""),
+	})
+	credentialsRequestBody = objectSchema([]string{"tenantId", "email", "password", "totp"}, map[string]any{
+		"tenantId": formattedStringSchema("uuid"),
+		"email":    stringSchema(""),
+		"password": stringSchema(""),
+		"totp":     stringSchema("A TOTP or one of the user's recovery codes"),
+	})
+	apiTokenRequestBody = objectSchema([]string{"name", "scopes"}, map[string]any{
+		"name":      stringSchema(""),
+		"scopes":    arraySchema(enumSchema("read", "write")),
+		"expiresAt": formattedStringSchema("date-time"),
+	})
+	apiTokenResponseBody = objectSchema(nil, map[string]any{
+		"token":     stringSchema("Only returned once. Sent as a bearer token in the Authorization header"),
+		"expiresAt": formattedStringSchema("date-time"),
+	})
+	passwordResponseBody = objectSchema(nil, map[string]any{
+		"password": stringSchema("The user's initial password, which must be changed on their first login"),
+	})
+	redirectHeaders = map[string]any{
+		"Location": map[string]any{"description": "The identity provider's sign in page", "schema": stringSchema("")},
+	}
+	paginationParameters = []apiParameter{
+		{"limit", "The number of results per page, from 1 to 100. Defaults to 20", false},
+		{"cursor", "The nextCursor of the previous page", false},
+		{"offset", "The number of results to skip. Cannot be used together with cursor", false},
+		{"sortBy", "The field to sort the results by", false},
+		{"sortOrder", "asc or desc", false},
+	}
+)
+
+// Every route in NewRouter must have an operation here. TestOpenApiShouldDocumentEveryRoute fails otherwise
+var apiOperations = map[string]apiOperation{
+	"GET /healthz": {
+		summary: "Liveness probe", tag: "operations", public: true, status: http.StatusOK,
+		responseBody: objectSchema(nil, map[string]any{"status": enumSchema("ok")}),
+	},
+	"GET /readyz": {
+		summary: "Readiness probe. Responds with 503 if a dependency cannot be reached", tag: "operations", public: true, status: http.StatusOK,
+		responseBody: objectSchema(nil, map[string]any{
+			"status": enumSchema("ok", "unavailable"),
+			"checks": map[string]any{"type": "object", "additionalProperties": stringSchema("")},
+		}),
+	},
+	"GET /api/openapi.json": {
+		summary: "This OpenAPI document", tag: "operations", public: true, status: http.StatusOK,
+		responseBody: map[string]any{"type": "object"},
+	},
+
+	"POST /api/session": {
+		summary: "Log in with a password & TOTP", tag: "sessions", public: true, status: http.StatusOK,
+		requestBody: credentialsRequestBody,
+		errorCodes:  []string{"USER-UNAUTHENTICATED", "LOGIN-LOCKED", "PASSWORD-CHANGE-REQUIRED", "TOTP-ENROLLMENT-REQUIRED"},
+	},
+	"DELETE /api/session": {
+		summary: "Log out of the current session", tag: "sessions", public: true, status: http.StatusOK,
+	},
+	"PUT /api/password": {
+		summary: "Change the user's password", tag: "passwords", public: true, status: http.StatusOK,
+		requestBody: objectSchema([]string{"tenantId", "email", "oldPassword", "totp", "newPassword"}, map[string]any{
+			"tenantId":    formattedStringSchema("uuid"),
+			"email":       stringSchema(""),
+			"oldPassword": stringSchema(""),
+			"totp":        stringSchema("A TOTP or one of the user's recovery codes"),
+			"newPassword": stringSchema("12 to 64 characters, & different from the old password"),
+		}),
+		errorCodes: []string{"USER-UNAUTHENTICATED", "LOGIN-LOCKED", "TOTP-ENROLLMENT-REQUIRED"},
+	},
+	"POST /api/password-reset": {
+		summary: "Reset the user's password with a password reset token", tag: "passwords", public: true, status: http.StatusOK,
+		requestBody: objectSchema([]string{"tenantId", "email", "token", "newPassword"}, map[string]any{
+			"tenantId":    formattedStringSchema("uuid"),
+			"email":       stringSchema(""),
+			"token":       stringSchema(""),
+			"totp":        stringSchema("Required if the user has enrolled in TOTP"),
+			"newPassword": stringSchema("12 to 64 characters"),
+		}),
+		errorCodes: []string{"LOGIN-LOCKED", "INVALID-PASSWORD-RESET-TOKEN-ERROR"},
+	},
+	"POST /api/totp-enrollment": {
+		summary: "Start enrolling in TOTP", tag: "totp", public: true, status: http.StatusOK,
+		requestBody: objectSchema([]string{"tenantId", "email", "password"}, map[string]any{
+			"tenantId":        formattedStringSchema("uuid"),
+			"email":           stringSchema(""),
+			"password":        stringSchema(""),
+			"enrollmentToken": stringSchema("Required if the user's TOTP has been reset by an admin"),
+		}),
+		responseBody: objectSchema(nil, map[string]any{
+			"otpauthUri": stringSchema(""),
+			"qrCode":     stringSchema("Base64 encoded PNG of the otpauth URI"),
+		}),
+		errorCodes: []string{"USER-UNAUTHENTICATED", "LOGIN-LOCKED", "TOTP-ALREADY-ENROLLED-ERROR", "INVALID-TOTP-ENROLLMENT-TOKEN-ERROR",
+			"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"PUT /api/totp-enrollment": {
+		summary: "Confirm the TOTP enrollment with a TOTP from the authenticator app", tag: "totp", public: true, status: http.StatusOK,
+		requestBody: objectSchema([]string{"tenantId", "email", "password", "totp"}, map[string]any{
+			"tenantId":        formattedStringSchema("uuid"),
+			"email":           stringSchema(""),
+			"password":        stringSchema(""),
+			"totp":            stringSchema("A TOTP from the authenticator app being enrolled"),
+			"enrollmentToken": stringSchema("Required if the user's TOTP has been reset by an admin"),
+		}),
+		responseBody: objectSchema(nil, map[string]any{
+			"recoveryCodes": arraySchema(stringSchema("")),
+		}),
+		errorCodes: []string{"USER-UNAUTHENTICATED", "LOGIN-LOCKED", "TOTP-ALREADY-ENROLLED-ERROR", "INVALID-TOTP-ENROLLMENT-TOKEN-ERROR",
+			"INVALID-TOTP-ERROR", "RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}": {
+		summary: "Create a tenant", tag: "tenants", status: http.StatusCreated,
+		requestBody: nameRequestBody,
+		errorCodes:  []string{"UNIQUE-VIOLATION-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/divisions/{divisionId}": {
+		summary: "Create a division", tag: "tenants", status: http.StatusCreated,
+		requestBody: nameRequestBody,
+		errorCodes:  []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/divisions/{divisionId}/departments/{departmentId}": {
+		summary: "Create a department", tag: "tenants", status: http.StatusCreated,
+		requestBody: nameRequestBody,
+		errorCodes:  []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/policies": {
+		summary: "Allow a role to access resources", tag: "authorization", status: http.StatusCreated,
+		requestBody: objectSchema([]string{"subject", "resources"}, map[string]any{
+			"subject":   stringSchema("The role's name"),
+			"resources": arraySchema(refSchema("Resource")),
+		}),
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/policies": {
+		summary: "List the resources that each role or user of the tenant can access", tag: "authorization", status: http.StatusOK,
+		responseBody: arraySchema(refSchema("Policies")),
+	},
+	"GET /api/tenants/{tenantId}/policies/{subject}": {
+		summary: "Get the resources that a role or user can access", tag: "authorization", status: http.StatusOK,
+		responseBody: refSchema("Policies"),
+		errorCodes:   []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"PUT /api/tenants/{tenantId}/policies/{subject}": {
+		summary: "Replace the resources that a role or user can access", tag: "authorization", status: http.StatusOK,
+		requestBody: objectSchema([]string{"resources"}, map[string]any{
+			"resources": arraySchema(refSchema("Resource")),
+		}),
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR"},
+	},
+	"DELETE /api/tenants/{tenantId}/policies/{subject}": {
+		summary: "Remove every policy of a role or user, or only the policy of a resource", tag: "authorization", status: http.StatusNoContent,
+		queryParameters: []apiParameter{
+			{"path", "The resource's path. Required if method is provided", false},
+			{"method", "The resource's method. Required if path is provided", false},
+		},
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/authorization-decision": {
+		summary: "Explain whether a role or user can access a resource, without accessing it", tag: "authorization", status: http.StatusOK,
+		queryParameters: []apiParameter{
+			{"subject", "A role or a user id", true},
+			{"path", "The resource's path, e.g. /api/tenants/{tenantId}/users/{userId} with the ids filled in", true},
+			{"method", "The resource's method", true},
+		},
+		responseBody: objectSchema(nil, map[string]any{
+			"subject":    stringSchema(""),
+			"tenantId":   formattedStringSchema("uuid"),
+			"path":       stringSchema(""),
+			"method":     stringSchema(""),
+			"authorized": map[string]any{"type": "boolean"},
+			"policy":     refSchema("Policy"),
+			"roleChain":  arraySchema(stringSchema("The subject, followed by the roles it inherits up to the policy's subject")),
+		}),
+	},
+
+	"PUT /api/tenants/{tenantId}/sso/oidc": {
+		summary: "Set the tenant's OIDC configuration", tag: "sso", status: http.StatusOK,
+		requestBody: objectSchema([]string{"issuer", "clientId", "clientSecret", "redirectUrl", "emailClaim"}, map[string]any{
+			"issuer":       formattedStringSchema("uri"),
+			"clientId":     stringSchema(""),
+			"clientSecret": stringSchema(""),
+			"redirectUrl":  formattedStringSchema("uri"),
+			"emailClaim":   stringSchema("The ID token claim that holds the user's email. Logins are rejected if the token marks the email as unverified (email_verified)"),
+		}),
+		errorCodes: []string{"INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/sso/oidc/login": {
+		summary: "Redirect to the tenant's OIDC provider to sign in", tag: "sso", public: true, status: http.StatusFound,
+		responseHeaders: redirectHeaders,
+		errorCodes:      []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/sso/oidc/callback": {
+		summary: "Complete an OIDC sign in & start a session", tag: "sso", public: true, status: http.StatusOK,
+		queryParameters: []apiParameter{
+			{"state", "The state sent to the identity provider", true},
+			{"code", "The authorization code", false},
+		},
+		errorCodes: []string{"INVALID-OIDC-STATE-ERROR", "USER-UNAUTHENTICATED", "RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"PUT /api/tenants/{tenantId}/sso/saml": {
+		summary: "Set the tenant's SAML configuration", tag: "sso", status: http.StatusOK,
+		requestBody: objectSchema([]string{"entityId", "acsUrl", "idpMetadata"}, map[string]any{
+			"entityId":        stringSchema(""),
+			"acsUrl":          formattedStringSchema("uri"),
+			"idpMetadata":     stringSchema("The identity provider's metadata XML"),
+			"emailAttribute":  stringSchema("The assertion attribute that holds the user's email. Defaults to the NameID"),
+			"jitProvisioning": map[string]any{"type": "boolean", "description": "Whether users are created on their first sign in"},
+		}),
+		errorCodes: []string{"INVALID-SAML-METADATA-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/sso/saml/metadata": {
+		summary: "The tenant's SAML service provider metadata", tag: "sso", public: true, status: http.StatusOK,
+		responseBody: stringSchema(""), responseType: "application/samlmetadata+xml",
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/sso/saml/login": {
+		summary: "Redirect to the tenant's SAML identity provider to sign in", tag: "sso", public: true, status: http.StatusFound,
+		responseHeaders: redirectHeaders,
+		errorCodes:      []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/sso/saml/acs": {
+		summary: "Complete a SAML sign in & start a session. Must be POSTed by the browser that started the sign in", tag: "sso", public: true, status: http.StatusOK,
+		requestContentType: "application/x-www-form-urlencoded",
+		requestBody: objectSchema([]string{"SAMLResponse"}, map[string]any{
+			"SAMLResponse": stringSchema("Base64 encoded"),
+			"RelayState":   stringSchema(""),
+		}),
+		errorCodes: []string{"INVALID-SAML-RESPONSE-ERROR", "USER-UNAUTHENTICATED", "RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/service-accounts/{serviceAccountId}": {
+		summary: "Create a service account", tag: "api tokens", status: http.StatusCreated,
+		requestBody: nameRequestBody,
+		errorCodes:  []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/service-accounts/{serviceAccountId}/api-tokens/{apiTokenId}": {
+		summary: "Issue an API token to a service account", tag: "api tokens", status: http.StatusCreated,
+		requestBody: apiTokenRequestBody, responseBody: apiTokenResponseBody,
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"DELETE /api/tenants/{tenantId}/service-accounts/{serviceAccountId}/api-tokens/{apiTokenId}": {
+		summary: "Revoke a service account's API token", tag: "api tokens", status: http.StatusNoContent,
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/positions/{positionId}": {
+		summary: "Create a position", tag: "positions", status: http.StatusCreated,
+		requestBody: objectSchema([]string{"title", "departmentId", "supervisorPositionIds"}, map[string]any{
+			"title":                 stringSchema(""),
+			"departmentId":          formattedStringSchema("uuid"),
+			"supervisorPositionIds": arraySchema(formattedStringSchema("uuid")),
+		}),
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR", "INVALID-SUBORDINATE-SUPERVISOR-PAIR-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/job-applications/{jobApplicationId}": {
+		summary: "Apply for a job with a resume", tag: "job applications", public: true, status: http.StatusCreated,
+		requestContentType: "multipart/form-data",
+		requestBody: objectSchema([]string{"data", "resume"}, map[string]any{
+			"data": objectSchema([]string{"jobRequisitionId", "firstName", "lastName", "countryCode", "phoneNumber", "email"}, map[string]any{
+				"jobRequisitionId": formattedStringSchema("uuid"),
+				"firstName":        stringSchema("Letters only"),
+				"lastName":         stringSchema("Letters only"),
+				"countryCode":      stringSchema("Digits only"),
+				"phoneNumber":      stringSchema("Digits only"),
+				"email":            formattedStringSchema("email"),
+			}),
+			"resume": map[string]any{"type": "string", "format": "binary", "description": "A .pdf or .docx file"},
+		}),
+		errorCodes: []string{"FILE-TOO-BIG-ERROR", "RESOURCE-NOT-FOUND-ERROR", "UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/users/{userId}": {
+		summary: "Create a user", tag: "users", status: http.StatusCreated,
+		requestBody: objectSchema([]string{"email"}, map[string]any{
+			"email": formattedStringSchema("email"),
+		}),
+		responseBody: passwordResponseBody,
+		errorCodes:   []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/positions/{positionId}": {
+		summary: "Assign a user to a position", tag: "positions", status: http.StatusCreated,
+		requestBody: objectSchema([]string{"startDate"}, map[string]any{
+			"startDate": formattedStringSchema("date"),
+			"endDate":   formattedStringSchema("date"),
+		}),
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/roles": {
+		summary: "List the user's roles", tag: "authorization", status: http.StatusOK,
+		responseBody: arraySchema(objectSchema(nil, map[string]any{
+			"role":       stringSchema(""),
+			"validFrom":  formattedStringSchema("date-time"),
+			"validUntil": formattedStringSchema("date-time"),
+			"createdAt":  formattedStringSchema("date-time"),
+			"updatedAt":  formattedStringSchema("date-time"),
+		})),
+	},
+	"PUT /api/tenants/{tenantId}/users/{userId}/roles": {
+		summary: "Replace the user's roles. An empty list removes all of them", tag: "authorization", status: http.StatusOK,
+		requestBody: objectSchema([]string{"roles"}, map[string]any{
+			"roles": arraySchema(stringSchema("")),
+		}),
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/roles/{roleName}": {
+		summary: "Assign a role to a user, optionally for a period. The role is ignored outside of it & is deleted once it expires. The policy is reloaded when the period starts & ends, so it can take a few seconds to take effect", tag: "authorization", status: http.StatusCreated,
+		requestBody: objectSchema(nil, map[string]any{
+			"validFrom":  formattedStringSchema("date-time"),
+			"validUntil": formattedStringSchema("date-time"),
+		}),
+		optionalRequestBody: true,
+		errorCodes:          []string{"UNIQUE-VIOLATION-ERROR"},
+	},
+	"DELETE /api/tenants/{tenantId}/users/{userId}/roles/{roleName}": {
+		summary: "Remove a role from a user", tag: "authorization", status: http.StatusNoContent,
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/permissions": {
+		summary: "List every resource that the user can access, including those of inherited roles & of the PUBLIC role", tag: "authorization", status: http.StatusOK,
+		responseBody: objectSchema(nil, map[string]any{
+			"roles":       arraySchema(stringSchema("")),
+			"permissions": arraySchema(refSchema("Policy")),
+		}),
+	},
+
+	"GET /api/tenants/{tenantId}/users/{userId}/sessions": {
+		summary: "List the user's active sessions", tag: "sessions", status: http.StatusOK,
+		responseBody: arraySchema(objectSchema(nil, map[string]any{
+			"id":        formattedStringSchema("uuid"),
+			"clientIp":  stringSchema(""),
+			"userAgent": stringSchema(""),
+			"current":   map[string]any{"type": "boolean", "description": "Whether the session is the one used to make the request"},
+			"expiresAt": formattedStringSchema("date-time"),
+			"createdAt": formattedStringSchema("date-time"),
+			"updatedAt": formattedStringSchema("date-time"),
+		})),
+	},
+	"DELETE /api/tenants/{tenantId}/users/{userId}/sessions": {
+		summary: "Revoke all of the user's sessions", tag: "sessions", status: http.StatusOK,
+	},
+	"DELETE /api/tenants/{tenantId}/users/{userId}/sessions/{sessionId}": {
+		summary: "Revoke one of the user's sessions", tag: "sessions", status: http.StatusOK,
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/users/{userId}/api-tokens/{apiTokenId}": {
+		summary: "Issue an API token to a user", tag: "api tokens", status: http.StatusCreated,
+		requestBody: apiTokenRequestBody, responseBody: apiTokenResponseBody,
+		errorCodes: []string{"UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+	"DELETE /api/tenants/{tenantId}/users/{userId}/api-tokens/{apiTokenId}": {
+		summary: "Revoke a user's API token", tag: "api tokens", status: http.StatusNoContent,
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/users/{userId}/password-reset-token": {
+		summary: "Issue a password reset token for the user", tag: "passwords", status: http.StatusCreated,
+		responseBody: objectSchema(nil, map[string]any{
+			"token":     stringSchema(""),
+			"expiresAt": formattedStringSchema("date-time"),
+		}),
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"DELETE /api/tenants/{tenantId}/users/{userId}/totp": {
+		summary: "Reset the user's TOTP, so that they must enroll again with the returned enrollment token", tag: "totp", status: http.StatusOK,
+		responseBody: objectSchema(nil, map[string]any{
+			"enrollmentToken": stringSchema("Only shown once"),
+			"expiresAt":       formattedStringSchema("date-time"),
+		}),
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor": {
+		summary: "List the job requisitions that the user requested", tag: "job requisitions", paginated: true, status: http.StatusOK,
+		responseBody: refSchema("JobRequisitionPage"),
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor/{jobRequisitionId}": {
+		summary: "Get a job requisition that the user requested", tag: "job requisitions", status: http.StatusOK,
+		responseBody: refSchema("JobRequisition"),
+		errorCodes:   []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-supervisor": {
+		summary: "List the job requisitions that the user supervises", tag: "job requisitions", paginated: true, status: http.StatusOK,
+		responseBody: refSchema("JobRequisitionPage"),
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-supervisor/{jobRequisitionId}": {
+		summary: "Get a job requisition that the user supervises", tag: "job requisitions", status: http.StatusOK,
+		responseBody: refSchema("JobRequisition"),
+		errorCodes:   []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-hr-approver": {
+		summary: "List the job requisitions that the user is the HR approver of", tag: "job requisitions", paginated: true, status: http.StatusOK,
+		responseBody: refSchema("JobRequisitionPage"),
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-hr-approver/{jobRequisitionId}": {
+		summary: "Get a job requisition that the user is the HR approver of", tag: "job requisitions", status: http.StatusOK,
+		responseBody: refSchema("JobRequisition"),
+		errorCodes:   []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter": {
+		summary: "List the job requisitions that the user recruits for", tag: "job requisitions", paginated: true, status: http.StatusOK,
+		responseBody: refSchema("JobRequisitionPage"),
+	},
+	"GET /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}": {
+		summary: "Get a job requisition that the user recruits for", tag: "job requisitions", status: http.StatusOK,
+		responseBody: refSchema("JobRequisition"),
+		errorCodes:   []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor/{jobRequisitionId}": {
+		summary: "Request a job requisition for an existing or a new position", tag: "job requisitions", status: http.StatusCreated,
+		requestBody: objectSchema([]string{"jobDescription", "jobRequirements", "supervisor", "hrApprover"}, map[string]any{
+			"positionId":            map[string]any{"type": "string", "format": "uuid", "description": "Required if title, departmentId & supervisorPositionIds are not provided"},
+			"title":                 stringSchema("Required if positionId is not provided"),
+			"departmentId":          map[string]any{"type": "string", "format": "uuid", "description": "Required if positionId is not provided"},
+			"supervisorPositionIds": arraySchema(formattedStringSchema("uuid")),
+			"jobDescription":        stringSchema(""),
+			"jobRequirements":       stringSchema(""),
+			"supervisor":            formattedStringSchema("uuid"),
+			"hrApprover":            formattedStringSchema("uuid"),
+		}),
+		errorCodes: []string{"INVALID-SUPERVISOR-ERROR", "INVALID-HR-APPROVER-ERROR", "UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR", "INVALID-SUBORDINATE-SUPERVISOR-PAIR-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-supervisor/{jobRequisitionId}/supervisor-decision": {
+		summary: "Approve or reject a job requisition as its supervisor", tag: "job requisitions", status: http.StatusNoContent,
+		requestBody: objectSchema([]string{"supervisorDecision", "password", "totp"}, map[string]any{
+			"supervisorDecision": enumSchema("APPROVED", "REJECTED"),
+			"password":           stringSchema(""),
+			"totp":               stringSchema("A TOTP or one of the user's recovery codes"),
+		}),
+		errorCodes: []string{"USER-UNAUTHENTICATED", "LOGIN-LOCKED", "TOTP-ENROLLMENT-REQUIRED", "RESOURCE-NOT-FOUND-ERROR", "JOB-REQUISITION-ALREADY-FILLED"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-hr-approver/{jobRequisitionId}/hr-approver-decision": {
+		summary: "Approve or reject a job requisition as its HR approver", tag: "job requisitions", status: http.StatusNoContent,
+		requestBody: objectSchema([]string{"hrApproverDecision", "password", "totp"}, map[string]any{
+			"hrApproverDecision": enumSchema("APPROVED", "REJECTED"),
+			"recruiter":          map[string]any{"type": "string", "format": "uuid", "description": "Required if the job requisition is approved"},
+			"password":           stringSchema(""),
+			"totp":               stringSchema("A TOTP or one of the user's recovery codes"),
+		}),
+		errorCodes: []string{"USER-UNAUTHENTICATED", "LOGIN-LOCKED", "TOTP-ENROLLMENT-REQUIRED", "RESOURCE-NOT-FOUND-ERROR", "MISSING-SUPERVISOR-APPROVAL-ERROR",
+			"MISSING-RECRUITER-ASSIGNMENT-ERROR", "JOB-REQUISITION-ALREADY-FILLED"},
+	},
+
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/recruiter-decision": {
+		summary: "Shortlist or reject a job application", tag: "job applications", status: http.StatusNoContent,
+		requestBody: objectSchema([]string{"recruiterDecision"}, map[string]any{
+			"recruiterDecision": enumSchema("SHORTLISTED", "REJECTED"),
+		}),
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/interview-date": {
+		summary: "Set the interview date of a shortlisted job application", tag: "job applications", status: http.StatusNoContent,
+		requestBody: objectSchema([]string{"interviewDate"}, map[string]any{
+			"interviewDate": formattedStringSchema("date"),
+		}),
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR", "MISSING-RECRUITER-SHORTLIST-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor/{jobRequisitionId}/job-applications/{jobApplicationId}/hiring-manager-decision": {
+		summary: "Make an offer to or reject an interviewed applicant", tag: "job applications", status: http.StatusNoContent,
+		requestBody: objectSchema([]string{"hiringManagerDecision", "offerStartDate"}, map[string]any{
+			"hiringManagerDecision": enumSchema("OFFERED", "REJECTED"),
+			"offerStartDate":        formattedStringSchema("date"),
+			"offerEndDate":          formattedStringSchema("date"),
+		}),
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR", "MISSING-INTERVIEW-DATE-ERROR"},
+	},
+	"POST /api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/applicant-decision": {
+		summary: "Record whether the applicant accepted the offer. Accepted applicants are onboarded as users", tag: "job applications", status: http.StatusNoContent,
+		requestBody: objectSchema([]string{"applicantDecision"}, map[string]any{
+			"applicantDecision": enumSchema("ACCEPTED", "REJECTED"),
+		}),
+		errorCodes: []string{"RESOURCE-NOT-FOUND-ERROR", "MISSING-HIRING-MANAGER-OFFER-ERROR", "UNIQUE-VIOLATION-ERROR", "INVALID-FOREIGN-KEY-ERROR"},
+	},
+}
+
+var openApiSchemas = map[string]any{
+	"Problem": objectSchema([]string{"type", "title", "status", "detail", "code"}, map[string]any{
+		"type":   stringSchema("Always about:blank"),
+		"title":  stringSchema("The status' text"),
+		"status": map[string]any{"type": "integer"},
+		"detail": stringSchema("A message in the language of the Accept-Language header"),
+		"code":   stringSchema("Identifies the error, e.g. INPUT-VALIDATION-ERROR"),
+		"errors": arraySchema(objectSchema([]string{"field", "tag", "message"}, map[string]any{
+			"field":   stringSchema("The input's name in the request, e.g. scopes[1]"),
+			"tag":     stringSchema("The check that failed, e.g. required or unique"),
+			"message": stringSchema(""),
+		})),
+		"traceId": stringSchema("The trace of the request, if tracing is enabled"),
+	}),
+	"Resource": objectSchema([]string{"path", "method"}, map[string]any{
+		"path":   stringSchema("A route template, e.g. /api/tenants/{tenantId}/users/{userId}"),
+		"method": enumSchema("POST", "GET", "PUT", "DELETE"),
+	}),
+	"Policies": objectSchema(nil, map[string]any{
+		"subject":   stringSchema("A role or a user id"),
+		"resources": arraySchema(refSchema("Resource")),
+		"createdAt": formattedStringSchema("date-time"),
+		"updatedAt": formattedStringSchema("date-time"),
+	}),
+	"Policy": objectSchema(nil, map[string]any{
+		"subject":  stringSchema("The role or user that is allowed"),
+		"tenantId": stringSchema("The tenant's id, or * if the policy applies to every tenant"),
+		"path":     stringSchema(""),
+		"method":   stringSchema(""),
+	}),
+	"JobRequisition": objectSchema(nil, map[string]any{
+		"id":                    formattedStringSchema("uuid"),
+		"tenantId":              formattedStringSchema("uuid"),
+		"positionId":            formattedStringSchema("uuid"),
+		"title":                 stringSchema(""),
+		"departmentId":          formattedStringSchema("uuid"),
+		"supervisorPositionIds": arraySchema(formattedStringSchema("uuid")),
+		"jobDescription":        stringSchema(""),
+		"jobRequirements":       stringSchema(""),
+		"requestor":             formattedStringSchema("uuid"),
+		"supervisor":            formattedStringSchema("uuid"),
+		"supervisorDecision":    enumSchema("", "APPROVED", "REJECTED"),
+		"hrApprover":            formattedStringSchema("uuid"),
+		"hrApproverDecision":    enumSchema("", "APPROVED", "REJECTED"),
+		"recruiter":             stringSchema(""),
+		"filledBy":              stringSchema(""),
+		"filledAt":              stringSchema("RFC 3339 timestamp, or empty if the job requisition is unfilled"),
+		"createdAt":             formattedStringSchema("date-time"),
+		"updatedAt":             formattedStringSchema("date-time"),
+	}),
+	"JobRequisitionPage": objectSchema(nil, map[string]any{
+		"jobRequisitions": arraySchema(refSchema("JobRequisition")),
+		"nextCursor":      stringSchema("Empty if there are no more pages"),
+	}),
+}
+
+var pathParameterPattern = regexp.MustCompile(`{(\w+)}`)
+
+// Builds the OpenAPI document from the routes registered on the router, so that a route cannot be left out
+func (router *Router) openApiDocument() map[string]any {
+	paths := map[string]any{}
+
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// Subrouters' path prefixes have no methods
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		for _, method := range methods {
+			operation, ok := apiOperations[method+" "+pathTemplate]
+			if !ok {
+				continue
+			}
+
+			pathItem, ok := paths[pathTemplate].(map[string]any)
+			if !ok {
+				pathItem = map[string]any{}
+				paths[pathTemplate] = pathItem
+			}
+			pathItem[strings.ToLower(method)] = operation.document(method, pathTemplate)
+		}
+		return nil
+	})
+
+	return map[string]any{
+		"openapi": openApiVersion,
+		"info": map[string]any{
+			"title":   "Multi-Tenant HRIS API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": openApiSchemas,
+			"securitySchemes": map[string]any{
+				"session":  map[string]any{"type": "apiKey", "in": "cookie", "name": authSessionName},
+				"apiToken": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{
+			map[string]any{"session": []string{}},
+			map[string]any{"apiToken": []string{}},
+		},
+	}
+}
+
+func (operation apiOperation) document(method string, pathTemplate string) map[string]any {
+	document := map[string]any{
+		"summary":     operation.summary,
+		"tags":        []string{operation.tag},
+		"operationId": method + " " + pathTemplate,
+	}
+	if operation.public {
+		document["security"] = []any{}
+	}
+
+	parameters := []any{}
+	for _, match := range pathParameterPattern.FindAllStringSubmatch(pathTemplate, -1) {
+		schema := stringSchema("")
+		if strings.HasSuffix(match[1], "Id") {
+			schema = formattedStringSchema("uuid")
+		}
+		parameters = append(parameters, map[string]any{"name": match[1], "in": "path", "required": true, "schema": schema})
+	}
+	queryParameters := operation.queryParameters
+	if operation.paginated {
+		queryParameters = append(queryParameters, paginationParameters...)
+	}
+	for _, parameter := range queryParameters {
+		parameters = append(parameters, map[string]any{
+			"name": parameter.name, "in": "query", "required": parameter.required, "description": parameter.description, "schema": stringSchema(""),
+		})
+	}
+	if len(parameters) > 0 {
+		document["parameters"] = parameters
+	}
+
+	if operation.requestBody != nil {
+		contentType := operation.requestContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		content := map[string]any{"schema": operation.requestBody}
+		// The job application's fields are sent as a JSON part alongside the resume
+		if contentType == "multipart/form-data" {
+			content["encoding"] = map[string]any{"data": map[string]any{"contentType": "application/json"}}
+		}
+		document["requestBody"] = map[string]any{"required": !operation.optionalRequestBody, "content": map[string]any{contentType: content}}
+	}
+
+	response := map[string]any{"description": http.StatusText(operation.status)}
+	if operation.responseBody != nil {
+		responseType := operation.responseType
+		if responseType == "" {
+			responseType = "application/json"
+		}
+		response["content"] = map[string]any{responseType: map[string]any{"schema": operation.responseBody}}
+	}
+	if operation.responseHeaders != nil {
+		response["headers"] = operation.responseHeaders
+	}
+	responses := map[string]any{strconv.Itoa(operation.status): response}
+
+	for status, codes := range operation.errorCodesByStatus(pathTemplate, method) {
+		responses[strconv.Itoa(status)] = map[string]any{
+			"description": http.StatusText(status) + ": " + strings.Join(codes, ", "),
+			"content": map[string]any{
+				"application/problem+json": map[string]any{
+					"schema": map[string]any{
+						"allOf": []any{refSchema("Problem"), objectSchema(nil, map[string]any{"code": enumSchema(codes...)})},
+					},
+				},
+			},
+		}
+	}
+	document["responses"] = responses
+
+	return document
+}
+
+// Adds the errors of the middleware & of validation to the operation's own error codes
+func (operation apiOperation) errorCodesByStatus(pathTemplate string, method string) map[int][]string {
+	codes := append([]string{"INTERNAL-SERVER-ERROR"}, operation.errorCodes...)
+	if pathParameterPattern.MatchString(pathTemplate) || operation.requestBody != nil || operation.paginated || operation.queryParameters != nil {
+		codes = append(codes, "INPUT-VALIDATION-ERROR")
+	}
+	if operation.requestBody != nil && (operation.requestContentType == "" || operation.requestContentType == "multipart/form-data") {
+		codes = append(codes, ErrInvalidJSON.Code)
+	}
+	if operation.paginated {
+		codes = append(codes, "INVALID-SORT-FIELD-ERROR", "INVALID-SORT-ORDER-ERROR", "INVALID-CURSOR-ERROR")
+	}
+	if strings.HasPrefix(pathTemplate, "/api/") {
+		if !operation.public {
+			codes = append(codes, ErrUserUnauthenticated.Code, ErrUserUnauthorised.Code)
+		}
+		if method != http.MethodGet {
+			codes = append(codes, ErrCrossOriginRequest.Code)
+		}
+	}
+
+	codesByStatus := map[int][]string{}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		status := errorCodeStatuses[code]
+		codesByStatus[status] = append(codesByStatus[status], code)
+	}
+	for _, codes := range codesByStatus {
+		sort.Strings(codes)
+	}
+	return codesByStatus
+}
+
+func (router *Router) handleGetOpenApiDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(router.openApiDocument())
+}
@@ -0,0 +1,158 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+)
+
+// A router with every route of NewRouter, whose dependencies are never called
+func newRouterWithoutDependencies(t *testing.T) *Router {
+	universalTranslator := NewUniversalTranslator()
+	validate, err := NewValidator(universalTranslator)
+	if err != nil {
+		t.Fatalf("Validator failed to instantiate: %s", err.Error())
+	}
+
+	return NewRouter(tracedStorage{}, tracedFileStorage{}, universalTranslator, validate, NewRootLogger(io.Discard),
+		sessions.NewCookieStore([]byte("key")), nil, LockoutPolicy{}, SessionPolicy{})
+}
+
+// Fails if a route is added without documenting it, or if a documented route is removed
+func TestOpenApiShouldDocumentEveryRoute(t *testing.T) {
+	router := newRouterWithoutDependencies(t)
+
+	// The path prefixes of subrouters are not endpoints, so they have no methods. Every other route must restrict its methods,
+	// so that it cannot be left out of the specification
+	subrouterPrefixes := map[string]bool{
+		"/api":                                   true,
+		"/api/tenants/{tenantId}":                true,
+		"/api/tenants/{tenantId}/users/{userId}": true,
+	}
+
+	routes := map[string]bool{}
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			t.Errorf("Route has no path template: %s", err)
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			if !subrouterPrefixes[pathTemplate] {
+				t.Errorf("%s has no methods, so it cannot be documented in apiOperations", pathTemplate)
+			}
+			return nil
+		}
+		for _, method := range methods {
+			routes[method+" "+pathTemplate] = true
+		}
+		return nil
+	})
+
+	for route := range routes {
+		if _, ok := apiOperations[route]; !ok {
+			t.Errorf("%s is not documented in apiOperations", route)
+		}
+	}
+	for route := range apiOperations {
+		if !routes[route] {
+			t.Errorf("%s is documented in apiOperations but is not a route", route)
+		}
+	}
+}
+
+func TestOpenApiErrorCodesShouldHaveStatus(t *testing.T) {
+	for route, operation := range apiOperations {
+		for _, code := range operation.errorCodes {
+			if _, ok := errorCodeStatuses[code]; !ok {
+				t.Errorf("%s documents %s, which has no status in errorCodeStatuses", route, code)
+			}
+		}
+	}
+
+	// Every error that can be translated can be returned, so it should be documentable too
+	for code := range errorMessageTranslations["zh"] {
+		if _, ok := errorCodeStatuses[code]; !ok {
+			t.Errorf("%s has no status in errorCodeStatuses", code)
+		}
+	}
+}
+
+func TestOpenApiDocument(t *testing.T) {
+	document := newRouterWithoutDependencies(t).openApiDocument()
+
+	var got struct {
+		Paths map[string]map[string]struct {
+			Security   []any
+			Parameters []struct {
+				Name string
+				In   string
+			}
+			RequestBody struct {
+				Content map[string]any
+			}
+			Responses map[string]any
+		}
+	}
+	bytes, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(bytes, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	jobApplication := got.Paths["/api/tenants/{tenantId}/job-applications/{jobApplicationId}"]["post"]
+	if _, ok := jobApplication.RequestBody.Content["multipart/form-data"]; !ok {
+		t.Errorf("the job application should be uploaded as multipart/form-data, got %v", jobApplication.RequestBody.Content)
+	}
+	if jobApplication.Security == nil || len(jobApplication.Security) != 0 {
+		t.Errorf("the job application should not require authentication, got %v", jobApplication.Security)
+	}
+	if len(jobApplication.Parameters) != 2 || jobApplication.Parameters[0].Name != "tenantId" || jobApplication.Parameters[1].In != "path" {
+		t.Errorf("the job application should have the tenantId & jobApplicationId path parameters, got %+v", jobApplication.Parameters)
+	}
+	for _, status := range []string{"201", "400", "404", "409", "500"} {
+		if _, ok := jobApplication.Responses[status]; !ok {
+			t.Errorf("the job application should have a %s response", status)
+		}
+	}
+
+	sessions := got.Paths["/api/tenants/{tenantId}/users/{userId}/sessions"]["get"]
+	if sessions.Security != nil {
+		t.Errorf("listing sessions should require authentication, got %v", sessions.Security)
+	}
+	for _, status := range []string{"200", "401", "403"} {
+		if _, ok := sessions.Responses[status]; !ok {
+			t.Errorf("listing sessions should have a %s response", status)
+		}
+	}
+}
+
+// The document is public, so that clients can be generated before they have credentials
+func (s *IntegrationTestSuite) TestGetOpenApiDocument() {
+	r, err := http.NewRequest("GET", "/api/openapi.json", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody struct {
+		OpenApi string
+		Paths   map[string]any
+	}
+	json.NewDecoder(w.Body).Decode(&resBody)
+	s.Equal(openApiVersion, resBody.OpenApi)
+	s.Contains(resBody.Paths, "/api/tenants/{tenantId}/users/{userId}")
+}
@@ -0,0 +1,300 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/argon2id"
+	"github.com/gorilla/mux"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+const passwordResetTokenDuration = 24 * time.Hour
+
+// Only the hash of a reset token is stored. A fast hash is sufficient because the token is too random to be brute-forced
+func hashPasswordResetToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Changing the password does not require a session, so that users who must change their password can do so before logging in
+func (router *Router) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		TenantId    string
+		Email       string
+		OldPassword string
+		Totp        string
+		NewPassword string
+	}
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+
+	type Input struct {
+		TenantId    string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Email       string `validate:"required,notBlank,max=300" name:"user email"`
+		OldPassword string `validate:"required" name:"old password"`
+		Totp        string `validate:"required" name:"totp"`
+		NewPassword string `validate:"required,notBlank,min=12,max=64,nefield=OldPassword" name:"new password"`
+	}
+	input := Input{
+		TenantId:    reqBody.TenantId,
+		Email:       reqBody.Email,
+		OldPassword: reqBody.OldPassword,
+		Totp:        reqBody.Totp,
+		NewPassword: reqBody.NewPassword,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	valid, err := router.validateCredentials(r, input.Email, input.TenantId, input.OldPassword, input.Totp)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if !valid {
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	users, _, err := router.storageFor(r).GetUsers(storage.User{TenantId: input.TenantId, Email: input.Email}, storage.PageRequest{})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if len(users) == 0 {
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	passwordHash, err := argon2id.CreateHash(input.NewPassword, argon2id.DefaultParams)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	err = router.storageFor(r).ChangePassword(users[0].Id, users[0].TenantId, passwordHash)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// Existing sessions may belong to whoever knew the old password
+	revoked, err := router.storageFor(r).DeleteUserSessions(storage.UserSession{TenantId: users[0].TenantId, UserId: users[0].Id})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("PASSWORD-CHANGED", "userId", users[0].Id, "tenantId", users[0].TenantId, "sessionsRevoked", revoked)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Issues a one-time token that the user can redeem to set a new password, e.g. when they have forgotten their password
+// Any token previously issued to the user is invalidated
+func (router *Router) handleCreatePasswordResetToken(w http.ResponseWriter, r *http.Request) {
+	type responseBody struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId   string `validate:"required,notBlank,uuid" name:"user id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		UserId:   vars["userId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	users, _, err := router.storageFor(r).GetUsers(storage.User{TenantId: input.TenantId, Id: input.UserId}, storage.PageRequest{})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if len(users) == 0 {
+		sendToErrorHandlingMiddleware(Err404NotFound, r)
+		return
+	}
+
+	randomBytes := make([]byte, 32)
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(randomBytes)
+
+	passwordResetToken := storage.PasswordResetToken{
+		TenantId:  input.TenantId,
+		UserId:    input.UserId,
+		TokenHash: hashPasswordResetToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTokenDuration),
+	}
+	err = router.storageFor(r).CreatePasswordResetToken(passwordResetToken)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("PASSWORD-RESET-TOKEN-ISSUED", "userId", input.UserId, "tenantId", input.TenantId,
+		"issuedBy", getAuthenticatedUser(r).Id)
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(responseBody{
+		Token:     token,
+		ExpiresAt: passwordResetToken.ExpiresAt,
+	})
+}
+
+// Sets a new password using a token issued by an admin. The user's TOTP is still required (unless it has been reset), so a leaked token alone is not sufficient
+func (router *Router) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		TenantId    string
+		Email       string
+		Token       string
+		Totp        string
+		NewPassword string
+	}
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+
+	type Input struct {
+		TenantId    string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Email       string `validate:"required,notBlank,max=300" name:"user email"`
+		Token       string `validate:"required,notBlank" name:"password reset token"`
+		Totp        string
+		NewPassword string `validate:"required,notBlank,min=12,max=64" name:"new password"`
+	}
+	input := Input{
+		TenantId:    reqBody.TenantId,
+		Email:       reqBody.Email,
+		Token:       reqBody.Token,
+		Totp:        reqBody.Totp,
+		NewPassword: reqBody.NewPassword,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// Resets are subject to the same lockout as logins, so that tokens & TOTPs cannot be brute-forced here instead
+	err = router.checkLoginLockout(r, input.TenantId, input.Email)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	users, _, err := router.storageFor(r).GetUsers(storage.User{TenantId: input.TenantId, Email: input.Email}, storage.PageRequest{})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	// The same error is returned for an unknown email, an invalid token & an invalid TOTP, so that password resets
+	// cannot be used to find out which emails have accounts
+	if len(users) == 0 {
+		sendToErrorHandlingMiddleware(router.rejectPasswordReset(r, input.TenantId, input.Email, "INVALID-PASSWORD-RESET-TOKEN"), r)
+		return
+	}
+	passwordResetToken := storage.PasswordResetToken{
+		TenantId:  users[0].TenantId,
+		UserId:    users[0].Id,
+		TokenHash: hashPasswordResetToken(input.Token),
+	}
+	// The token is checked before the TOTP, so that the TOTP cannot be guessed (or used up) without a valid token
+	valid, err := router.storageFor(r).IsPasswordResetTokenValid(passwordResetToken)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if !valid {
+		sendToErrorHandlingMiddleware(router.rejectPasswordReset(r, input.TenantId, input.Email, "INVALID-PASSWORD-RESET-TOKEN"), r)
+		return
+	}
+	// Users whose TOTP has been reset by an admin (e.g. they have also lost their authenticator) have no TOTP to provide
+	// They enroll again with their new password after the reset
+	if users[0].TotpSecretKey != "" {
+		valid, err := router.useTotp(r, users[0], users[0].TotpSecretKey, input.Totp)
+		if err != nil {
+			sendToErrorHandlingMiddleware(err, r)
+			return
+		}
+		if !valid {
+			sendToErrorHandlingMiddleware(router.rejectPasswordReset(r, input.TenantId, input.Email, "INVALID-OTP"), r)
+			return
+		}
+	}
+
+	// Hashed before redeeming the token so that a hashing failure does not use up the token
+	passwordHash, err := argon2id.CreateHash(input.NewPassword, argon2id.DefaultParams)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	err = router.storageFor(r).RedeemPasswordResetToken(passwordResetToken, passwordHash)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	err = router.resetFailedLogins(r, input.TenantId, input.Email)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	revoked, err := router.storageFor(r).DeleteUserSessions(storage.UserSession{TenantId: users[0].TenantId, UserId: users[0].Id})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("PASSWORD-RESET", "userId", users[0].Id, "tenantId", users[0].TenantId, "sessionsRevoked", revoked)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Counts the failed reset towards the lockout of the tenant & email, whether or not the user exists
+// Returns ErrInvalidPasswordResetToken whatever the reason, so that the reason is not revealed
+func (router *Router) rejectPasswordReset(r *http.Request, tenantId string, email string, reason string) error {
+	err := router.recordFailedLogin(r, tenantId, email, reason)
+	if err != nil {
+		return err
+	}
+
+	return ErrInvalidPasswordResetToken
+}
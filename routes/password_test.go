@@ -0,0 +1,457 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+type changePasswordRequestBody struct {
+	TenantId    string
+	Email       string
+	OldPassword string
+	Totp        string
+	NewPassword string
+}
+
+type resetPasswordRequestBody struct {
+	TenantId    string
+	Email       string
+	Token       string
+	Totp        string
+	NewPassword string
+}
+
+func (s *IntegrationTestSuite) setMustChangePassword(userId string) {
+	_, err := s.dbRootConn.Exec("UPDATE user_account SET must_change_password = TRUE WHERE id = $1", userId)
+	if err != nil {
+		log.Fatalf("Could not set must_change_password: %s", err)
+	}
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldRequirePasswordChange() {
+	s.setMustChangePassword(s.defaultUser.Id)
+
+	code, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+	reqBody := map[string]string{
+		"TenantId": s.defaultUser.TenantId,
+		"Email":    s.defaultUser.Email,
+		"Password": "jU%q837d!QP7",
+		"Totp":     code,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "PASSWORD-CHANGE-REQUIRED")
+	s.Equal("", w.Header().Get("Set-Cookie"), "Session should not have been created")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"PASSWORD-CHANGE-REQUIRED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldNotRequirePasswordChangeBeforeOtpIsValid() {
+	s.setMustChangePassword(s.defaultUser.Id)
+
+	reqBody := map[string]string{
+		"TenantId": s.defaultUser.TenantId,
+		"Email":    s.defaultUser.Email,
+		"Password": "jU%q837d!QP7",
+		"Totp":     "123456",
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	// A correct password alone should not reveal that the user must change it
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"reason":"INVALID-OTP"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHENTICATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestChangePassword() {
+	s.setMustChangePassword(s.defaultUser.Id)
+
+	// Existing sessions should be revoked
+	sessionRequest, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(sessionRequest, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	code, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+	reqBody := changePasswordRequestBody{
+		TenantId:    s.defaultUser.TenantId,
+		Email:       s.defaultUser.Email,
+		OldPassword: "jU%q837d!QP7",
+		Totp:        code,
+		NewPassword: "n3w-P@ssword-123",
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("PUT", "/api/password", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	s.expectSelectQueryToReturnOneRow(
+		"user_account",
+		map[string]any{
+			"id":                   s.defaultUser.Id,
+			"must_change_password": false,
+		},
+	)
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"user_account_id": s.defaultUser.Id})
+
+	// The TOTP has already been used, so only the password can be checked
+	_, valid, err := s.router.validatePassword(r, s.defaultUser.Email, s.defaultUser.TenantId, reqBody.NewPassword)
+	s.Equal(nil, err)
+	s.Equal(true, valid, "The new password should be valid")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"PASSWORD-CHANGED"`, `"sessionsRevoked":1`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestChangePasswordInvalidInput() {
+	code, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+
+	tests := []struct {
+		name       string
+		input      changePasswordRequestBody
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			"Should fail because the old password is wrong",
+			changePasswordRequestBody{
+				TenantId:    s.defaultUser.TenantId,
+				Email:       s.defaultUser.Email,
+				OldPassword: "abcd1234!@#$%",
+				Totp:        code,
+				NewPassword: "n3w-P@ssword-123",
+			},
+			401,
+			"USER-UNAUTHENTICATED",
+		},
+		{
+			"Should fail because the totp is wrong",
+			changePasswordRequestBody{
+				TenantId:    s.defaultUser.TenantId,
+				Email:       s.defaultUser.Email,
+				OldPassword: "jU%q837d!QP7",
+				Totp:        "123456",
+				NewPassword: "n3w-P@ssword-123",
+			},
+			401,
+			"USER-UNAUTHENTICATED",
+		},
+		{
+			"Should fail because the new password is the same as the old password",
+			changePasswordRequestBody{
+				TenantId:    s.defaultUser.TenantId,
+				Email:       s.defaultUser.Email,
+				OldPassword: "jU%q837d!QP7",
+				Totp:        code,
+				NewPassword: "jU%q837d!QP7",
+			},
+			400,
+			"INPUT-VALIDATION-ERROR",
+		},
+		{
+			"Should fail because the new password is too short",
+			changePasswordRequestBody{
+				TenantId:    s.defaultUser.TenantId,
+				Email:       s.defaultUser.Email,
+				OldPassword: "jU%q837d!QP7",
+				Totp:        code,
+				NewPassword: "short",
+			},
+			400,
+			"INPUT-VALIDATION-ERROR",
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			bodyBuf := new(bytes.Buffer)
+			json.NewEncoder(bodyBuf).Encode(test.input)
+
+			r, err := http.NewRequest("PUT", "/api/password", bodyBuf)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, test.wantStatus)
+			s.expectErrorCode(w, test.wantCode)
+
+			_, valid, err := s.router.validatePassword(r, s.defaultUser.Email, s.defaultUser.TenantId, "jU%q837d!QP7")
+			s.Equal(nil, err)
+			s.Equal(true, valid, "The password should not have been changed")
+		})
+	}
+}
+
+// Issues a password reset token for the supervisor as the default (admin) user
+func (s *IntegrationTestSuite) createPasswordResetToken() string {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/password-reset-token", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id)
+	r, err := http.NewRequest("POST", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 201)
+
+	var resBody struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+	err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err)
+	s.NotEqual("", resBody.Token)
+
+	return resBody.Token
+}
+
+func (s *IntegrationTestSuite) resetPassword(reqBody resetPasswordRequestBody) *httptest.ResponseRecorder {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/password-reset", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	return w
+}
+
+func (s *IntegrationTestSuite) TestResetPassword() {
+	token := s.createPasswordResetToken()
+	s.expectSelectQueryToReturnOneRow(
+		"password_reset_token",
+		map[string]any{
+			"user_account_id": s.defaultSupervisor.Id,
+			"token_hash":      hashPasswordResetToken(token),
+			"used_at":         "",
+		},
+	)
+
+	code, _ := totp.GenerateCode(s.defaultSupervisor.TotpSecretKey, time.Now().UTC())
+	reqBody := resetPasswordRequestBody{
+		TenantId:    s.defaultSupervisor.TenantId,
+		Email:       s.defaultSupervisor.Email,
+		Token:       token,
+		Totp:        code,
+		NewPassword: "n3w-P@ssword-123",
+	}
+	s.logOutput.Reset()
+	w := s.resetPassword(reqBody)
+	s.expectHttpStatus(w, 200)
+
+	r := httptest.NewRequest("POST", "/api/session", nil)
+	_, valid, err := s.router.validatePassword(r, s.defaultSupervisor.Email, s.defaultSupervisor.TenantId, reqBody.NewPassword)
+	s.Equal(nil, err)
+	s.Equal(true, valid, "The new password should be valid")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"PASSWORD-RESET"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// The token can only be used once
+	s.clearTotpLastUsedStep(s.defaultSupervisor.Id)
+	reqBody.NewPassword = "an0ther-P@ssword"
+	w = s.resetPassword(reqBody)
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-PASSWORD-RESET-TOKEN-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestResetPasswordInvalidToken() {
+	firstToken := s.createPasswordResetToken()
+	latestToken := s.createPasswordResetToken()
+
+	_, err := s.dbRootConn.Exec("INSERT INTO password_reset_token (tenant_id, user_account_id, token_hash, expires_at) VALUES ($1, $2, $3, now() - interval '1 second')",
+		s.defaultSupervisor.TenantId, s.defaultSupervisor.Id, hashPasswordResetToken("expired-token"))
+	if err != nil {
+		log.Fatalf("Could not insert expired token: %s", err)
+	}
+
+	code, _ := totp.GenerateCode(s.defaultSupervisor.TotpSecretKey, time.Now().UTC())
+	defaultUserCode, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+
+	tests := []struct {
+		name       string
+		input      resetPasswordRequestBody
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			"Should fail because a newer token has been issued",
+			resetPasswordRequestBody{
+				TenantId:    s.defaultSupervisor.TenantId,
+				Email:       s.defaultSupervisor.Email,
+				Token:       firstToken,
+				Totp:        code,
+				NewPassword: "n3w-P@ssword-123",
+			},
+			400,
+			"INVALID-PASSWORD-RESET-TOKEN-ERROR",
+		},
+		{
+			"Should fail because the token has expired",
+			resetPasswordRequestBody{
+				TenantId:    s.defaultSupervisor.TenantId,
+				Email:       s.defaultSupervisor.Email,
+				Token:       "expired-token",
+				Totp:        code,
+				NewPassword: "n3w-P@ssword-123",
+			},
+			400,
+			"INVALID-PASSWORD-RESET-TOKEN-ERROR",
+		},
+		{
+			"Should fail because the token belongs to another user",
+			resetPasswordRequestBody{
+				TenantId:    s.defaultUser.TenantId,
+				Email:       s.defaultUser.Email,
+				Token:       firstToken,
+				Totp:        defaultUserCode,
+				NewPassword: "n3w-P@ssword-123",
+			},
+			400,
+			"INVALID-PASSWORD-RESET-TOKEN-ERROR",
+		},
+		{
+			"Should fail because the TOTP is invalid",
+			resetPasswordRequestBody{
+				TenantId:    s.defaultSupervisor.TenantId,
+				Email:       s.defaultSupervisor.Email,
+				Token:       latestToken,
+				Totp:        "000000",
+				NewPassword: "n3w-P@ssword-123",
+			},
+			400,
+			"INVALID-PASSWORD-RESET-TOKEN-ERROR",
+		},
+		{
+			"Should fail with the same error because there is no user with the email",
+			resetPasswordRequestBody{
+				TenantId:    s.defaultSupervisor.TenantId,
+				Email:       "nobody@example.com",
+				Token:       latestToken,
+				Totp:        code,
+				NewPassword: "n3w-P@ssword-123",
+			},
+			400,
+			"INVALID-PASSWORD-RESET-TOKEN-ERROR",
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			// The same TOTP is used for each test
+			s.clearTotpLastUsedStep(s.defaultSupervisor.Id)
+			s.clearTotpLastUsedStep(s.defaultUser.Id)
+			w := s.resetPassword(test.input)
+			s.expectHttpStatus(w, test.wantStatus)
+			s.expectErrorCode(w, test.wantCode)
+			// The TOTP should only be used once the token has been verified
+			s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultSupervisor.Id, "totp_last_used_step": ""})
+			s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultUser.Id, "totp_last_used_step": ""})
+		})
+	}
+
+	// The token should not have been used up by the failed attempts
+	s.expectSelectQueryToReturnOneRow(
+		"password_reset_token",
+		map[string]any{"token_hash": hashPasswordResetToken(latestToken), "used_at": ""},
+	)
+}
+
+func (s *IntegrationTestSuite) TestResetPasswordShouldBeLockedOut() {
+	token := s.createPasswordResetToken()
+	code, _ := totp.GenerateCode(s.defaultSupervisor.TotpSecretKey, time.Now().UTC())
+	reqBody := resetPasswordRequestBody{
+		TenantId:    s.defaultSupervisor.TenantId,
+		Email:       s.defaultSupervisor.Email,
+		Token:       "wrong-token",
+		Totp:        code,
+		NewPassword: "n3w-P@ssword-123",
+	}
+
+	// Failed resets count towards the same lockout as failed logins
+	w := s.resetPassword(reqBody)
+	s.expectHttpStatus(w, 400)
+	s.expectSelectQueryToReturnOneRow("login_attempt", map[string]any{"email": s.defaultSupervisor.Email, "failed_count": 1})
+	for i := 1; i < s.router.lockoutPolicy.MaxFailedAttempts; i++ {
+		s.resetPassword(reqBody)
+	}
+
+	s.logOutput.Reset()
+	reqBody.Token = token
+	w = s.resetPassword(reqBody)
+	s.expectHttpStatus(w, 429)
+	s.expectErrorCode(w, "LOGIN-LOCKED")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOCKED-LOGIN-ATTEMPTED"`)
+
+	// Neither the token nor the TOTP should have been used up
+	s.expectSelectQueryToReturnOneRow("password_reset_token", map[string]any{"token_hash": hashPasswordResetToken(token), "used_at": ""})
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultSupervisor.Id, "totp_last_used_step": ""})
+
+	// A successful reset clears the failed attempts
+	_, err := s.dbRootConn.Exec("UPDATE login_attempt SET locked_until = now() - interval '1 second'")
+	if err != nil {
+		log.Fatalf("Could not expire the lockout: %s", err)
+	}
+	w = s.resetPassword(reqBody)
+	s.expectHttpStatus(w, 200)
+	s.expectSelectQueryToReturnNoRows("login_attempt", map[string]any{"email": s.defaultSupervisor.Email})
+}
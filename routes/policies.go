@@ -2,7 +2,9 @@ package routes
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -55,7 +57,7 @@ func (router *Router) handleCreatePolicies(w http.ResponseWriter, r *http.Reques
 		TenantId:  input.TenantId,
 		Resources: reqBody.Resources,
 	}
-	err = router.storage.CreatePolicies(policies)
+	err = router.storageFor(r).CreatePolicies(policies)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -64,10 +66,427 @@ func (router *Router) handleCreatePolicies(w http.ResponseWriter, r *http.Reques
 	reqLogger := getRequestLogger(r)
 	reqLogger.Info("POLICIES-CREATED", "tenantId", policies.TenantId, "subject", policies.Subject)
 
+	// The policy watcher does not reload the instance's own changes
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
 	w.WriteHeader(http.StatusCreated)
 }
 
+// The role can be limited to a period, e.g. for contractors & acting managers. The enforcer ignores the role outside of it
 func (router *Router) handleCreateRoleAssignment(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		ValidFrom  *time.Time
+		ValidUntil *time.Time
+	}
+
+	// The body is optional, as most roles are assigned indefinitely
+	var reqBody requestBody
+	if r.Body != nil {
+		err := json.NewDecoder(r.Body).Decode(&reqBody)
+		if err != nil && err != io.EOF {
+			sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+			return
+		}
+	}
+
+	vars := mux.Vars(r)
+
+	type Input struct {
+		UserId     string     `validate:"required,notBlank,uuid" name:"user id"`
+		Role       string     `validate:"required,notBlank" name:"role name"`
+		TenantId   string     `validate:"required,notBlank,uuid" name:"tenant id"`
+		ValidFrom  *time.Time `name:"start of the role assignment"`
+		ValidUntil *time.Time `validate:"omitempty,gt,validRoleAssignmentPeriod" name:"expiry of the role assignment"`
+	}
+	input := Input{
+		UserId:     vars["userId"],
+		Role:       vars["roleName"],
+		TenantId:   vars["tenantId"],
+		ValidFrom:  reqBody.ValidFrom,
+		ValidUntil: reqBody.ValidUntil,
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	roleAssignment := storage.RoleAssignment{
+		UserId:   input.UserId,
+		Role:     input.Role,
+		TenantId: input.TenantId,
+	}
+	if input.ValidFrom != nil {
+		roleAssignment.ValidFrom = *input.ValidFrom
+	}
+	if input.ValidUntil != nil {
+		roleAssignment.ValidUntil = *input.ValidUntil
+	}
+	err = router.storageFor(r).CreateRoleAssignment(roleAssignment)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("ROLE-ASSIGNMENT-CREATED", "tenantId", roleAssignment.TenantId, "userId", roleAssignment.UserId, "role", roleAssignment.Role,
+		"validFrom", input.ValidFrom, "validUntil", input.ValidUntil)
+
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Re-loads the updated policy into the enforcer, so that changes to policies & role assignments apply to the next request
+// The other instances reload when they are notified of the change by the policy watcher
+func (router *Router) reloadAuthorizationPolicy(r *http.Request) error {
+	err := router.authEnforcer.LoadPolicy()
+	router.metrics.observeAuthorizationReload("request", err)
+	if err != nil {
+		return err
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("AUTHORIZATION-ENFORCER-RELOADED")
+
+	return nil
+}
+
+// Called by the policy watcher when casbin_rule was changed, possibly by another instance. The whole policy is reloaded
+// rather than applying the change, as the notification does not say which rules changed
+func (router *Router) ReloadAuthorizationPolicyOnChange(change string) {
+	err := router.authEnforcer.LoadPolicy()
+	router.metrics.observeAuthorizationReload("notification", err)
+	if err != nil {
+		router.rootLogger.Error("AUTHORIZATION-ENFORCER-RELOAD-FAILED", "change", change, "errorMessage", err.Error())
+		return
+	}
+
+	router.rootLogger.Info("AUTHORIZATION-ENFORCER-RELOADED", "change", change)
+}
+
+type resourceResponseBody struct {
+	Path   string `json:"path"`
+	Method string `json:"method"`
+}
+
+type policiesResponseBody struct {
+	Subject   string                 `json:"subject"`
+	Resources []resourceResponseBody `json:"resources"`
+	CreatedAt string                 `json:"createdAt"`
+	UpdatedAt string                 `json:"updatedAt"`
+}
+
+func newPoliciesResponseBody(policies storage.Policies) policiesResponseBody {
+	resources := []resourceResponseBody{}
+	for _, resource := range policies.Resources {
+		resources = append(resources, resourceResponseBody{resource.Path, resource.Method})
+	}
+
+	return policiesResponseBody{
+		Subject:   policies.Subject,
+		Resources: resources,
+		CreatedAt: policies.CreatedAt,
+		UpdatedAt: policies.UpdatedAt,
+	}
+}
+
+// Lists the resources that each subject (i.e. role or user) of the tenant can access
+// If a subject is provided in the path, only that subject's policies are returned
+func (router *Router) handleGetPolicies(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Subject  string `validate:"omitempty,notBlank" name:"role name"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		Subject:  vars["subject"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	filter := storage.Policies{
+		Subject:  input.Subject,
+		TenantId: input.TenantId,
+	}
+	policiesBySubject, err := router.storageFor(r).GetPolicies(filter)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	if input.Subject != "" {
+		if len(policiesBySubject) == 0 {
+			sendToErrorHandlingMiddleware(Err404NotFound, r)
+			return
+		}
+
+		reqLogger.Info("POLICIES-RETRIEVED", "tenantId", input.TenantId, "subject", input.Subject)
+
+		w.Header().Add("content-type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newPoliciesResponseBody(policiesBySubject[0]))
+		return
+	}
+
+	resBody := []policiesResponseBody{}
+	for _, policies := range policiesBySubject {
+		resBody = append(resBody, newPoliciesResponseBody(policies))
+	}
+
+	reqLogger.Info("POLICIES-RETRIEVED", "tenantId", input.TenantId, "count", len(resBody))
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resBody)
+}
+
+// Replaces every resource that the subject can access with the resources in the request body
+func (router *Router) handleReplacePolicies(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Resources []storage.Resource
+	}
+
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	type Resource struct {
+		Path   string `validate:"required,notBlank" name:"resource path"`
+		Method string `validate:"required,notBlank,oneof=POST GET PUT DELETE" name:"resource method"`
+	}
+	type Input struct {
+		Subject   string     `validate:"required,notBlank" name:"role name"`
+		TenantId  string     `validate:"required,notBlank,uuid" name:"tenant id"`
+		Resources []Resource `validate:"required,notBlank,dive" name:"resources"`
+	}
+	resources := []Resource{}
+	for _, resource := range reqBody.Resources {
+		resources = append(resources, Resource{resource.Path, resource.Method})
+	}
+	input := Input{
+		Subject:   vars["subject"],
+		TenantId:  vars["tenantId"],
+		Resources: resources,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	policies := storage.Policies{
+		Subject:   input.Subject,
+		TenantId:  input.TenantId,
+		Resources: reqBody.Resources,
+	}
+	err = router.storageFor(r).ReplacePolicies(policies)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("POLICIES-REPLACED", "tenantId", policies.TenantId, "subject", policies.Subject, "count", len(policies.Resources))
+
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Deletes every policy of the subject, or only the policy of the resource in the path & method query parameters
+func (router *Router) handleDeletePolicies(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	type Input struct {
+		Subject  string `validate:"required,notBlank" name:"role name"`
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Path     string `validate:"required_with=Method" name:"resource path"`
+		Method   string `validate:"required_with=Path,omitempty,oneof=POST GET PUT DELETE" name:"resource method"`
+	}
+	input := Input{
+		Subject:  vars["subject"],
+		TenantId: vars["tenantId"],
+		Path:     query.Get("path"),
+		Method:   query.Get("method"),
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	filter := storage.Policies{
+		Subject:  input.Subject,
+		TenantId: input.TenantId,
+	}
+	if input.Path != "" {
+		filter.Resources = []storage.Resource{{Path: input.Path, Method: input.Method}}
+	}
+	deleted, err := router.storageFor(r).DeletePolicies(filter)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if deleted == 0 {
+		sendToErrorHandlingMiddleware(Err404NotFound, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("POLICIES-DELETED", "tenantId", input.TenantId, "subject", input.Subject, "count", deleted)
+
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type roleAssignmentResponseBody struct {
+	Role       string     `json:"role"`
+	ValidFrom  *time.Time `json:"validFrom"`
+	ValidUntil *time.Time `json:"validUntil"`
+	CreatedAt  string     `json:"createdAt"`
+	UpdatedAt  string     `json:"updatedAt"`
+}
+
+func (router *Router) handleGetRoleAssignments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		UserId   string `validate:"required,notBlank,uuid" name:"user id"`
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+	}
+	input := Input{
+		UserId:   vars["userId"],
+		TenantId: vars["tenantId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	filter := storage.RoleAssignment{
+		UserId:   input.UserId,
+		TenantId: input.TenantId,
+	}
+	roleAssignments, err := router.storageFor(r).GetRoleAssignments(filter)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// Role assignments that have not started or have expired (but have not been deleted yet) are listed too
+	resBody := []roleAssignmentResponseBody{}
+	for _, roleAssignment := range roleAssignments {
+		roleAssignmentBody := roleAssignmentResponseBody{
+			Role:      roleAssignment.Role,
+			CreatedAt: roleAssignment.CreatedAt,
+			UpdatedAt: roleAssignment.UpdatedAt,
+		}
+		if validFrom := roleAssignment.ValidFrom; !validFrom.IsZero() {
+			roleAssignmentBody.ValidFrom = &validFrom
+		}
+		if validUntil := roleAssignment.ValidUntil; !validUntil.IsZero() {
+			roleAssignmentBody.ValidUntil = &validUntil
+		}
+		resBody = append(resBody, roleAssignmentBody)
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("ROLE-ASSIGNMENTS-RETRIEVED", "tenantId", input.TenantId, "userId", input.UserId, "count", len(resBody))
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resBody)
+}
+
+// Replaces every role of the user with the roles in the request body. An empty list removes all of the user's roles
+// Roles that the user already had keep their period of validity
+func (router *Router) handleReplaceRoleAssignments(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		Roles []string
+	}
+
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	// Roles must be provided, even if empty, so that a body without them does not remove every role by accident
+	type Input struct {
+		UserId   string   `validate:"required,notBlank,uuid" name:"user id"`
+		TenantId string   `validate:"required,notBlank,uuid" name:"tenant id"`
+		Roles    []string `validate:"required,dive,notBlank" name:"roles"`
+	}
+	input := Input{
+		UserId:   vars["userId"],
+		TenantId: vars["tenantId"],
+		Roles:    reqBody.Roles,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	err = router.storageFor(r).ReplaceRoleAssignments(input.UserId, input.TenantId, input.Roles)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("ROLE-ASSIGNMENTS-REPLACED", "tenantId", input.TenantId, "userId", input.UserId, "roles", input.Roles)
+
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (router *Router) handleDeleteRoleAssignment(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
 	type Input struct {
@@ -87,28 +506,29 @@ func (router *Router) handleCreateRoleAssignment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	roleAssignment := storage.RoleAssignment{
+	filter := storage.RoleAssignment{
 		UserId:   input.UserId,
 		Role:     input.Role,
 		TenantId: input.TenantId,
 	}
-	err = router.storage.CreateRoleAssignment(roleAssignment)
+	deleted, err := router.storageFor(r).DeleteRoleAssignments(filter)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
+	if deleted == 0 {
+		sendToErrorHandlingMiddleware(Err404NotFound, r)
+		return
+	}
 
 	reqLogger := getRequestLogger(r)
-	reqLogger.Info("ROLE-ASSIGNMENT-CREATED", "tenantId", roleAssignment.TenantId, "userId", roleAssignment.UserId, "role", roleAssignment.Role)
+	reqLogger.Info("ROLE-ASSIGNMENT-DELETED", "tenantId", input.TenantId, "userId", input.UserId, "role", input.Role)
 
-	// Re-load the updated policy into the enforcer
-	err = router.authEnforcer.LoadPolicy()
+	err = router.reloadAuthorizationPolicy(r)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
 
-	reqLogger.Info("AUTHORIZATION-ENFORCER-RELOADED")
-
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -8,6 +8,8 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"time"
 
 	"multi-tenant-HR-information-system-backend/storage"
 )
@@ -66,6 +68,7 @@ func (s *IntegrationTestSuite) TestCreatePolicies() {
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"POLICIES-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
@@ -227,6 +230,72 @@ func (s *IntegrationTestSuite) TestCreateRoleAssignment() {
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
+func (s *IntegrationTestSuite) TestCreateRoleAssignmentForPeriod() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+	now := time.Now().UTC().Truncate(time.Second)
+	oneHourAgo, inOneHour, inTwoHours := now.Add(-time.Hour), now.Add(time.Hour), now.Add(2*time.Hour)
+
+	tests := []struct {
+		name           string
+		validFrom      *time.Time
+		validUntil     *time.Time
+		wantStatus     int
+		wantAuthorized bool
+	}{
+		{"Should apply the role until it expires", nil, &inOneHour, 201, true},
+		{"Should not apply the role before it starts", &inOneHour, &inTwoHours, 201, false},
+		{"Should be invalid because the role has already expired", nil, &oneHourAgo, 400, false},
+		{"Should be invalid because the role expires before it starts", &inTwoHours, &inOneHour, 400, false},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			_, err := s.dbRootConn.Exec("DELETE FROM casbin_rule WHERE Ptype = 'g' AND V0 = $1", s.defaultSupervisor.Id)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			body, err := json.Marshal(map[string]any{"validFrom": test.validFrom, "validUntil": test.validUntil})
+			if err != nil {
+				log.Fatal(err)
+			}
+			path := fmt.Sprintf("/api/tenants/%s/users/%s/roles/%s", s.defaultTenant.Id, s.defaultSupervisor.Id, seedPolicies.Subject)
+			r, err := http.NewRequest("POST", path, bytes.NewBuffer(body))
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			s.logOutput.Reset()
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, test.wantStatus)
+
+			authorized, err := s.router.authEnforcer.Enforce(s.defaultSupervisor.Id, s.defaultTenant.Id, seedPolicies.Resources[0].Path, seedPolicies.Resources[0].Method)
+			s.Equal(nil, err)
+			s.Equal(test.wantAuthorized, authorized)
+
+			roleAssignments, err := s.router.storage.GetRoleAssignments(storage.RoleAssignment{UserId: s.defaultSupervisor.Id, TenantId: s.defaultTenant.Id})
+			s.Equal(nil, err)
+			if test.wantStatus != 201 {
+				s.expectErrorCode(w, "INPUT-VALIDATION-ERROR")
+				s.Equal(0, len(roleAssignments))
+				return
+			}
+			s.Require().Equal(1, len(roleAssignments))
+			s.Equal(test.validFrom == nil, roleAssignments[0].ValidFrom.IsZero())
+			s.True(test.validUntil.Equal(roleAssignments[0].ValidUntil))
+
+			reader := bufio.NewReader(s.logOutput)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"ROLE-ASSIGNMENT-CREATED"`)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+		})
+	}
+}
+
 func (s *IntegrationTestSuite) TestCreateRoleAssignmentInvalidInput() {
 	wantRoleAssignment := storage.RoleAssignment{
 		UserId:   s.defaultUser.Id,
@@ -296,3 +365,460 @@ func (s *IntegrationTestSuite) TestCreateRoleAssignmentViolatesUniqueConstraint(
 	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"UNIQUE-VIOLATION-ERROR"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
+
+func (s *IntegrationTestSuite) seedTenantAdminPolicies(resources ...storage.Resource) storage.Policies {
+	policies := storage.Policies{
+		Subject:   "TENANT_ROLE_ADMIN",
+		TenantId:  s.defaultTenant.Id,
+		Resources: resources,
+	}
+
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2, V3) VALUES ('p', $1, $2, $3, $4)"
+	for _, resource := range resources {
+		_, err := s.dbRootConn.Exec(query, policies.Subject, policies.TenantId, resource.Path, resource.Method)
+		if err != nil {
+			log.Fatalf("Policy seeding failed: %s", err)
+		}
+	}
+
+	return policies
+}
+
+func (s *IntegrationTestSuite) TestGetPolicies() {
+	seedPolicies := s.seedTenantAdminPolicies(
+		storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"},
+		storage.Resource{Path: "/api/tenants/{tenantId}/divisions/{divisionId}", Method: "POST"},
+	)
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/policies", s.defaultTenant.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody []policiesResponseBody
+	json.NewDecoder(w.Body).Decode(&resBody)
+	policiesBySubject := map[string]policiesResponseBody{}
+	for _, policies := range resBody {
+		policiesBySubject[policies.Subject] = policies
+	}
+	// The PUBLIC policies are not the tenant's, so they are not listed
+	s.NotContains(policiesBySubject, "PUBLIC")
+	s.Len(policiesBySubject[s.defaultPolicies.Subject].Resources, len(s.defaultPolicies.Resources))
+	s.Equal([]resourceResponseBody{
+		{"/api/tenants/{tenantId}/divisions/{divisionId}", "POST"},
+		{"/api/tenants/{tenantId}/users/{userId}", "POST"},
+	}, policiesBySubject[seedPolicies.Subject].Resources)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"POLICIES-RETRIEVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestGetPoliciesOfSubject() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+
+	tests := []struct {
+		name       string
+		subject    string
+		wantStatus int
+	}{
+		{"Should return the subject's policies", seedPolicies.Subject, 200},
+		{"Should return 404 because the subject has no policies", "TENANT_ROLE_AUDITOR", 404},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/policies/%s", s.defaultTenant.Id, test.subject), nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, test.wantStatus)
+			if test.wantStatus != 200 {
+				s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+				return
+			}
+
+			var resBody policiesResponseBody
+			json.NewDecoder(w.Body).Decode(&resBody)
+			s.Equal(seedPolicies.Subject, resBody.Subject)
+			s.Equal([]resourceResponseBody{{"/api/tenants/{tenantId}/users/{userId}", "POST"}}, resBody.Resources)
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestReplacePolicies() {
+	oldResource := storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"}
+	seedPolicies := s.seedTenantAdminPolicies(oldResource)
+	newResources := []storage.Resource{
+		{Path: "/api/tenants/{tenantId}/users/{userId}/sessions", Method: "GET"},
+		{Path: "/api/tenants/{tenantId}/users/{userId}/sessions", Method: "DELETE"},
+	}
+
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(map[string]any{"Resources": newResources})
+
+	r, err := http.NewRequest("PUT", fmt.Sprintf("/api/tenants/%s/policies/%s", s.defaultTenant.Id, seedPolicies.Subject), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{
+		"Ptype": "p", "V0": seedPolicies.Subject, "V1": seedPolicies.TenantId, "V2": oldResource.Path, "V3": oldResource.Method,
+	})
+	for _, resource := range newResources {
+		s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{
+			"Ptype": "p", "V0": seedPolicies.Subject, "V1": seedPolicies.TenantId, "V2": resource.Path, "V3": resource.Method,
+		})
+	}
+
+	// Check that the authorization enforcer was reloaded
+	authorized, err := s.router.authEnforcer.Enforce(seedPolicies.Subject, seedPolicies.TenantId, newResources[0].Path, newResources[0].Method)
+	s.Equal(nil, err)
+	s.True(authorized, "The role should be allowed to access the new resource")
+	authorized, err = s.router.authEnforcer.Enforce(seedPolicies.Subject, seedPolicies.TenantId, oldResource.Path, oldResource.Method)
+	s.Equal(nil, err)
+	s.False(authorized, "The role should not be allowed to access the old resource")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"POLICIES-REPLACED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestReplacePoliciesShouldNotReplaceAnyPolicyIfOneIsInvalid() {
+	oldResource := storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"}
+	seedPolicies := s.seedTenantAdminPolicies(oldResource)
+	newResource := storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}/sessions", Method: "GET"}
+
+	tests := []struct {
+		name      string
+		resources []storage.Resource
+		wantCode  string
+	}{
+		{"Should be invalid because a resource has no method", []storage.Resource{newResource, {Path: "/api/test"}}, "INPUT-VALIDATION-ERROR"},
+		{"Should be invalid because no resource was provided", []storage.Resource{}, "INPUT-VALIDATION-ERROR"},
+		{"Should violate unique constraint because a resource was provided twice", []storage.Resource{newResource, newResource}, "UNIQUE-VIOLATION-ERROR"},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			bodyBuf := new(bytes.Buffer)
+			json.NewEncoder(bodyBuf).Encode(map[string]any{"Resources": test.resources})
+
+			r, err := http.NewRequest("PUT", fmt.Sprintf("/api/tenants/%s/policies/%s", s.defaultTenant.Id, seedPolicies.Subject), bodyBuf)
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectErrorCode(w, test.wantCode)
+			s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{
+				"Ptype": "p", "V0": seedPolicies.Subject, "V1": seedPolicies.TenantId, "V2": oldResource.Path, "V3": oldResource.Method,
+			})
+			s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{
+				"Ptype": "p", "V0": seedPolicies.Subject, "V1": seedPolicies.TenantId, "V2": newResource.Path, "V3": newResource.Method,
+			})
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestDeletePolicies() {
+	resources := []storage.Resource{
+		{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"},
+		{Path: "/api/tenants/{tenantId}/users/{userId}/sessions", Method: "GET"},
+	}
+	seedPolicies := s.seedTenantAdminPolicies(resources...)
+
+	// Only the policy of the resource in the query parameters is deleted
+	path := fmt.Sprintf("/api/tenants/%s/policies/%s?path=%s&method=%s", s.defaultTenant.Id, seedPolicies.Subject,
+		url.QueryEscape(resources[0].Path), resources[0].Method)
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 204)
+	s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{
+		"Ptype": "p", "V0": seedPolicies.Subject, "V1": seedPolicies.TenantId, "V2": resources[0].Path, "V3": resources[0].Method,
+	})
+	s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{
+		"Ptype": "p", "V0": seedPolicies.Subject, "V1": seedPolicies.TenantId, "V2": resources[1].Path, "V3": resources[1].Method,
+	})
+
+	authorized, err := s.router.authEnforcer.Enforce(seedPolicies.Subject, seedPolicies.TenantId, resources[0].Path, resources[0].Method)
+	s.Equal(nil, err)
+	s.False(authorized, "The role should not be allowed to access the deleted resource")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"POLICIES-DELETED"`, `"count":1`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// Without query parameters, every policy of the subject is deleted
+	r, err = http.NewRequest("DELETE", fmt.Sprintf("/api/tenants/%s/policies/%s", s.defaultTenant.Id, seedPolicies.Subject), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 204)
+	s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{"Ptype": "p", "V0": seedPolicies.Subject})
+
+	// There is nothing left to delete
+	r, err = http.NewRequest("DELETE", fmt.Sprintf("/api/tenants/%s/policies/%s", s.defaultTenant.Id, seedPolicies.Subject), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestDeletePoliciesShouldRequireBothPathAndMethod() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+
+	path := fmt.Sprintf("/api/tenants/%s/policies/%s?path=%s", s.defaultTenant.Id, seedPolicies.Subject, url.QueryEscape("/api/tenants/{tenantId}/users/{userId}"))
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INPUT-VALIDATION-ERROR")
+	s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{"Ptype": "p", "V0": seedPolicies.Subject})
+}
+
+func (s *IntegrationTestSuite) TestGetRoleAssignments() {
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, $2, $3)"
+	_, err := s.dbRootConn.Exec(query, s.defaultUser.Id, "TENANT_ROLE_AUDITOR", s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Roles that have expired are listed until they are deleted by the sweeper
+	validUntil := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	query = "INSERT INTO casbin_rule (Ptype, V0, V1, V2, valid_until) VALUES ('g', $1, $2, $3, $4)"
+	_, err = s.dbRootConn.Exec(query, s.defaultUser.Id, "TENANT_ROLE_CONTRACTOR", s.defaultTenant.Id, validUntil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/roles", s.defaultTenant.Id, s.defaultUser.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody []roleAssignmentResponseBody
+	json.NewDecoder(w.Body).Decode(&resBody)
+	roles := []string{}
+	for _, roleAssignment := range resBody {
+		roles = append(roles, roleAssignment.Role)
+	}
+	s.Equal([]string{s.defaultRoleAssignment.Role, "TENANT_ROLE_AUDITOR", "TENANT_ROLE_CONTRACTOR"}, roles)
+	s.Nil(resBody[0].ValidUntil, "A role without an expiry should have no validUntil")
+	s.True(validUntil.Equal(*resBody[2].ValidUntil), "A role's expiry should be returned")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"ROLE-ASSIGNMENTS-RETRIEVED"`, `"count":3`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestReplaceRoleAssignments() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantRoles  []string
+	}{
+		{"Should replace the user's roles", `{"roles": ["TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR"]}`, 200, []string{"TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR"}},
+		{"Should be invalid because the roles were not provided", `{}`, 400, []string{"TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR"}},
+		{"Should violate unique constraint because a role was provided twice", `{"roles": ["TENANT_ROLE_AUDITOR", "TENANT_ROLE_AUDITOR"]}`, 409, []string{"TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR"}},
+		{"Should remove all of the user's roles", `{"roles": []}`, 200, []string{}},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			path := fmt.Sprintf("/api/tenants/%s/users/%s/roles", s.defaultTenant.Id, s.defaultSupervisor.Id)
+			r, err := http.NewRequest("PUT", path, bytes.NewBufferString(test.body))
+			if err != nil {
+				log.Fatal(err)
+			}
+			s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			s.expectHttpStatus(w, test.wantStatus)
+
+			roleAssignments, err := s.router.storage.GetRoleAssignments(storage.RoleAssignment{UserId: s.defaultSupervisor.Id, TenantId: s.defaultTenant.Id})
+			s.Equal(nil, err)
+			roles := []string{}
+			for _, roleAssignment := range roleAssignments {
+				roles = append(roles, roleAssignment.Role)
+			}
+			s.Equal(test.wantRoles, roles)
+
+			authorized, err := s.router.authEnforcer.Enforce(s.defaultSupervisor.Id, s.defaultTenant.Id, seedPolicies.Resources[0].Path, seedPolicies.Resources[0].Method)
+			s.Equal(nil, err)
+			s.Equal(len(test.wantRoles) > 0, authorized)
+		})
+	}
+}
+
+// Replacing the roles must not turn a temporary role into a permanent one
+func (s *IntegrationTestSuite) TestReplaceRoleAssignmentsShouldKeepValidity() {
+	validUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2, valid_until) VALUES ('g', $1, $2, $3, $4)"
+	_, err := s.dbRootConn.Exec(query, s.defaultSupervisor.Id, "TENANT_ROLE_CONTRACTOR", s.defaultTenant.Id, validUntil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/roles", s.defaultTenant.Id, s.defaultSupervisor.Id)
+	r, err := http.NewRequest("PUT", path, bytes.NewBufferString(`{"roles": ["TENANT_ROLE_AUDITOR", "TENANT_ROLE_CONTRACTOR"]}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	roleAssignments, err := s.router.storage.GetRoleAssignments(storage.RoleAssignment{UserId: s.defaultSupervisor.Id, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Require().Equal(2, len(roleAssignments))
+	s.Equal("TENANT_ROLE_AUDITOR", roleAssignments[0].Role)
+	s.True(roleAssignments[0].ValidUntil.IsZero(), "A new role should not expire")
+	s.Equal("TENANT_ROLE_CONTRACTOR", roleAssignments[1].Role)
+	s.True(validUntil.Equal(roleAssignments[1].ValidUntil), "A kept role should keep its expiry")
+}
+
+func (s *IntegrationTestSuite) TestDeleteRoleAssignment() {
+	seedPolicies := s.seedTenantAdminPolicies(storage.Resource{Path: "/api/tenants/{tenantId}/users/{userId}", Method: "POST"})
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, $2, $3)"
+	_, err := s.dbRootConn.Exec(query, s.defaultSupervisor.Id, seedPolicies.Subject, s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = s.router.authEnforcer.LoadPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/roles/%s", s.defaultTenant.Id, s.defaultSupervisor.Id, seedPolicies.Subject)
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 204)
+	s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{
+		"Ptype": "g", "V0": s.defaultSupervisor.Id, "V1": seedPolicies.Subject, "V2": s.defaultTenant.Id,
+	})
+
+	authorized, err := s.router.authEnforcer.Enforce(s.defaultSupervisor.Id, s.defaultTenant.Id, seedPolicies.Resources[0].Path, seedPolicies.Resources[0].Method)
+	s.Equal(nil, err)
+	s.False(authorized, "User should no longer be authorized")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"ROLE-ASSIGNMENT-DELETED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// The role is no longer assigned
+	r, err = http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+// Simulates another instance changing the policies, which is notified by the policy watcher
+func (s *IntegrationTestSuite) TestReloadAuthorizationPolicyOnChange() {
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, $2, $3)"
+	_, err := s.dbRootConn.Exec(query, s.defaultSupervisor.Id, "TENANT_ROLE_WATCHED", s.defaultTenant.Id)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hasRole, err := s.router.authEnforcer.HasRoleForUser(s.defaultSupervisor.Id, "TENANT_ROLE_WATCHED", s.defaultTenant.Id)
+	s.Equal(nil, err)
+	s.False(hasRole, "The change should not apply before the enforcer is reloaded")
+
+	s.logOutput.Reset()
+	s.router.ReloadAuthorizationPolicyOnChange("INSERT")
+
+	hasRole, err = s.router.authEnforcer.HasRoleForUser(s.defaultSupervisor.Id, "TENANT_ROLE_WATCHED", s.defaultTenant.Id)
+	s.Equal(nil, err)
+	s.True(hasRole, "The change should apply after the enforcer is reloaded")
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`, `"change":"INSERT"`)
+
+	r, err := http.NewRequest("GET", "/metrics", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.router.MetricsHandler().ServeHTTP(w, r)
+	s.Contains(w.Body.String(), `hris_authorization_policy_reloads_total{result="success",trigger="notification"}`)
+}
@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"strings"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// The resources that a user needs to take part in a workflow, as route templates
+// A template is instantiated for a user by replacing {tenantId} & {userId} with the user's ids, so that the user can only
+// act as themselves. The other path parameters, e.g. {jobRequisitionId}, are left as is & match any value
+type policyTemplate []storage.Resource
+
+// Given to every user when they are created, as anyone can manage their own sessions, request a job requisition & be a
+// requestor's supervisor. Job requisitions are only returned to their requestor or supervisor, so the supervisor's routes
+// are safe to give to everyone
+var employeePolicyTemplate = policyTemplate{
+	{Path: "/api/tenants/{tenantId}/users/{userId}/sessions", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/sessions", Method: "DELETE"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/sessions/{sessionId}", Method: "DELETE"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor/{jobRequisitionId}", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor/{jobRequisitionId}", Method: "POST"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-requestor/{jobRequisitionId}/job-applications/{jobApplicationId}/hiring-manager-decision", Method: "POST"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-supervisor", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-supervisor/{jobRequisitionId}", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-supervisor/{jobRequisitionId}/supervisor-decision", Method: "POST"},
+}
+
+// Only users holding this role in a tenant may be chosen as the HR approver of a job requisition
+const hrApproverRole = "TENANT_ROLE_HR_APPROVER"
+
+// Given to a user when they are chosen as the HR approver of a job requisition
+var hrApproverPolicyTemplate = policyTemplate{
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-hr-approver", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-hr-approver/{jobRequisitionId}", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-hr-approver/{jobRequisitionId}/hr-approver-decision", Method: "POST"},
+}
+
+// Given to a user when they are assigned as the recruiter of a job requisition by its HR approver
+var recruiterPolicyTemplate = policyTemplate{
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}", Method: "GET"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/recruiter-decision", Method: "POST"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/interview-date", Method: "POST"},
+	{Path: "/api/tenants/{tenantId}/users/{userId}/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/applicant-decision", Method: "POST"},
+}
+
+// Returns the template's policies for the user, whose subject is the user's id
+func (template policyTemplate) instantiate(userId string, tenantId string) storage.Policies {
+	replacer := strings.NewReplacer("{tenantId}", tenantId, "{userId}", userId)
+
+	resources := []storage.Resource{}
+	for _, resource := range template {
+		resources = append(resources, storage.Resource{
+			Path:   replacer.Replace(resource.Path),
+			Method: resource.Method,
+		})
+	}
+
+	return storage.Policies{
+		Subject:   userId,
+		TenantId:  tenantId,
+		Resources: resources,
+	}
+}
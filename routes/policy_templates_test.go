@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+var policyTemplates = map[string]policyTemplate{
+	"employee":    employeePolicyTemplate,
+	"hr approver": hrApproverPolicyTemplate,
+	"recruiter":   recruiterPolicyTemplate,
+}
+
+// Fails if a route in a template is renamed or removed, as the users given the template would silently lose access to it
+func TestPolicyTemplatesShouldOnlyHaveRoutes(t *testing.T) {
+	routes := map[storage.Resource]bool{}
+	newRouterWithoutDependencies(t).Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		pathTemplate, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			routes[storage.Resource{Path: pathTemplate, Method: method}] = true
+		}
+		return nil
+	})
+
+	for name, template := range policyTemplates {
+		for _, resource := range template {
+			if !routes[resource] {
+				t.Errorf("the %s template has %s %s, which is not a route", name, resource.Method, resource.Path)
+			}
+		}
+	}
+}
+
+func TestInstantiatePolicyTemplate(t *testing.T) {
+	userId := "e7f31b70-ae26-42b3-b7a6-01ec68d5c33a"
+	tenantId := "2ad1dcfc-8867-49f7-87a3-8bd8d1154924"
+
+	for name, template := range policyTemplates {
+		policies := template.instantiate(userId, tenantId)
+		if policies.Subject != userId || policies.TenantId != tenantId {
+			t.Errorf("the %s policies should belong to the user, got %s in %s", name, policies.Subject, policies.TenantId)
+		}
+		if len(policies.Resources) != len(template) {
+			t.Fatalf("the %s policies should have %d resources, got %d", name, len(template), len(policies.Resources))
+		}
+
+		prefix := "/api/tenants/" + tenantId + "/users/" + userId + "/"
+		for i, resource := range policies.Resources {
+			if !strings.HasPrefix(resource.Path, prefix) || strings.Contains(resource.Path, "{userId}") {
+				t.Errorf("the %s policy %s should only allow the user to act as themselves", name, resource.Path)
+			}
+			if resource.Method != template[i].Method {
+				t.Errorf("the %s policy %s should keep the method %s, got %s", name, resource.Path, template[i].Method, resource.Method)
+			}
+		}
+	}
+
+	// The template itself must not be modified, as it is shared by every user
+	if !strings.Contains(employeePolicyTemplate[0].Path, "{userId}") {
+		t.Errorf("instantiating the template should not modify it, got %s", employeePolicyTemplate[0].Path)
+	}
+}
+
+// Seeded users can manage their own sessions, so users created later must be able to as well
+func TestEmployeePolicyTemplateShouldAllowManagingOwnSessions(t *testing.T) {
+	userId := "e7f31b70-ae26-42b3-b7a6-01ec68d5c33a"
+	tenantId := "2ad1dcfc-8867-49f7-87a3-8bd8d1154924"
+	sessionsPath := "/api/tenants/" + tenantId + "/users/" + userId + "/sessions"
+
+	resources := map[storage.Resource]bool{}
+	for _, resource := range employeePolicyTemplate.instantiate(userId, tenantId).Resources {
+		resources[resource] = true
+	}
+
+	for _, want := range []storage.Resource{
+		{Path: sessionsPath, Method: "GET"},
+		{Path: sessionsPath, Method: "DELETE"},
+		{Path: sessionsPath + "/{sessionId}", Method: "DELETE"},
+	} {
+		if !resources[want] {
+			t.Errorf("the employee policies should have %s %s", want.Method, want.Path)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/argon2id"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+const recoveryCodeCount = 10
+const recoveryCodeLength = 10
+const recoveryCodeCharSet = "abcdefghjkmnpqrstuvwxyz23456789" // Excludes characters that are easily confused (e.g. 0 & o, 1 & l)
+const recoveryCodeLookupHashLength = 4                        // In hex characters, i.e. 16 bits
+
+// A truncated SHA-256 of the code, so that a code being tried only needs to be compared with the argon2id hash of the
+// matching code, instead of every code of the user. It is too short to give away the code if the database is leaked
+func recoveryCodeLookupHash(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])[:recoveryCodeLookupHashLength]
+}
+
+// Returns the codes to be shown to the user once, along with their hashes to be stored
+// Codes are formatted as 2 groups of 5 characters (e.g. abcde-fghjk) for readability
+// Each code of a user has a different lookup hash, so that at most 1 argon2id hash is compared when a code is used
+func generateRecoveryCodes() (codes []string, recoveryCodes []storage.RecoveryCode, err error) {
+	lookupHashes := map[string]bool{}
+	for len(codes) < recoveryCodeCount {
+		var code strings.Builder
+		for j := 0; j < recoveryCodeLength; j++ {
+			random, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeCharSet))))
+			if err != nil {
+				return nil, nil, httperror.NewInternalServerError(err)
+			}
+			code.WriteByte(recoveryCodeCharSet[random.Int64()])
+		}
+
+		lookupHash := recoveryCodeLookupHash(code.String())
+		if lookupHashes[lookupHash] {
+			continue
+		}
+		lookupHashes[lookupHash] = true
+
+		codeHash, err := argon2id.CreateHash(code.String(), argon2id.DefaultParams)
+		if err != nil {
+			return nil, nil, httperror.NewInternalServerError(err)
+		}
+
+		codes = append(codes, code.String()[:recoveryCodeLength/2]+"-"+code.String()[recoveryCodeLength/2:])
+		recoveryCodes = append(recoveryCodes, storage.RecoveryCode{LookupHash: lookupHash, CodeHash: codeHash})
+	}
+
+	return codes, recoveryCodes, nil
+}
+
+// Users may enter the code in uppercase or without the hyphen
+func normaliseRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// Distinguishes recovery codes from TOTPs, which only contain 6 digits
+func isRecoveryCode(code string) bool {
+	return len(normaliseRecoveryCode(code)) == recoveryCodeLength
+}
+
+// Checks the code against the user's unused recovery codes & consumes the matching code
+// The use of a recovery code is logged as a security event, as it may indicate that the user's password has been compromised
+func (router *Router) useRecoveryCode(r *http.Request, user storage.User, code string) (bool, error) {
+	recoveryCodes, err := router.storageFor(r).GetRecoveryCodes(storage.RecoveryCode{TenantId: user.TenantId, UserId: user.Id})
+	if err != nil {
+		return false, err
+	}
+
+	code = normaliseRecoveryCode(code)
+	lookupHash := recoveryCodeLookupHash(code)
+	for _, recoveryCode := range recoveryCodes {
+		// Argon2id is deliberately slow, so wrong codes should rarely need to be compared
+		if recoveryCode.LookupHash != lookupHash {
+			continue
+		}
+
+		match, err := argon2id.ComparePasswordAndHash(code, recoveryCode.CodeHash)
+		if err != nil {
+			return false, httperror.NewInternalServerError(err)
+		}
+		if !match {
+			continue
+		}
+
+		err = router.storageFor(r).UseRecoveryCode(recoveryCode)
+		if httpErr, ok := err.(*httperror.Error); ok && httpErr.Status == http.StatusNotFound {
+			// The code was used by a concurrent request
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		reqLogger := getRequestLogger(r)
+		reqLogger.Warn("RECOVERY-CODE-USED", "userId", user.Id, "tenantId", user.TenantId, "remaining", len(recoveryCodes)-1)
+
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/alexedwards/argon2id"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, recoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("Recovery codes could not be generated: %s", err)
+	}
+
+	if len(codes) != recoveryCodeCount || len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("want %d codes, got %d codes & %d hashes", recoveryCodeCount, len(codes), len(recoveryCodes))
+	}
+
+	format := regexp.MustCompile(`^[a-z2-9]{5}-[a-z2-9]{5}$`)
+	seen := map[string]bool{}
+	seenLookupHashes := map[string]bool{}
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("Code %s should be formatted as 2 groups of 5 characters", code)
+		}
+		if seen[code] {
+			t.Errorf("Code %s should be unique", code)
+		}
+		seen[code] = true
+
+		if !isRecoveryCode(code) {
+			t.Errorf("Code %s should be recognised as a recovery code", code)
+		}
+
+		// Codes are hashed without the hyphen, so that users can enter them with or without it
+		match, err := argon2id.ComparePasswordAndHash(normaliseRecoveryCode(code), recoveryCodes[i].CodeHash)
+		if err != nil || !match {
+			t.Errorf("The hash of code %s should match the code", code)
+		}
+
+		// Codes are found by their lookup hash, so that at most 1 argon2id hash is compared per attempt
+		if recoveryCodes[i].LookupHash != recoveryCodeLookupHash(normaliseRecoveryCode(code)) {
+			t.Errorf("The lookup hash of code %s should match the code", code)
+		}
+		if seenLookupHashes[recoveryCodes[i].LookupHash] {
+			t.Errorf("The lookup hash of code %s should be unique", code)
+		}
+		seenLookupHashes[recoveryCodes[i].LookupHash] = true
+	}
+}
+
+func TestIsRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"abcde-fghjk", true},
+		{"ABCDE-FGHJK", true},
+		{"abcdefghjk", true},
+		{"123456", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isRecoveryCode(test.code); got != test.want {
+			t.Errorf("isRecoveryCode(%q): want %v, got %v", test.code, test.want, got)
+		}
+	}
+}
+
+func (s *IntegrationTestSuite) insertRecoveryCode(userId string, tenantId string, code string) {
+	codeHash, err := argon2id.CreateHash(normaliseRecoveryCode(code), argon2id.DefaultParams)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lookupHash := recoveryCodeLookupHash(normaliseRecoveryCode(code))
+	_, err = s.dbRootConn.Exec("INSERT INTO recovery_code (tenant_id, user_account_id, lookup_hash, code_hash) VALUES ($1, $2, $3, $4)", tenantId, userId, lookupHash, codeHash)
+	if err != nil {
+		log.Fatalf("Could not insert recovery code: %s", err)
+	}
+}
+
+func (s *IntegrationTestSuite) TestLoginWithRecoveryCode() {
+	s.insertRecoveryCode(s.defaultUser.Id, s.defaultUser.TenantId, "abcde-fghjk")
+	s.insertRecoveryCode(s.defaultUser.Id, s.defaultUser.TenantId, "mnpqr-stuvw")
+
+	login := func(otp string) *httptest.ResponseRecorder {
+		reqBody := map[string]string{
+			"TenantId": s.defaultUser.TenantId,
+			"Email":    s.defaultUser.Email,
+			"Password": "jU%q837d!QP7",
+			"Totp":     otp,
+		}
+		bodyBuf := new(bytes.Buffer)
+		json.NewEncoder(bodyBuf).Encode(reqBody)
+
+		r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// Codes can be entered in uppercase
+	w := login("ABCDE-FGHJK")
+	s.expectHttpStatus(w, 200)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"RECOVERY-CODE-USED"`, `"remaining":1`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"SESSION-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHENTICATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// Each code can only be used once
+	w = login("abcde-fghjk")
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+	var unused int
+	err := s.dbRootConn.QueryRow("SELECT count(*) FROM recovery_code WHERE user_account_id = $1 AND used_at IS NULL", s.defaultUser.Id).Scan(&unused)
+	s.Equal(nil, err)
+	s.Equal(1, unused, "Only the code that was used should have been consumed")
+}
+
+func (s *IntegrationTestSuite) TestLoginWithRecoveryCodeWrongPassword() {
+	s.insertRecoveryCode(s.defaultUser.Id, s.defaultUser.TenantId, "abcde-fghjk")
+
+	reqBody := map[string]string{
+		"TenantId": s.defaultUser.TenantId,
+		"Email":    s.defaultUser.Email,
+		"Password": "abcd1234!@#$%",
+		"Totp":     "abcde-fghjk",
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+	// The code should not be consumed if the password is wrong
+	s.expectSelectQueryToReturnOneRow("recovery_code", map[string]any{"user_account_id": s.defaultUser.Id, "used_at": ""})
+}
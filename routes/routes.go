@@ -9,7 +9,6 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
-	"github.com/pquerna/otp/totp"
 
 	"multi-tenant-HR-information-system-backend/httperror"
 	"multi-tenant-HR-information-system-backend/storage"
@@ -25,9 +24,14 @@ type Router struct {
 	rootLogger          *tailoredLogger
 	sessionStore        sessions.Store
 	authEnforcer        casbin.IEnforcer
+	lockoutPolicy       LockoutPolicy
+	sessionPolicy       SessionPolicy
+	readinessChecks     map[string]ReadinessCheck
+	metrics             *metrics
+	oidcProviders       *oidcProviderCache
 }
 
-func NewRouter(storage storage.Storage, fileStorage storage.FileStorage, universalTranslator *ut.UniversalTranslator, validate *validator.Validate, rootLogger *tailoredLogger, sessionStore sessions.Store, authEnforcer casbin.IEnforcer) *Router {
+func NewRouter(storage storage.Storage, fileStorage storage.FileStorage, universalTranslator *ut.UniversalTranslator, validate *validator.Validate, rootLogger *tailoredLogger, sessionStore sessions.Store, authEnforcer casbin.IEnforcer, lockoutPolicy LockoutPolicy, sessionPolicy SessionPolicy) *Router {
 	r := mux.NewRouter()
 
 	router := &Router{
@@ -39,19 +43,40 @@ func NewRouter(storage storage.Storage, fileStorage storage.FileStorage, univers
 		rootLogger:          rootLogger,
 		sessionStore:        sessionStore,
 		authEnforcer:        authEnforcer,
+		lockoutPolicy:       lockoutPolicy,
+		sessionPolicy:       sessionPolicy,
+		readinessChecks: map[string]ReadinessCheck{
+			"postgres":    storage.Ping,
+			"fileStorage": fileStorage.Ping,
+		},
+		metrics:       newMetrics(),
+		oidcProviders: newOidcProviderCache(),
 	}
 
 	// Logging middleware wraps around error handling middleware because an error in logging has zero impact on the user
 	router.Use(setRequestLogger(router.rootLogger))
-	router.Use(logRequestCompletion)
+	router.Use(logRequestCompletion(router.metrics))
+	router.Use(setTranslator(router.universalTranslator)) // Before error handling, so that error messages can be translated
 	router.Use(errorHandling)
-	router.Use(setTranslator(router.universalTranslator))
-	router.Use(authenticateUser(router.sessionStore))
-	router.Use(verifyAuthorization(router.authEnforcer))
 
+	// Probes are called by the orchestrator, which is neither authenticated nor authorised
+	r.HandleFunc("/healthz", router.handleHealthz).Methods("GET")
+	r.HandleFunc("/readyz", router.handleReadyz).Methods("GET")
+
+	// The subrouter's middleware runs after the router's, so every /api route is authenticated & authorised
 	apiRouter := r.PathPrefix("/api").Subrouter()
+	apiRouter.Use(verifyRequestOrigin(router.sessionPolicy.TrustedOrigins))
+	apiRouter.Use(authenticateUser(router.sessionStore, router.storageFor))
+	apiRouter.Use(verifyAuthorization(router.authEnforcer, router.metrics))
+
+	apiRouter.HandleFunc("/openapi.json", router.handleGetOpenApiDocument).Methods("GET")
+
 	apiRouter.HandleFunc("/session", router.handleLogin).Methods("POST")
 	apiRouter.HandleFunc("/session", router.handleLogout).Methods("DELETE")
+	apiRouter.HandleFunc("/password", router.handleChangePassword).Methods("PUT")
+	apiRouter.HandleFunc("/password-reset", router.handleResetPassword).Methods("POST")
+	apiRouter.HandleFunc("/totp-enrollment", router.handleStartTotpEnrollment).Methods("POST")
+	apiRouter.HandleFunc("/totp-enrollment", router.handleConfirmTotpEnrollment).Methods("PUT")
 
 
 	tenantRouter := apiRouter.PathPrefix("/tenants/{tenantId}").Subrouter()
@@ -60,6 +85,23 @@ func NewRouter(storage storage.Storage, fileStorage storage.FileStorage, univers
 	tenantRouter.HandleFunc("/divisions/{divisionId}/departments/{departmentId}", router.handleCreateDepartment).Methods("POST")
 
 	tenantRouter.HandleFunc("/policies", router.handleCreatePolicies).Methods("POST")
+	tenantRouter.HandleFunc("/policies", router.handleGetPolicies).Methods("GET")
+	tenantRouter.HandleFunc("/policies/{subject}", router.handleGetPolicies).Methods("GET")
+	tenantRouter.HandleFunc("/policies/{subject}", router.handleReplacePolicies).Methods("PUT")
+	tenantRouter.HandleFunc("/policies/{subject}", router.handleDeletePolicies).Methods("DELETE")
+	tenantRouter.HandleFunc("/authorization-decision", router.handleExplainAuthorizationDecision).Methods("GET")
+
+	tenantRouter.HandleFunc("/sso/oidc", router.handleSetOidcConfiguration).Methods("PUT")
+	tenantRouter.HandleFunc("/sso/oidc/login", router.handleOidcLogin).Methods("GET")
+	tenantRouter.HandleFunc("/sso/oidc/callback", router.handleOidcCallback).Methods("GET")
+	tenantRouter.HandleFunc("/sso/saml", router.handleSetSamlConfiguration).Methods("PUT")
+	tenantRouter.HandleFunc("/sso/saml/metadata", router.handleGetSamlMetadata).Methods("GET")
+	tenantRouter.HandleFunc("/sso/saml/login", router.handleSamlLogin).Methods("GET")
+	tenantRouter.HandleFunc("/sso/saml/acs", router.handleSamlAcs).Methods("POST")
+
+	tenantRouter.HandleFunc("/service-accounts/{serviceAccountId}", router.handleCreateServiceAccount).Methods("POST")
+	tenantRouter.HandleFunc("/service-accounts/{serviceAccountId}/api-tokens/{apiTokenId}", router.handleCreateApiToken).Methods("POST")
+	tenantRouter.HandleFunc("/service-accounts/{serviceAccountId}/api-tokens/{apiTokenId}", router.handleRevokeApiToken).Methods("DELETE")
 
 	tenantRouter.HandleFunc("/positions/{positionId}", router.handleCreatePosition).Methods("POST")
 
@@ -71,7 +113,30 @@ func NewRouter(storage storage.Storage, fileStorage storage.FileStorage, univers
 
 	userRouter.HandleFunc("/positions/{positionId}", router.handleCreatePositionAssignment).Methods("POST")
 
+	userRouter.HandleFunc("/roles", router.handleGetRoleAssignments).Methods("GET")
+	userRouter.HandleFunc("/roles", router.handleReplaceRoleAssignments).Methods("PUT")
 	userRouter.HandleFunc("/roles/{roleName}", router.handleCreateRoleAssignment).Methods("POST")
+	userRouter.HandleFunc("/roles/{roleName}", router.handleDeleteRoleAssignment).Methods("DELETE")
+	userRouter.HandleFunc("/permissions", router.handleGetUserPermissions).Methods("GET")
+
+	userRouter.HandleFunc("/sessions", router.handleGetUserSessions).Methods("GET")
+	userRouter.HandleFunc("/sessions", router.handleRevokeAllUserSessions).Methods("DELETE")
+	userRouter.HandleFunc("/sessions/{sessionId}", router.handleRevokeUserSession).Methods("DELETE")
+
+	userRouter.HandleFunc("/api-tokens/{apiTokenId}", router.handleCreateApiToken).Methods("POST")
+	userRouter.HandleFunc("/api-tokens/{apiTokenId}", router.handleRevokeApiToken).Methods("DELETE")
+
+	userRouter.HandleFunc("/password-reset-token", router.handleCreatePasswordResetToken).Methods("POST")
+	userRouter.HandleFunc("/totp", router.handleResetTotp).Methods("DELETE")
+
+	userRouter.HandleFunc("/job-requisitions/role-requestor", router.handleGetJobRequisitionsAsRequestor).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-requestor/{jobRequisitionId}", router.handleGetJobRequisitionsAsRequestor).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-supervisor", router.handleGetJobRequisitionsAsSupervisor).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-supervisor/{jobRequisitionId}", router.handleGetJobRequisitionsAsSupervisor).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-hr-approver", router.handleGetJobRequisitionsAsHrApprover).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-hr-approver/{jobRequisitionId}", router.handleGetJobRequisitionsAsHrApprover).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-recruiter", router.handleGetJobRequisitionsAsRecruiter).Methods("GET")
+	userRouter.HandleFunc("/job-requisitions/role-recruiter/{jobRequisitionId}", router.handleGetJobRequisitionsAsRecruiter).Methods("GET")
 
 	userRouter.HandleFunc("/job-requisitions/role-requestor/{jobRequisitionId}", router.handleCreateJobRequisition).Methods("POST")
 	userRouter.HandleFunc("/job-requisitions/role-supervisor/{jobRequisitionId}/supervisor-decision", router.handleSupervisorApproveJobRequisition).Methods("POST")
@@ -82,7 +147,7 @@ func NewRouter(storage storage.Storage, fileStorage storage.FileStorage, univers
 	userRouter.HandleFunc("/job-requisitions/role-requestor/{jobRequisitionId}/job-applications/{jobApplicationId}/hiring-manager-decision", router.handleSetHiringManagerDecision).Methods("POST")	
 	userRouter.HandleFunc("/job-requisitions/role-recruiter/{jobRequisitionId}/job-applications/{jobApplicationId}/applicant-decision", router.handleSetApplicantDecision).Methods("POST")		
 
-	router.NotFoundHandler = setRequestLogger(router.rootLogger)(errorHandling(http.HandlerFunc(router.handleNotFound))) // Custom 404 handler
+	router.NotFoundHandler = setRequestLogger(router.rootLogger)(setTranslator(router.universalTranslator)(errorHandling(http.HandlerFunc(router.handleNotFound)))) // Custom 404 handler
 
 	return router
 }
@@ -91,35 +156,85 @@ func (router *Router) handleNotFound(w http.ResponseWriter, r *http.Request) {
 	sendToErrorHandlingMiddleware(Err404NotFound, r)
 }
 
-func (router *Router) validateCredentials(email string, tenantId string, password string, otp string) (bool, error) {
+// Either a TOTP or one of the user's recovery codes can be provided as the otp. Both are consumed on use
+// Returns ErrTotpEnrollmentRequired if the password is correct but the user has yet to enroll in TOTP
+func (router *Router) validateCredentials(r *http.Request, email string, tenantId string, password string, otp string) (bool, error) {
+	user, passwordMatch, err := router.validatePassword(r, email, tenantId, password)
+	if err != nil {
+		return false, err
+	}
+	// The failed attempt has already been recorded by validatePassword
+	if !passwordMatch {
+		return false, nil
+	}
+
+	return router.validateOtp(r, user, email, tenantId, otp)
+}
+
+// Validates the otp of a user whose password has already been validated. The failed logins are reset if the otp is valid
+// Returns ErrTotpEnrollmentRequired if the user has yet to enroll in TOTP
+func (router *Router) validateOtp(r *http.Request, user storage.User, email string, tenantId string, otp string) (bool, error) {
+	if user.TotpSecretKey == "" {
+		return false, ErrTotpEnrollmentRequired
+	}
+
+	valid, err := router.useTotp(r, user, user.TotpSecretKey, otp)
+	if err != nil {
+		return false, err
+	}
+
+	// Recovery codes are only checked if the password is correct, so that they cannot be used up by someone guessing them
+	if !valid && isRecoveryCode(otp) {
+		valid, err = router.useRecoveryCode(r, user, otp)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if !valid {
+		return false, router.recordFailedLogin(r, tenantId, email, "INVALID-OTP")
+	}
+
+	return true, router.resetFailedLogins(r, tenantId, email)
+}
+
+// Only validates the password, for flows where the user cannot provide a TOTP yet (i.e. TOTP enrollment)
+// Wrong passwords count towards the lockout of the tenant & email, regardless of whether the user exists
+func (router *Router) validatePassword(r *http.Request, email string, tenantId string, password string) (storage.User, bool, error) {
+	err := router.checkLoginLockout(r, tenantId, email)
+	if err != nil {
+		return storage.User{}, false, err
+	}
+
 	filter := storage.User{
 		TenantId: tenantId,
 		Email:    email,
 	}
-	users, err := router.storage.GetUsers(filter)
+	users, _, err := router.storageFor(r).GetUsers(filter, storage.PageRequest{})
 	if err != nil {
-		return false, err
+		return storage.User{}, false, err
 	}
 
 	var user storage.User
 	if len(users) == 0 {
-		// If the user does not exist, use the default password and totp secret key
+		// If the user does not exist, use the default password
 		// The password hash is pre-generated using the password "default"
 		// Executing the password check nonetheless prevents timing attacks
 		user = storage.User{
-			Password:      `$argon2id$v=19$m=65536,t=1,p=8$RWNiQ1R3UTVnQ1Fxb3dQdg$y0BaFbMhsPz4YqIuXWe5pUPF/1g66t2fogccTlkYpyQ`,
-			TotpSecretKey: `default`,
+			Password: `$argon2id$v=19$m=65536,t=1,p=8$RWNiQ1R3UTVnQ1Fxb3dQdg$y0BaFbMhsPz4YqIuXWe5pUPF/1g66t2fogccTlkYpyQ`,
 		}
 	} else {
 		user = users[0]
 	}
 
-	//validate the password & TOTP
 	passwordMatch, err := argon2id.ComparePasswordAndHash(password, user.Password)
 	if err != nil {
-		return false, httperror.NewInternalServerError(err)
+		return storage.User{}, false, httperror.NewInternalServerError(err)
+	}
+
+	if !passwordMatch || len(users) == 0 {
+		return storage.User{}, false, router.recordFailedLogin(r, tenantId, email, "INVALID-PASSWORD")
 	}
-	valid := totp.Validate(otp, user.TotpSecretKey)
 
-	return passwordMatch && valid && len(users) != 0, nil
+	return user, true, nil
 }
@@ -0,0 +1,419 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// The session that binds the SAML request to the browser that started the login, between the redirect & the ACS
+const samlSessionName = "saml"
+
+const samlRequestMaxAge = 10 * time.Minute // Time that the user has to sign in with the identity provider
+
+// Sets (or replaces) the SAML identity provider that the tenant's users sign in with
+func (router *Router) handleSetSamlConfiguration(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		EntityId        string
+		AcsUrl          string
+		IdpMetadata     string
+		EmailAttribute  string
+		JitProvisioning bool
+	}
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId        string `validate:"required,notBlank,uuid" name:"tenant id"`
+		EntityId        string `validate:"required,notBlank" name:"entity id"`
+		AcsUrl          string `validate:"required,notBlank,url" name:"acs url"`
+		IdpMetadata     string `validate:"required,notBlank" name:"identity provider metadata"`
+		EmailAttribute  string
+		JitProvisioning bool
+	}
+	input := Input{
+		TenantId:        vars["tenantId"],
+		EntityId:        reqBody.EntityId,
+		AcsUrl:          reqBody.AcsUrl,
+		IdpMetadata:     reqBody.IdpMetadata,
+		EmailAttribute:  reqBody.EmailAttribute,
+		JitProvisioning: reqBody.JitProvisioning,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	idpMetadata, err := parseIdpMetadata(input.IdpMetadata)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	config := storage.SamlConfiguration{
+		TenantId:        input.TenantId,
+		EntityId:        input.EntityId,
+		AcsUrl:          input.AcsUrl,
+		IdpMetadata:     input.IdpMetadata,
+		EmailAttribute:  input.EmailAttribute,
+		JitProvisioning: input.JitProvisioning,
+	}
+	err = router.storageFor(r).SetSamlConfiguration(config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("SAML-CONFIGURATION-SET", "tenantId", config.TenantId, "idpEntityId", idpMetadata.EntityID,
+		"jitProvisioning", config.JitProvisioning)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Returns the service provider metadata, which the tenant's admins register with their identity provider
+func (router *Router) handleGetSamlMetadata(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	config, err := router.storageFor(r).GetSamlConfiguration(input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	serviceProvider, err := newSamlServiceProvider(config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	metadata, err := xml.MarshalIndent(serviceProvider.Metadata(), "", "  ")
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("SAML-METADATA-RETRIEVED", "tenantId", input.TenantId)
+
+	w.Header().Add("content-type", "application/samlmetadata+xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(metadata)
+}
+
+// Redirects the user to the tenant's identity provider to sign in
+func (router *Router) handleSamlLogin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	config, err := router.storageFor(r).GetSamlConfiguration(input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	serviceProvider, err := newSamlServiceProvider(config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	authnRequest, err := serviceProvider.MakeAuthenticationRequest(
+		serviceProvider.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding,
+	)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	// The request is tracked in postgres so that each one can only be responded to once
+	err = router.storageFor(r).CreateSamlRequest(storage.SamlRequest{
+		Id:        authnRequest.ID,
+		TenantId:  input.TenantId,
+		ExpiresAt: time.Now().Add(samlRequestMaxAge),
+	})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	// The request is also bound to this browser, so that a response to another browser's request (e.g. the attacker's own
+	// login) cannot be POSTed by this browser to sign its user in as someone else (i.e. login CSRF)
+	session, err := router.sessionStore.Get(r, samlSessionName)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	session.Options = router.sessionPolicy.samlCookieOptions(int(samlRequestMaxAge.Seconds()))
+	session.Values["samlTenantId"] = input.TenantId
+	session.Values["samlRequestId"] = authnRequest.ID
+
+	err = router.sessionStore.Save(r, w, session)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	redirectUrl, err := authnRequest.Redirect("", serviceProvider)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("SAML-LOGIN-STARTED", "tenantId", input.TenantId, "requestId", authnRequest.ID)
+
+	http.Redirect(w, r, redirectUrl.String(), http.StatusFound)
+}
+
+// The identity provider POSTs the signed assertion here after the user has signed in (i.e. the Assertion Consumer Service)
+// The assertion's email is matched against the emails of the tenant's users, & the same session as handleLogin is created
+func (router *Router) handleSamlAcs(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	config, err := router.storageFor(r).GetSamlConfiguration(input.TenantId)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	serviceProvider, err := newSamlServiceProvider(config)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+
+	// The request that is being responded to is only read here to look it up. The response is validated in full by ParseResponse
+	err = r.ParseForm()
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidSamlResponse, r)
+		return
+	}
+	rawResponse, err := base64.StdEncoding.DecodeString(r.PostForm.Get("SAMLResponse"))
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidSamlResponse, r)
+		return
+	}
+	var response saml.Response
+	err = xml.Unmarshal(rawResponse, &response)
+	if err != nil || response.InResponseTo == "" {
+		sendToErrorHandlingMiddleware(ErrInvalidSamlResponse, r)
+		return
+	}
+
+	// The response must answer the request that this browser started (i.e. it is not login CSRF)
+	session, err := router.sessionStore.Get(r, samlSessionName)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	tenantId, _ := session.Values["samlTenantId"].(string)
+	requestId, _ := session.Values["samlRequestId"].(string)
+	if requestId == "" || subtle.ConstantTimeCompare([]byte(requestId), []byte(response.InResponseTo)) != 1 || tenantId != input.TenantId {
+		reqLogger.Warn("SAML-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "REQUEST-FROM-ANOTHER-BROWSER", "requestId", response.InResponseTo)
+		sendToErrorHandlingMiddleware(ErrInvalidSamlResponse, r)
+		return
+	}
+
+	// The signature is validated before the request is used up, so that a forged response cannot use up the user's request
+	assertion, err := serviceProvider.ParseResponse(r, []string{response.InResponseTo})
+	if err != nil {
+		// The reason is only available from the InvalidResponseError's private error, which must not be returned to the user
+		errorMessage := err.Error()
+		if invalidResponseErr, ok := err.(*saml.InvalidResponseError); ok {
+			errorMessage = invalidResponseErr.PrivateErr.Error()
+		}
+		reqLogger.Warn("SAML-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "INVALID-ASSERTION", "errorMessage", errorMessage)
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	// Each request can only be responded to once, so a captured response cannot be replayed
+	err = router.storageFor(r).UseSamlRequest(storage.SamlRequest{Id: response.InResponseTo, TenantId: input.TenantId})
+	if httpErr, ok := err.(*httperror.Error); ok && httpErr.Status == http.StatusNotFound {
+		reqLogger.Warn("SAML-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "UNKNOWN-REQUEST", "requestId", response.InResponseTo)
+		sendToErrorHandlingMiddleware(ErrInvalidSamlResponse, r)
+		return
+	}
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	session.Options = router.sessionPolicy.samlCookieOptions(-1)
+	err = router.sessionStore.Save(r, w, session)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	email := getSamlEmail(assertion, config.EmailAttribute)
+	if email == "" {
+		reqLogger.Warn("SAML-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "MISSING-EMAIL", "emailAttribute", config.EmailAttribute)
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	users, _, err := router.storageFor(r).GetUsers(storage.User{TenantId: input.TenantId, Email: email}, storage.PageRequest{})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	var user storage.User
+	if len(users) != 0 {
+		user = users[0]
+	} else if config.JitProvisioning && router.validate.Var(email, "email") == nil {
+		// The user signs in through the identity provider, so the generated password is never shared with them
+		user = storage.User{Id: uuid.New().String(), TenantId: input.TenantId, Email: email}
+		_, err = router.provisionUser(r, user.Id, user.TenantId, user.Email)
+		if err != nil {
+			sendToErrorHandlingMiddleware(err, r)
+			return
+		}
+	} else {
+		reqLogger.Warn("SAML-LOGIN-FAILED", "tenantId", input.TenantId, "reason", "USER-NOT-FOUND", "email", email)
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+
+	err = router.createSession(w, r, user)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger.Info("USER-AUTHENTICATED", "userId", user.Id, "tenantId", user.TenantId, "idpEntityId", serviceProvider.IDPMetadata.EntityID)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Assertions are only accepted if they are signed with one of the certificates in the identity provider's metadata
+func newSamlServiceProvider(config storage.SamlConfiguration) (*saml.ServiceProvider, error) {
+	idpMetadata, err := parseIdpMetadata(config.IdpMetadata)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+
+	acsUrl, err := url.Parse(config.AcsUrl)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+
+	serviceProvider := &saml.ServiceProvider{
+		EntityID:          config.EntityId,
+		AcsURL:            *acsUrl,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.EmailAddressNameIDFormat,
+		AllowIDPInitiated: false, // Responses must be to a request made by handleSamlLogin
+	}
+
+	return serviceProvider, nil
+}
+
+// The metadata must describe an identity provider with a signing certificate & an endpoint that users can be redirected to
+func parseIdpMetadata(metadata string) (*saml.EntityDescriptor, error) {
+	var idpMetadata saml.EntityDescriptor
+	err := xml.Unmarshal([]byte(metadata), &idpMetadata)
+	if err != nil || len(idpMetadata.IDPSSODescriptors) == 0 {
+		return nil, ErrInvalidSamlMetadata
+	}
+
+	hasSigningCertificate := false
+	hasRedirectEndpoint := false
+	for _, idpSsoDescriptor := range idpMetadata.IDPSSODescriptors {
+		for _, keyDescriptor := range idpSsoDescriptor.KeyDescriptors {
+			if keyDescriptor.Use != "encryption" && len(keyDescriptor.KeyInfo.X509Data.X509Certificates) != 0 {
+				hasSigningCertificate = true
+			}
+		}
+		for _, endpoint := range idpSsoDescriptor.SingleSignOnServices {
+			if endpoint.Binding == saml.HTTPRedirectBinding {
+				hasRedirectEndpoint = true
+			}
+		}
+	}
+	if !hasSigningCertificate || !hasRedirectEndpoint {
+		return nil, ErrInvalidSamlMetadata
+	}
+
+	return &idpMetadata, nil
+}
+
+// Returns the NameID if no email attribute is configured
+func getSamlEmail(assertion *saml.Assertion, emailAttribute string) string {
+	if emailAttribute == "" {
+		if assertion.Subject == nil || assertion.Subject.NameID == nil {
+			return ""
+		}
+		return assertion.Subject.NameID.Value
+	}
+
+	for _, attributeStatement := range assertion.AttributeStatements {
+		for _, attribute := range attributeStatement.Attributes {
+			if (attribute.Name == emailAttribute || attribute.FriendlyName == emailAttribute) && len(attribute.Values) != 0 {
+				return attribute.Values[0].Value
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,353 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"multi-tenant-HR-information-system-backend/samlmock"
+)
+
+// Starts a mock IdP, configures it as the default tenant's identity provider & registers the tenant's service provider metadata
+// with it. Close the IdP at the end of the test
+func (s *IntegrationTestSuite) setUpSaml(emailAttribute string, jitProvisioning bool) *samlmock.Server {
+	idp, err := samlmock.NewServer()
+	if err != nil {
+		log.Fatalf("Could not start the mock IdP: %s", err)
+	}
+
+	idpMetadata, err := idp.Metadata()
+	if err != nil {
+		log.Fatalf("Could not get the mock IdP's metadata: %s", err)
+	}
+
+	reqBody := map[string]any{
+		"EntityId":        fmt.Sprintf("http://localhost:3000/api/tenants/%s/sso/saml/metadata", s.defaultTenant.Id),
+		"AcsUrl":          fmt.Sprintf("http://localhost:3000/api/tenants/%s/sso/saml/acs", s.defaultTenant.Id),
+		"IdpMetadata":     string(idpMetadata),
+		"EmailAttribute":  emailAttribute,
+		"JitProvisioning": jitProvisioning,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("PUT", fmt.Sprintf("/api/tenants/%s/sso/saml", s.defaultTenant.Id), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	// The tenant's admin would register the service provider with their IdP
+	r, err = http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/sso/saml/metadata", s.defaultTenant.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	err = idp.AddServiceProvider(w.Body.Bytes())
+	if err != nil {
+		log.Fatalf("Could not register the service provider with the mock IdP: %s", err)
+	}
+
+	return idp
+}
+
+// Starts the login, signs in with the IdP & returns the SAML response that the IdP has the user's browser POST to the ACS,
+// along with the cookies that the browser was given when starting the login
+func (s *IntegrationTestSuite) signInWithSaml(idp *samlmock.Server) (string, []*http.Cookie) {
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/sso/saml/login", s.defaultTenant.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 302)
+
+	authenticationUrl := w.Header().Get("Location")
+	s.Contains(authenticationUrl, idp.URL+"/sso", "The user should be redirected to the IdP")
+
+	res, err := http.Get(authenticationUrl)
+	if err != nil {
+		log.Fatalf("Could not sign in with the mock IdP: %s", err)
+	}
+	defer res.Body.Close()
+
+	// The IdP responds with a form that the browser automatically submits to the ACS
+	form, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	match := regexp.MustCompile(`name="SAMLResponse" value="([^"]*)"`).FindSubmatch(form)
+	if match == nil {
+		log.Fatalf("The mock IdP did not return a SAML response: %s", form)
+	}
+
+	return html.UnescapeString(string(match[1])), w.Result().Cookies()
+}
+
+func (s *IntegrationTestSuite) newSamlAcsRequest(samlResponse string, cookies []*http.Cookie) *http.Request {
+	form := url.Values{"SAMLResponse": {samlResponse}}
+	r, err := http.NewRequest("POST", fmt.Sprintf("/api/tenants/%s/sso/saml/acs", s.defaultTenant.Id), strings.NewReader(form.Encode()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+
+	return r
+}
+
+func (s *IntegrationTestSuite) TestSamlLogin() {
+	idp := s.setUpSaml("", false)
+	defer idp.Close()
+	idp.SetUser(s.defaultSupervisor.Email, nil)
+
+	s.expectSelectQueryToReturnOneRow("saml_configuration", map[string]any{"tenant_id": s.defaultTenant.Id, "jit_provisioning": false})
+
+	samlResponse, cookies := s.signInWithSaml(idp)
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(samlResponse, cookies))
+	s.expectHttpStatus(w, 200)
+
+	var sessionCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == authSessionName {
+			sessionCookie = cookie
+		}
+	}
+	s.NotNil(sessionCookie, "The same session as a password login should be created")
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"SESSION-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHENTICATED"`, fmt.Sprintf(`"userId":"%s"`, s.defaultSupervisor.Id))
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// The response cannot be replayed
+	s.logOutput.Reset()
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(samlResponse, cookies))
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-SAML-RESPONSE-ERROR")
+
+	reader = bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"SAML-LOGIN-FAILED"`, `"reason":"REQUEST-FROM-ANOTHER-BROWSER"`)
+	s.expectSelectQueryToReturnNoRows("saml_request", map[string]any{"tenant_id": s.defaultTenant.Id})
+}
+
+func (s *IntegrationTestSuite) TestSamlLoginShouldRejectResponsesToAnotherBrowser() {
+	idp := s.setUpSaml("", false)
+	defer idp.Close()
+	idp.SetUser(s.defaultSupervisor.Email, nil)
+
+	// e.g. an attacker signs in with their own account & has the victim's browser POST the response
+	samlResponse, _ := s.signInWithSaml(idp)
+	_, victimCookies := s.signInWithSaml(idp)
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(samlResponse, victimCookies))
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-SAML-RESPONSE-ERROR")
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"SAML-LOGIN-FAILED"`, `"reason":"REQUEST-FROM-ANOTHER-BROWSER"`)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(samlResponse, nil))
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-SAML-RESPONSE-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestSamlLoginShouldNotUseUpRequestsForInvalidResponses() {
+	idp := s.setUpSaml("", false)
+	defer idp.Close()
+	idp.SetUser(s.defaultSupervisor.Email, nil)
+
+	samlResponse, cookies := s.signInWithSaml(idp)
+
+	// An unsigned response to the same request, e.g. forged by an attacker who has learnt the request id
+	rawResponse, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		log.Fatal(err)
+	}
+	unsigned := regexp.MustCompile(`(?s)<ds:Signature.*?</ds:Signature>`).ReplaceAll(rawResponse, nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(base64.StdEncoding.EncodeToString(unsigned), cookies))
+	s.expectHttpStatus(w, 401)
+	s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+
+	// The user can still sign in with the genuine response
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(samlResponse, cookies))
+	s.expectHttpStatus(w, 200)
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+}
+
+func (s *IntegrationTestSuite) TestSamlLoginWithEmailAttribute() {
+	idp := s.setUpSaml("mail", false)
+	defer idp.Close()
+	idp.SetUser("12345@idp.example.com", map[string]string{"mail": s.defaultSupervisor.Email})
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(s.signInWithSaml(idp)))
+	s.expectHttpStatus(w, 200)
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+}
+
+func (s *IntegrationTestSuite) TestSamlLoginWithJitProvisioning() {
+	idp := s.setUpSaml("", true)
+	defer idp.Close()
+	email := "new.hire@hrisEnterprises.org"
+	idp.SetUser(email, nil)
+
+	samlResponse, cookies := s.signInWithSaml(idp)
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(samlResponse, cookies))
+	s.expectHttpStatus(w, 200)
+
+	// The user is created in the same way as an admin would create them
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"tenant_id": s.defaultTenant.Id, "email": email, "must_change_password": true})
+
+	var userId string
+	err := s.dbRootConn.QueryRow("SELECT id FROM user_account WHERE email = $1", email).Scan(&userId)
+	if err != nil {
+		log.Fatalf("Could not get the provisioned user: %s", err)
+	}
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"user_account_id": userId})
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-CREATED"`, fmt.Sprintf(`"userId":"%s"`, userId))
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"SESSION-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHENTICATED"`, fmt.Sprintf(`"userId":"%s"`, userId))
+
+	// The provisioned user is signed in on subsequent logins
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newSamlAcsRequest(s.signInWithSaml(idp)))
+	s.expectHttpStatus(w, 200)
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"tenant_id": s.defaultTenant.Id, "email": email})
+}
+
+func (s *IntegrationTestSuite) TestSamlLoginShouldFail() {
+	idp := s.setUpSaml("mail", false)
+	defer idp.Close()
+
+	// Changes the signed user in the response, which invalidates its signature
+	tamper := func(samlResponse string) string {
+		rawResponse, err := base64.StdEncoding.DecodeString(samlResponse)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(bytes.ReplaceAll(rawResponse, []byte("unknown@hrisEnterprises.org"),
+			[]byte(s.defaultSupervisor.Email)))
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		modify     func(string) string
+		wantReason string
+	}{
+		{
+			"Should fail because the user does not exist in the tenant & JIT provisioning is disabled",
+			map[string]string{"mail": "unknown@hrisEnterprises.org"},
+			func(samlResponse string) string { return samlResponse },
+			"USER-NOT-FOUND",
+		},
+		{
+			"Should fail because the email attribute is missing",
+			map[string]string{"uid": "12345"},
+			func(samlResponse string) string { return samlResponse },
+			"MISSING-EMAIL",
+		},
+		{
+			"Should fail because the assertion has been tampered with",
+			map[string]string{"mail": "unknown@hrisEnterprises.org"},
+			tamper,
+			"INVALID-ASSERTION",
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			idp.SetUser("12345@idp.example.com", test.attributes)
+
+			samlResponse, cookies := s.signInWithSaml(idp)
+			s.logOutput.Reset()
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, s.newSamlAcsRequest(test.modify(samlResponse), cookies))
+
+			s.expectHttpStatus(w, 401)
+			s.expectErrorCode(w, "USER-UNAUTHENTICATED")
+			s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"user_account_id": s.defaultSupervisor.Id})
+			s.expectSelectQueryToReturnNoRows("user_account", map[string]any{"email": "unknown@hrisEnterprises.org"})
+
+			reader := bufio.NewReader(s.logOutput)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+			s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"SAML-LOGIN-FAILED"`, fmt.Sprintf(`"reason":"%s"`, test.wantReason))
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestSetSamlConfigurationShouldValidateMetadata() {
+	reqBody := map[string]any{
+		"EntityId":    fmt.Sprintf("http://localhost:3000/api/tenants/%s/sso/saml/metadata", s.defaultTenant.Id),
+		"AcsUrl":      fmt.Sprintf("http://localhost:3000/api/tenants/%s/sso/saml/acs", s.defaultTenant.Id),
+		"IdpMetadata": `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com"></EntityDescriptor>`,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("PUT", fmt.Sprintf("/api/tenants/%s/sso/saml", s.defaultTenant.Id), bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-SAML-METADATA-ERROR")
+	s.expectSelectQueryToReturnNoRows("saml_configuration", map[string]any{"tenant_id": s.defaultTenant.Id})
+}
+
+func (s *IntegrationTestSuite) TestSamlLoginShouldValidateConfigurationExistence() {
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/sso/saml/login", s.defaultTenant.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+}
@@ -2,9 +2,9 @@ package routes
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
 
+	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
 
 	"multi-tenant-HR-information-system-backend/httperror"
@@ -13,6 +13,39 @@ import (
 
 const authSessionName = "authenticated"
 
+// Cookie & CSRF settings for session-based authentication. Secure should be enabled wherever the API is served over HTTPS
+// SameSite must not be Strict if OIDC is used, as the identity provider redirects the user back from its own site
+// TrustedOrigins (e.g. https://hr.example.com) may make state-changing requests with the session cookie, in addition to the API's own origin
+type SessionPolicy struct {
+	Secure         bool
+	SameSite       http.SameSite
+	TrustedOrigins []string
+}
+
+func (policy SessionPolicy) cookieOptions(maxAge int) *sessions.Options {
+	return &sessions.Options{
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   policy.Secure,
+		SameSite: policy.SameSite,
+	}
+}
+
+// The SAML identity provider POSTs the response from its own site, which browsers only send cookies with if they are SameSite=None
+// Browsers reject SameSite=None cookies that are not Secure, so the cookie is Secure regardless of the policy
+func (policy SessionPolicy) samlCookieOptions(maxAge int) *sessions.Options {
+	options := policy.cookieOptions(maxAge)
+	options.SameSite = http.SameSiteNoneMode
+	options.Secure = true
+	return options
+}
+
+// Implemented by session stores that can discard a session's id without writing a cookie, so that the next save issues a new id
+type sessionRegenerator interface {
+	Regenerate(session *sessions.Session) error
+}
+
 func (router *Router) handleLogin(w http.ResponseWriter, r *http.Request) {
 	type requestBody struct {
 		TenantId string
@@ -28,8 +61,8 @@ func (router *Router) handleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type Input struct {
-		TenantId string `validate:"required" name:"tenant id"`
-		Email    string `validate:"required" name:"user email"`
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Email    string `validate:"required,notBlank,max=300" name:"user email"`
 		Password string
 		Totp     string
 	}
@@ -46,69 +79,87 @@ func (router *Router) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	valid, err := router.validateCredentials(reqBody.Email, reqBody.TenantId, reqBody.Password, reqBody.Totp)
+	user, passwordMatch, err := router.validatePassword(r, reqBody.Email, reqBody.TenantId, reqBody.Password)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
-	if !valid {
+	if !passwordMatch {
 		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
 		return
 	}
 
-	// If the session isn't in the req context, it tries to retrieve the it from the session store
-	// If it isn't in the session store, it returns a new session with an empty session id
-	session, err := router.sessionStore.Get(r, authSessionName)
+	valid, err := router.validateOtp(r, user, reqBody.Email, reqBody.TenantId, reqBody.Totp)
 	if err != nil {
-		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
-
-	session.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   86400,
-		HttpOnly: true,
-		//Secure: true, --> only in production where the frontend has an SSL certificate
+	if !valid {
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
 	}
 
-	session.Values["tenantId"] = reqBody.TenantId
-	session.Values["email"] = reqBody.Email
-
-	filter := storage.User{
-		TenantId: reqBody.TenantId,
-		Email:    reqBody.Email,
+	// Users still using a generated password must change it before they can log in
+	// This is only checked once both factors are valid, so that it cannot be used to tell whether a password is correct
+	if user.MustChangePassword {
+		sendToErrorHandlingMiddleware(ErrPasswordChangeRequired, r)
+		return
 	}
-	users, err := router.storage.GetUsers(filter)
+
+	err = router.createSession(w, r, user)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
-	if len(users) == 0 {
-		err := httperror.NewInternalServerError(
-			errors.New("race condition occurred: user was deleted after credentials validation but before session creation"),
-		)
-		sendToErrorHandlingMiddleware(err, r)
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("USER-AUTHENTICATED", "userId", user.Id, "tenantId", user.TenantId)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Creates the session that authenticateUser uses to identify the user. Used by every login method (e.g. password & TOTP, SSO)
+func (router *Router) createSession(w http.ResponseWriter, r *http.Request, user storage.User) error {
+	// If the session isn't in the req context, it tries to retrieve the it from the session store
+	// If it isn't in the session store, it returns a new session with an empty session id
+	session, err := router.sessionStore.Get(r, authSessionName)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	// A new session id is issued on every login, so that an id planted in the user's browser beforehand (i.e. session fixation)
+	// cannot be used to hijack the session
+	if regenerator, ok := router.sessionStore.(sessionRegenerator); ok {
+		err = regenerator.Regenerate(session)
+		if err != nil {
+			return httperror.NewInternalServerError(err)
+		}
+	} else {
+		session.ID = ""
 	}
-	session.Values["id"] = users[0].Id
+	session.Values = map[any]any{}
+
+	session.Options = router.sessionPolicy.cookieOptions(86400)
+
+	session.Values["tenantId"] = user.TenantId
+	session.Values["email"] = user.Email
+	session.Values["id"] = user.Id
 
 	err = router.sessionStore.Save(r, w, session)
 	if err != nil {
-		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
-		return
+		return httperror.NewInternalServerError(err)
 	}
 
 	// Check that session was saved & get its ID
 	s, err := router.sessionStore.Get(r, authSessionName)
 	if err != nil {
-		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
-		return
+		return httperror.NewInternalServerError(err)
 	}
 
 	reqLogger := getRequestLogger(r)
 	reqLogger.Info("SESSION-CREATED", "sessionId", s.ID)
-	reqLogger.Info("USER-AUTHENTICATED", "userId", users[0].Id, "tenantId", users[0].TenantId)
 
-	w.WriteHeader(http.StatusOK)
+	return nil
 }
 
 func (router *Router) handleLogout(w http.ResponseWriter, r *http.Request) {
@@ -122,9 +173,7 @@ func (router *Router) handleLogout(w http.ResponseWriter, r *http.Request) {
 	userId, _ := session.Values["id"].(string)
 	tenantId, sessionExists := session.Values["tenantId"].(string)
 
-	session.Options = &sessions.Options{
-		MaxAge: -1,
-	}
+	session.Options = router.sessionPolicy.cookieOptions(-1)
 
 	// Deletes the session from the storage & sets the cookie's max age to -1
 	err = router.sessionStore.Save(r, w, session)
@@ -142,3 +191,139 @@ func (router *Router) handleLogout(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+type userSessionResponseBody struct {
+	Id        string `json:"id"`
+	ClientIp  string `json:"clientIp"`
+	UserAgent string `json:"userAgent"`
+	Current   bool   `json:"current"` // Whether the session is the one used to make the request
+	ExpiresAt string `json:"expiresAt"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+func (router *Router) handleGetUserSessions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId   string `validate:"required,notBlank,uuid" name:"user id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		UserId:   vars["userId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	userSessions, err := router.storageFor(r).GetUserSessions(storage.UserSession{TenantId: input.TenantId, UserId: input.UserId})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	currentSession, err := router.sessionStore.Get(r, authSessionName)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	resBody := []userSessionResponseBody{}
+	for _, userSession := range userSessions {
+		resBody = append(resBody, userSessionResponseBody{
+			Id:        userSession.Id,
+			ClientIp:  userSession.ClientIp,
+			UserAgent: userSession.UserAgent,
+			Current:   userSession.SessionId == currentSession.ID,
+			ExpiresAt: userSession.ExpiresAt,
+			CreatedAt: userSession.CreatedAt,
+			UpdatedAt: userSession.UpdatedAt,
+		})
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("USER-SESSIONS-RETRIEVED", "userId", input.UserId, "tenantId", input.TenantId, "count", len(resBody))
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resBody)
+}
+
+// Revokes a single session of the user. Later attempts to use the session are logged as DELETED-SESSION-USED
+func (router *Router) handleRevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId  string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId    string `validate:"required,notBlank,uuid" name:"user id"`
+		SessionId string `validate:"required,notBlank,uuid" name:"session id"`
+	}
+	input := Input{
+		TenantId:  vars["tenantId"],
+		UserId:    vars["userId"],
+		SessionId: vars["sessionId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	filter := storage.UserSession{
+		Id:       input.SessionId,
+		TenantId: input.TenantId,
+		UserId:   input.UserId,
+	}
+	deleted, err := router.storageFor(r).DeleteUserSessions(filter)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if deleted == 0 {
+		sendToErrorHandlingMiddleware(Err404NotFound, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("SESSION-REVOKED", "sessionId", input.SessionId, "userId", input.UserId, "tenantId", input.TenantId,
+		"revokedBy", getAuthenticatedUser(r).Id)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Revokes every session of the user, e.g. to force-logout a compromised account
+func (router *Router) handleRevokeAllUserSessions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId   string `validate:"required,notBlank,uuid" name:"user id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		UserId:   vars["userId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	deleted, err := router.storageFor(r).DeleteUserSessions(storage.UserSession{TenantId: input.TenantId, UserId: input.UserId})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("ALL-SESSIONS-REVOKED", "userId", input.UserId, "tenantId", input.TenantId, "count", deleted,
+		"revokedBy", getAuthenticatedUser(r).Id)
+
+	w.WriteHeader(http.StatusOK)
+}
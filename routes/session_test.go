@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -143,12 +144,123 @@ func (s *IntegrationTestSuite) TestLoginWrongCredentials() {
 
 			reader := bufio.NewReader(s.logOutput)
 			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+			s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"LOGIN-FAILED"`, `"clientIp"`)
 			s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHENTICATED"`)
 			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 		})
 	}
 }
 
+func (s *IntegrationTestSuite) TestLoginInvalidInput() {
+	type requestBody struct {
+		TenantId string
+		Email    string
+		Password string
+		Totp     string
+	}
+
+	tests := []struct {
+		name  string
+		input requestBody
+	}{
+		{
+			"Login should fail because the tenant id is not a uuid",
+			requestBody{
+				TenantId: "abcd",
+				Email:    s.defaultUser.Email,
+				Password: "jU%q837d!QP7",
+				Totp:     "123456",
+			},
+		},
+		{
+			"Login should fail because the email is too long",
+			requestBody{
+				TenantId: s.defaultUser.TenantId,
+				Email:    strings.Repeat("a", 291) + "@gmail.com",
+				Password: "jU%q837d!QP7",
+				Totp:     "123456",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			bodyBuf := new(bytes.Buffer)
+			json.NewEncoder(bodyBuf).Encode(test.input)
+
+			r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, r)
+
+			// The input is rejected before the failed login is recorded, as it would not fit in login_attempt
+			s.expectHttpStatus(w, 400)
+			s.expectErrorCode(w, "INPUT-VALIDATION-ERROR")
+			s.expectSelectQueryToReturnNoRows("login_attempt", map[string]any{"email": test.input.Email})
+
+			reader := bufio.NewReader(s.logOutput)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+			s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"INPUT-VALIDATION-ERROR"`)
+			s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldRegenerateSessionId() {
+	type requestBody struct {
+		TenantId string
+		Email    string
+		Password string
+		Totp     string
+	}
+
+	totp, _ := totp.GenerateCode(s.defaultUser.TotpSecretKey, time.Now().UTC())
+	reqBody := requestBody{
+		TenantId: s.defaultUser.TenantId,
+		Email:    s.defaultUser.Email,
+		Password: "jU%q837d!QP7",
+		Totp:     totp,
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The session id was planted in the user's browser before they logged in (i.e. session fixation)
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+	plantedSession, err := s.sessionStore.Get(newRequestWithCookiesOf("GET", "/", r), authSessionName)
+	s.Equal(nil, err)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	cookies := w.Result().Cookies()
+	s.Equal(1, len(cookies), "Only the new session cookie should be set")
+	s.True(cookies[0].Secure)
+	s.True(cookies[0].HttpOnly)
+	s.Equal(http.SameSiteLaxMode, cookies[0].SameSite)
+
+	newSessionReq, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	newSessionReq.AddCookie(cookies[0])
+	newSession, err := s.sessionStore.Get(newSessionReq, authSessionName)
+	s.Equal(nil, err)
+	s.NotEqual(plantedSession.ID, newSession.ID)
+	s.Equal(s.defaultUser.Email, newSession.Values["email"])
+
+	// The planted session can no longer be used
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"id": plantedSession.ID})
+}
+
 func (s *IntegrationTestSuite) TestLogout() {
 	r, err := http.NewRequest("DELETE", "/api/session", nil)
 	if err != nil {
@@ -203,3 +315,180 @@ func (s *IntegrationTestSuite) TestLogoutTwice() {
 	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"SESSION-ALREADY-DELETED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
+
+// Returns a new request that carries the same cookies as the given request, but not its cached sessions
+func newRequestWithCookiesOf(method string, path string, cookieSource *http.Request) *http.Request {
+	r, err := http.NewRequest(method, path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, cookie := range cookieSource.Cookies() {
+		r.AddCookie(cookie)
+	}
+
+	return r
+}
+
+func (s *IntegrationTestSuite) getUserSessions(cookieSource *http.Request, tenantId string, userId string) []userSessionResponseBody {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions", tenantId, userId)
+	r := newRequestWithCookiesOf("GET", path, cookieSource)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	var resBody []userSessionResponseBody
+	err := json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err, "Response body should be a list of sessions")
+
+	return resBody
+}
+
+func (s *IntegrationTestSuite) TestGetUserSessions() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id)
+	r, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, newRequestWithCookiesOf("GET", path, r))
+
+	s.expectHttpStatus(w, 200)
+
+	var resBody []userSessionResponseBody
+	err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err, "Response body should be a list of sessions")
+	s.Equal(1, len(resBody), "Only the supervisor's session should be returned")
+	if len(resBody) == 1 {
+		s.Equal(true, resBody[0].Current, "The session used to make the request should be marked as current")
+	}
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-SESSIONS-RETRIEVED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestRevokeUserSession() {
+	// The supervisor is logged in on 2 devices
+	currentDevice, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(currentDevice, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	otherDevice, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(otherDevice, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	var otherSessionId string
+	for _, session := range s.getUserSessions(currentDevice, s.defaultSupervisor.TenantId, s.defaultSupervisor.Id) {
+		if !session.Current {
+			otherSessionId = session.Id
+		}
+	}
+	s.NotEqual("", otherSessionId, "The other device's session should have been listed")
+
+	// Revoke the other device's session
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions/%s", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id, otherSessionId)
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, newRequestWithCookiesOf("DELETE", path, currentDevice))
+
+	s.expectHttpStatus(w, 200)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"SESSION-REVOKED"`, otherSessionId)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// The revoked session can no longer be used
+	sessionsPath := fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id)
+	s.logOutput.Reset()
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, newRequestWithCookiesOf("GET", sessionsPath, otherDevice))
+
+	s.expectHttpStatus(w, 403)
+
+	reader = bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"DELETED-SESSION-USED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"AUTHORIZATION-DENIED"`)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"USER-UNAUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	s.Equal(1, len(s.getUserSessions(currentDevice, s.defaultSupervisor.TenantId, s.defaultSupervisor.Id)), "Only the current session should remain")
+}
+
+func (s *IntegrationTestSuite) TestRevokeUserSessionShouldValidateIdExistence() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions/%s", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id, "caaa7845-9601-4528-bd60-7cdae6cf298a")
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestRevokeAllUserSessionsAsAdmin() {
+	supervisorDevice, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(supervisorDevice, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	// The root role admin force-logs out the supervisor
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id)
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 200)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"ALL-SESSIONS-REVOKED"`, `"count":1`, s.defaultUser.Id)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// The supervisor's session can no longer be used
+	s.logOutput.Reset()
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, newRequestWithCookiesOf("GET", path, supervisorDevice))
+
+	s.expectHttpStatus(w, 403)
+
+	reader = bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"WARN"`, `"msg":"DELETED-SESSION-USED"`)
+
+	// The admin's own session is unaffected
+	s.Equal(1, len(s.getUserSessions(r, s.defaultUser.TenantId, s.defaultUser.Id)), "The admin's session should remain")
+}
+
+func (s *IntegrationTestSuite) TestRevokeAllUserSessionsShouldPreventIdExploit() {
+	// The supervisor attempts to log out the root role admin
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultUser.TenantId, s.defaultUser.Id)
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultSupervisor.Id, s.defaultSupervisor.TenantId, s.defaultSupervisor.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "USER-UNAUTHORISED")
+}
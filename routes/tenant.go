@@ -41,7 +41,7 @@ func (router *Router) handleCreateTenant(w http.ResponseWriter, r *http.Request)
 		Id:   input.Id,
 		Name: input.Name,
 	}
-	err = router.storage.CreateTenant(tenant)
+	err = router.storageFor(r).CreateTenant(tenant)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -88,7 +88,7 @@ func (router *Router) handleCreateDivision(w http.ResponseWriter, r *http.Reques
 		TenantId: input.TenantId,
 		Name:     input.Name,
 	}
-	err = router.storage.CreateDivision(division)
+	err = router.storageFor(r).CreateDivision(division)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -138,7 +138,7 @@ func (router *Router) handleCreateDepartment(w http.ResponseWriter, r *http.Requ
 		DivisionId: input.DivisionId,
 		Name:       input.Name,
 	}
-	err = router.storage.CreateDepartment(department)
+	err = router.storageFor(r).CreateDepartment(department)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -0,0 +1,367 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+const (
+	totpQrCodeSize              = 256
+	totpPeriod                  = 30 // Seconds
+	totpEnrollmentTokenDuration = 24 * time.Hour
+)
+
+// Returns the time step that the code was generated for, which is recorded to prevent the code from being reused
+// Like totp.Validate, codes of the previous & next steps are accepted to allow for clock drift
+func validateTotpStep(code string, secretKey string, t time.Time) (int64, bool) {
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Skew:      0,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+
+	for _, skew := range []int64{0, -1, 1} {
+		stepTime := t.Add(time.Duration(skew*totpPeriod) * time.Second)
+		valid, _ := totp.ValidateCustom(code, secretKey, stepTime, opts)
+		if valid {
+			return stepTime.Unix() / totpPeriod, true
+		}
+	}
+
+	return 0, false
+}
+
+// Validates the code against the secret key & records its time step, so that the code cannot be replayed by someone who has seen it
+func (router *Router) useTotp(r *http.Request, user storage.User, secretKey string, code string) (bool, error) {
+	step, valid := validateTotpStep(code, secretKey, time.Now())
+	if !valid {
+		return false, nil
+	}
+
+	used, err := router.storageFor(r).UseTotpStep(user.Id, user.TenantId, step)
+	if err != nil {
+		return false, err
+	}
+	if !used {
+		reqLogger := getRequestLogger(r)
+		reqLogger.Warn("TOTP-REPLAYED", "userId", user.Id, "tenantId", user.TenantId)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// The issuer is the name shown in the user's authenticator app, so the tenant's name is used to tell apart accounts of different tenants
+func (router *Router) generateTotpKey(r *http.Request, tenantId string, email string) (*otp.Key, error) {
+	tenants, _, err := router.storageFor(r).GetTenants(storage.Tenant{Id: tenantId}, storage.PageRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := tenantId
+	if len(tenants) != 0 && tenants[0].Name != "" {
+		issuer = tenants[0].Name
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: email,
+		SecretSize:  20,
+		Period:      30,
+	})
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+
+	return key, nil
+}
+
+// Validates the enrollment token that an admin issued when resetting the user's TOTP
+// Users who have never enrolled do not need a token, as they have not had a TOTP that an attacker could have reset
+// Invalid tokens count towards the lockout, like the other factors
+func (router *Router) validateTotpEnrollmentToken(r *http.Request, user storage.User, enrollmentToken string) error {
+	allowed, err := router.storageFor(r).IsTotpEnrollmentAllowed(user.Id, user.TenantId, hashPasswordResetToken(enrollmentToken))
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		err = router.recordFailedLogin(r, user.TenantId, user.Email, "INVALID-TOTP-ENROLLMENT-TOKEN")
+		if err != nil {
+			return err
+		}
+		return ErrInvalidTotpEnrollmentToken
+	}
+
+	return nil
+}
+
+// Starts (or restarts) TOTP enrollment for a user who has yet to enroll
+// Only the password is required, as the user does not have a TOTP yet. Users whose TOTP has been reset must also provide
+// the enrollment token issued with the reset
+func (router *Router) handleStartTotpEnrollment(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		TenantId        string
+		Email           string
+		Password        string
+		EnrollmentToken string
+	}
+
+	type responseBody struct {
+		OtpauthUri string `json:"otpauthUri"`
+		QrCode     string `json:"qrCode"` // Base64 encoded PNG of the otpauth URI
+	}
+
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+
+	type Input struct {
+		TenantId        string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Email           string `validate:"required,notBlank,max=300" name:"user email"`
+		Password        string `validate:"required" name:"password"`
+		EnrollmentToken string
+	}
+	input := Input{
+		TenantId:        reqBody.TenantId,
+		Email:           reqBody.Email,
+		Password:        reqBody.Password,
+		EnrollmentToken: reqBody.EnrollmentToken,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	user, passwordMatch, err := router.validatePassword(r, input.Email, input.TenantId, input.Password)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if !passwordMatch {
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+	if user.TotpSecretKey != "" {
+		sendToErrorHandlingMiddleware(ErrTotpAlreadyEnrolled, r)
+		return
+	}
+	err = router.validateTotpEnrollmentToken(r, user, input.EnrollmentToken)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	key, err := router.generateTotpKey(r, user.TenantId, user.Email)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	image, err := key.Image(totpQrCodeSize, totpQrCodeSize)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	var qrCode bytes.Buffer
+	err = png.Encode(&qrCode, image)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+
+	err = router.storageFor(r).SetPendingTotpSecretKey(user.Id, user.TenantId, key.Secret())
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("TOTP-ENROLLMENT-STARTED", "userId", user.Id, "tenantId", user.TenantId)
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(responseBody{
+		OtpauthUri: key.URL(),
+		QrCode:     base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	})
+}
+
+// Activates the pending secret once the user proves that their authenticator app generates valid codes with it
+// Recovery codes are returned so that the user can still log in if they lose their authenticator. They are only shown once
+func (router *Router) handleConfirmTotpEnrollment(w http.ResponseWriter, r *http.Request) {
+	type requestBody struct {
+		TenantId        string
+		Email           string
+		Password        string
+		Totp            string
+		EnrollmentToken string
+	}
+
+	type responseBody struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+
+	var reqBody requestBody
+	err := json.NewDecoder(r.Body).Decode(&reqBody)
+	if err != nil {
+		sendToErrorHandlingMiddleware(ErrInvalidJSON, r)
+		return
+	}
+
+	type Input struct {
+		TenantId        string `validate:"required,notBlank,uuid" name:"tenant id"`
+		Email           string `validate:"required,notBlank,max=300" name:"user email"`
+		Password        string `validate:"required" name:"password"`
+		Totp            string `validate:"required" name:"totp"`
+		EnrollmentToken string
+	}
+	input := Input{
+		TenantId:        reqBody.TenantId,
+		Email:           reqBody.Email,
+		Password:        reqBody.Password,
+		Totp:            reqBody.Totp,
+		EnrollmentToken: reqBody.EnrollmentToken,
+	}
+	translator := getTranslator(r)
+	err = validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	user, passwordMatch, err := router.validatePassword(r, input.Email, input.TenantId, input.Password)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if !passwordMatch {
+		sendToErrorHandlingMiddleware(ErrUserUnauthenticated, r)
+		return
+	}
+	if user.TotpSecretKey != "" {
+		sendToErrorHandlingMiddleware(ErrTotpAlreadyEnrolled, r)
+		return
+	}
+	err = router.validateTotpEnrollmentToken(r, user, input.EnrollmentToken)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	if user.TotpPendingSecretKey == "" {
+		sendToErrorHandlingMiddleware(ErrInvalidTotp, r)
+		return
+	}
+	valid, err := router.useTotp(r, user, user.TotpPendingSecretKey, input.Totp)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+	// Counted like a wrong TOTP at login, so that the codes cannot be brute-forced here instead
+	if !valid {
+		err = router.recordFailedLogin(r, user.TenantId, user.Email, "INVALID-OTP")
+		if err != nil {
+			sendToErrorHandlingMiddleware(err, r)
+			return
+		}
+		sendToErrorHandlingMiddleware(ErrInvalidTotp, r)
+		return
+	}
+
+	codes, recoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	err = router.storageFor(r).ConfirmTotpEnrollment(user.Id, user.TenantId, recoveryCodes)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("TOTP-ENROLLED", "userId", user.Id, "tenantId", user.TenantId)
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(responseBody{
+		RecoveryCodes: codes,
+	})
+}
+
+// Moves the user back to pending enrollment, e.g. after they have lost their authenticator
+// The user's sessions are revoked so that they must log in again after re-enrolling
+// The returned enrollment token must be given to the user, who needs it to enroll again. It is only shown once
+func (router *Router) handleResetTotp(w http.ResponseWriter, r *http.Request) {
+	type responseBody struct {
+		EnrollmentToken string    `json:"enrollmentToken"`
+		ExpiresAt       time.Time `json:"expiresAt"`
+	}
+
+	vars := mux.Vars(r)
+
+	type Input struct {
+		TenantId string `validate:"required,notBlank,uuid" name:"tenant id"`
+		UserId   string `validate:"required,notBlank,uuid" name:"user id"`
+	}
+	input := Input{
+		TenantId: vars["tenantId"],
+		UserId:   vars["userId"],
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	randomBytes := make([]byte, 32)
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		sendToErrorHandlingMiddleware(httperror.NewInternalServerError(err), r)
+		return
+	}
+	enrollmentToken := base64.RawURLEncoding.EncodeToString(randomBytes)
+	expiresAt := time.Now().Add(totpEnrollmentTokenDuration)
+
+	err = router.storageFor(r).ResetTotp(input.UserId, input.TenantId, hashPasswordResetToken(enrollmentToken), expiresAt)
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	revoked, err := router.storageFor(r).DeleteUserSessions(storage.UserSession{TenantId: input.TenantId, UserId: input.UserId})
+	if err != nil {
+		sendToErrorHandlingMiddleware(err, r)
+		return
+	}
+
+	reqLogger := getRequestLogger(r)
+	reqLogger.Info("TOTP-RESET", "userId", input.UserId, "tenantId", input.TenantId, "sessionsRevoked", revoked,
+		"resetBy", getAuthenticatedUser(r).Id)
+
+	w.Header().Add("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(responseBody{
+		EnrollmentToken: enrollmentToken,
+		ExpiresAt:       expiresAt,
+	})
+}
@@ -0,0 +1,323 @@
+package routes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+type totpEnrollmentRequestBody struct {
+	TenantId        string
+	Email           string
+	Password        string
+	Totp            string
+	EnrollmentToken string
+}
+
+type totpEnrollmentResponseBody struct {
+	OtpauthUri string `json:"otpauthUri"`
+	QrCode     string `json:"qrCode"`
+}
+
+func (s *IntegrationTestSuite) setTotpEnrollmentPending(userId string) {
+	_, err := s.dbRootConn.Exec("UPDATE user_account SET totp_secret_key = NULL WHERE id = $1", userId)
+	if err != nil {
+		log.Fatalf("Could not reset TOTP: %s", err)
+	}
+}
+
+// Allows a TOTP to be used again within the same test
+func (s *IntegrationTestSuite) clearTotpLastUsedStep(userId string) {
+	_, err := s.dbRootConn.Exec("UPDATE user_account SET totp_last_used_step = NULL WHERE id = $1", userId)
+	if err != nil {
+		log.Fatalf("Could not clear the last used TOTP step: %s", err)
+	}
+}
+
+func (s *IntegrationTestSuite) sendTotpEnrollmentRequest(method string, reqBody totpEnrollmentRequestBody) *httptest.ResponseRecorder {
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest(method, "/api/totp-enrollment", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	return w
+}
+
+// Starts enrollment for the default user & returns the pending key
+func (s *IntegrationTestSuite) startTotpEnrollment() *otp.Key {
+	w := s.sendTotpEnrollmentRequest("POST", totpEnrollmentRequestBody{
+		TenantId: s.defaultUser.TenantId,
+		Email:    s.defaultUser.Email,
+		Password: "jU%q837d!QP7",
+	})
+	s.expectHttpStatus(w, 200)
+
+	var resBody totpEnrollmentResponseBody
+	err := json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err)
+
+	key, err := otp.NewKeyFromURL(resBody.OtpauthUri)
+	s.Equal(nil, err, "A valid otpauth URI should be returned")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	qrCode, err := base64.StdEncoding.DecodeString(resBody.QrCode)
+	s.Equal(nil, err)
+	_, err = png.Decode(bytes.NewReader(qrCode))
+	s.Equal(nil, err, "The QR code should be a PNG")
+
+	return key
+}
+
+func (s *IntegrationTestSuite) TestLoginShouldRequireTotpEnrollment() {
+	s.setTotpEnrollmentPending(s.defaultUser.Id)
+
+	reqBody := totpEnrollmentRequestBody{
+		TenantId: s.defaultUser.TenantId,
+		Email:    s.defaultUser.Email,
+		Password: "jU%q837d!QP7",
+		Totp:     "123456",
+	}
+	bodyBuf := new(bytes.Buffer)
+	json.NewEncoder(bodyBuf).Encode(reqBody)
+
+	r, err := http.NewRequest("POST", "/api/session", bodyBuf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 403)
+	s.expectErrorCode(w, "TOTP-ENROLLMENT-REQUIRED")
+}
+
+func (s *IntegrationTestSuite) TestTotpEnrollment() {
+	s.setTotpEnrollmentPending(s.defaultUser.Id)
+
+	key := s.startTotpEnrollment()
+	s.Equal("HRIS Enterprises", key.Issuer(), "The tenant's name should be used as the issuer")
+	s.Equal(s.defaultUser.Email, key.AccountName())
+
+	// The secret is only activated after it is confirmed
+	s.expectSelectQueryToReturnOneRow(
+		"user_account",
+		map[string]any{
+			"id":                      s.defaultUser.Id,
+			"totp_secret_key":         "",
+			"totp_pending_secret_key": key.Secret(),
+		},
+	)
+
+	code, _ := totp.GenerateCode(key.Secret(), time.Now().UTC())
+	s.logOutput.Reset()
+	w := s.sendTotpEnrollmentRequest("PUT", totpEnrollmentRequestBody{
+		TenantId: s.defaultUser.TenantId,
+		Email:    s.defaultUser.Email,
+		Password: "jU%q837d!QP7",
+		Totp:     code,
+	})
+	s.expectHttpStatus(w, 200)
+
+	var resBody struct {
+		RecoveryCodes []string `json:"recoveryCodes"`
+	}
+	err := json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err)
+	s.Equal(recoveryCodeCount, len(resBody.RecoveryCodes), "Recovery codes should be returned")
+
+	var storedCodes int
+	err = s.dbRootConn.QueryRow("SELECT count(*) FROM recovery_code WHERE user_account_id = $1", s.defaultUser.Id).Scan(&storedCodes)
+	s.Equal(nil, err)
+	s.Equal(recoveryCodeCount, storedCodes, "Hashes of the recovery codes should be stored")
+
+	s.expectSelectQueryToReturnOneRow(
+		"user_account",
+		map[string]any{
+			"id":                      s.defaultUser.Id,
+			"totp_secret_key":         key.Secret(),
+			"totp_pending_secret_key": "",
+		},
+	)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"TOTP-ENROLLED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+
+	// Users cannot re-enroll without an admin resetting their TOTP
+	w = s.sendTotpEnrollmentRequest("POST", totpEnrollmentRequestBody{
+		TenantId: s.defaultUser.TenantId,
+		Email:    s.defaultUser.Email,
+		Password: "jU%q837d!QP7",
+	})
+	s.expectHttpStatus(w, 409)
+	s.expectErrorCode(w, "TOTP-ALREADY-ENROLLED-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestTotpEnrollmentInvalidInput() {
+	s.setTotpEnrollmentPending(s.defaultUser.Id)
+	key := s.startTotpEnrollment()
+	code, _ := totp.GenerateCode(key.Secret(), time.Now().UTC())
+
+	tests := []struct {
+		name       string
+		input      totpEnrollmentRequestBody
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			"Should fail because the password is wrong",
+			totpEnrollmentRequestBody{
+				TenantId: s.defaultUser.TenantId,
+				Email:    s.defaultUser.Email,
+				Password: "abcd1234!@#$%",
+				Totp:     code,
+			},
+			401,
+			"USER-UNAUTHENTICATED",
+		},
+		{
+			"Should fail because the totp was not generated with the pending secret",
+			totpEnrollmentRequestBody{
+				TenantId: s.defaultUser.TenantId,
+				Email:    s.defaultUser.Email,
+				Password: "jU%q837d!QP7",
+				Totp:     "123456",
+			},
+			400,
+			"INVALID-TOTP-ERROR",
+		},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			w := s.sendTotpEnrollmentRequest("PUT", test.input)
+			s.expectHttpStatus(w, test.wantStatus)
+			s.expectErrorCode(w, test.wantCode)
+
+			s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultUser.Id, "totp_secret_key": ""})
+		})
+	}
+}
+
+// Resets the supervisor's TOTP as the default user (an admin) & returns the enrollment token
+func (s *IntegrationTestSuite) resetSupervisorTotp() string {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/totp", s.defaultSupervisor.TenantId, s.defaultSupervisor.Id)
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	var resBody struct {
+		EnrollmentToken string    `json:"enrollmentToken"`
+		ExpiresAt       time.Time `json:"expiresAt"`
+	}
+	err = json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err)
+	s.NotEqual("", resBody.EnrollmentToken, "An enrollment token should be returned")
+	s.WithinDuration(time.Now().Add(totpEnrollmentTokenDuration), resBody.ExpiresAt, time.Minute)
+
+	return resBody.EnrollmentToken
+}
+
+func (s *IntegrationTestSuite) TestResetTotp() {
+	enrollmentToken := s.resetSupervisorTotp()
+	s.expectSelectQueryToReturnOneRow(
+		"user_account",
+		map[string]any{
+			"id":                         s.defaultSupervisor.Id,
+			"totp_secret_key":            "",
+			"totp_enrollment_token_hash": hashPasswordResetToken(enrollmentToken),
+		},
+	)
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"TOTP-RESET"`, fmt.Sprintf(`"resetBy":"%s"`, s.defaultUser.Id))
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
+}
+
+func (s *IntegrationTestSuite) TestResetTotpShouldValidateUserExistence() {
+	path := fmt.Sprintf("/api/tenants/%s/users/%s/totp", s.defaultUser.TenantId, "a9f998c6-ba2e-4359-b308-e56404534974")
+	r, err := http.NewRequest("DELETE", path, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+
+	s.expectHttpStatus(w, 404)
+	s.expectErrorCode(w, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestTotpEnrollmentAfterResetShouldRequireEnrollmentToken() {
+	enrollmentToken := s.resetSupervisorTotp()
+	reqBody := totpEnrollmentRequestBody{
+		TenantId: s.defaultSupervisor.TenantId,
+		Email:    s.defaultSupervisor.Email,
+		Password: "jU%q837d!QP7",
+	}
+
+	// The password alone is not sufficient, e.g. for someone who has learnt it & wants to enroll their own authenticator
+	for _, token := range []string{"", "wrong-token"} {
+		reqBody.EnrollmentToken = token
+		w := s.sendTotpEnrollmentRequest("POST", reqBody)
+		s.expectHttpStatus(w, 400)
+		s.expectErrorCode(w, "INVALID-TOTP-ENROLLMENT-TOKEN-ERROR")
+		s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultSupervisor.Id, "totp_pending_secret_key": ""})
+	}
+	// Invalid tokens count towards the lockout
+	s.expectSelectQueryToReturnOneRow("login_attempt", map[string]any{"email": s.defaultSupervisor.Email, "failed_count": 2})
+
+	reqBody.EnrollmentToken = enrollmentToken
+	w := s.sendTotpEnrollmentRequest("POST", reqBody)
+	s.expectHttpStatus(w, 200)
+	var resBody totpEnrollmentResponseBody
+	err := json.NewDecoder(w.Result().Body).Decode(&resBody)
+	s.Equal(nil, err)
+	key, err := otp.NewKeyFromURL(resBody.OtpauthUri)
+	s.Require().Equal(nil, err)
+
+	// Confirming also requires the token
+	reqBody.Totp, _ = totp.GenerateCode(key.Secret(), time.Now().UTC())
+	reqBody.EnrollmentToken = ""
+	w = s.sendTotpEnrollmentRequest("PUT", reqBody)
+	s.expectHttpStatus(w, 400)
+	s.expectErrorCode(w, "INVALID-TOTP-ENROLLMENT-TOKEN-ERROR")
+
+	reqBody.EnrollmentToken = enrollmentToken
+	w = s.sendTotpEnrollmentRequest("PUT", reqBody)
+	s.expectHttpStatus(w, 200)
+
+	// The token is used up by the enrollment
+	s.expectSelectQueryToReturnOneRow(
+		"user_account",
+		map[string]any{"id": s.defaultSupervisor.Id, "totp_secret_key": key.Secret(), "totp_enrollment_token_hash": ""},
+	)
+}
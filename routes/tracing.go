@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+)
+
+// The global tracer provider is used, so spans are only recorded once main has set it up. Otherwise, they are no-ops
+const tracerName = "multi-tenant-HR-information-system-backend/routes"
+
+func getTracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Continues the caller's trace if the request has a traceparent header (W3C Trace Context), otherwise starts a new trace
+// Spans are named after the route template (e.g. POST /api/tenants/{tenantId}), so that they can be grouped by endpoint
+func startServerSpan(r *http.Request, route string) (context.Context, trace.Span) {
+	propagator := propagation.TraceContext{}
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	name := r.Method
+	if route != "" {
+		name = r.Method + " " + route
+	}
+
+	return getTracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+			attribute.String("client.address", r.RemoteAddr),
+		),
+	)
+}
+
+// The status is only known once the response is written. Server errors mark the span as failed, whatever the route
+func endServerSpan(span trace.Span, status int) {
+	if status == 0 {
+		status = http.StatusOK // The handler wrote the body without calling WriteHeader
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
+
+// Client errors (e.g. a resource that does not exist) are expected, so only other errors mark the span as failed
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		var httpErr *httperror.Error
+		if !errors.As(err, &httpErr) || httpErr.Status >= http.StatusInternalServerError {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
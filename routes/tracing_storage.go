@@ -0,0 +1,432 @@
+package routes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Wraps every storage call of a request in a child span of the request's span
+// The storage interface does not take a context, so the wrapper is bound to the request instead
+type tracedStorage struct {
+	ctx  context.Context
+	next storage.Storage
+}
+
+// Returns the storage to use while handling the request, so that its calls are traced
+func (router *Router) storageFor(r *http.Request) storage.Storage {
+	return tracedStorage{ctx: r.Context(), next: router.storage}
+}
+
+func (traced tracedStorage) startSpan(method string) trace.Span {
+	_, span := getTracer().Start(traced.ctx, "storage."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "postgresql")),
+	)
+	return span
+}
+
+func (traced tracedStorage) Ping(ctx context.Context) error {
+	return traced.next.Ping(ctx)
+}
+
+func (traced tracedStorage) CreateTenant(tenant storage.Tenant) error {
+	span := traced.startSpan("CreateTenant")
+	err := traced.next.CreateTenant(tenant)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetTenants(filter storage.Tenant, page storage.PageRequest) ([]storage.Tenant, string, error) {
+	span := traced.startSpan("GetTenants")
+	result, nextCursor, err := traced.next.GetTenants(filter, page)
+	endSpan(span, err)
+	return result, nextCursor, err
+}
+
+func (traced tracedStorage) CreateDivision(division storage.Division) error {
+	span := traced.startSpan("CreateDivision")
+	err := traced.next.CreateDivision(division)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreateDepartment(department storage.Department) error {
+	span := traced.startSpan("CreateDepartment")
+	err := traced.next.CreateDepartment(department)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreateUser(user storage.User, policies storage.Policies) error {
+	span := traced.startSpan("CreateUser")
+	err := traced.next.CreateUser(user, policies)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetUsers(userFilter storage.User, page storage.PageRequest) ([]storage.User, string, error) {
+	span := traced.startSpan("GetUsers")
+	result, nextCursor, err := traced.next.GetUsers(userFilter, page)
+	endSpan(span, err)
+	return result, nextCursor, err
+}
+
+func (traced tracedStorage) GetUserSupervisors(userId string, tenantId string) ([]string, error) {
+	span := traced.startSpan("GetUserSupervisors")
+	result, err := traced.next.GetUserSupervisors(userId, tenantId)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) ChangePassword(userId string, tenantId string, passwordHash string) error {
+	span := traced.startSpan("ChangePassword")
+	err := traced.next.ChangePassword(userId, tenantId, passwordHash)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreatePasswordResetToken(token storage.PasswordResetToken) error {
+	span := traced.startSpan("CreatePasswordResetToken")
+	err := traced.next.CreatePasswordResetToken(token)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) IsPasswordResetTokenValid(token storage.PasswordResetToken) (bool, error) {
+	span := traced.startSpan("IsPasswordResetTokenValid")
+	valid, err := traced.next.IsPasswordResetTokenValid(token)
+	endSpan(span, err)
+	return valid, err
+}
+
+func (traced tracedStorage) RedeemPasswordResetToken(token storage.PasswordResetToken, passwordHash string) error {
+	span := traced.startSpan("RedeemPasswordResetToken")
+	err := traced.next.RedeemPasswordResetToken(token, passwordHash)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) SetPendingTotpSecretKey(userId string, tenantId string, totpSecretKey string) error {
+	span := traced.startSpan("SetPendingTotpSecretKey")
+	err := traced.next.SetPendingTotpSecretKey(userId, tenantId, totpSecretKey)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) ConfirmTotpEnrollment(userId string, tenantId string, recoveryCodes []storage.RecoveryCode) error {
+	span := traced.startSpan("ConfirmTotpEnrollment")
+	err := traced.next.ConfirmTotpEnrollment(userId, tenantId, recoveryCodes)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) IsTotpEnrollmentAllowed(userId string, tenantId string, enrollmentTokenHash string) (bool, error) {
+	span := traced.startSpan("IsTotpEnrollmentAllowed")
+	allowed, err := traced.next.IsTotpEnrollmentAllowed(userId, tenantId, enrollmentTokenHash)
+	endSpan(span, err)
+	return allowed, err
+}
+
+func (traced tracedStorage) ResetTotp(userId string, tenantId string, enrollmentTokenHash string, enrollmentTokenExpiresAt time.Time) error {
+	span := traced.startSpan("ResetTotp")
+	err := traced.next.ResetTotp(userId, tenantId, enrollmentTokenHash, enrollmentTokenExpiresAt)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetRecoveryCodes(filter storage.RecoveryCode) ([]storage.RecoveryCode, error) {
+	span := traced.startSpan("GetRecoveryCodes")
+	result, err := traced.next.GetRecoveryCodes(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) UseRecoveryCode(recoveryCode storage.RecoveryCode) error {
+	span := traced.startSpan("UseRecoveryCode")
+	err := traced.next.UseRecoveryCode(recoveryCode)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) UseTotpStep(userId string, tenantId string, step int64) (bool, error) {
+	span := traced.startSpan("UseTotpStep")
+	result, err := traced.next.UseTotpStep(userId, tenantId, step)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) GetLoginAttempt(tenantId string, email string) (storage.LoginAttempt, error) {
+	span := traced.startSpan("GetLoginAttempt")
+	result, err := traced.next.GetLoginAttempt(tenantId, email)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) RecordFailedLogin(tenantId string, email string) (storage.LoginAttempt, error) {
+	span := traced.startSpan("RecordFailedLogin")
+	result, err := traced.next.RecordFailedLogin(tenantId, email)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) LockLogin(tenantId string, email string, lockedUntil time.Time) error {
+	span := traced.startSpan("LockLogin")
+	err := traced.next.LockLogin(tenantId, email, lockedUntil)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) DeleteLoginAttempt(tenantId string, email string) error {
+	span := traced.startSpan("DeleteLoginAttempt")
+	err := traced.next.DeleteLoginAttempt(tenantId, email)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) SetOidcConfiguration(config storage.OidcConfiguration) error {
+	span := traced.startSpan("SetOidcConfiguration")
+	err := traced.next.SetOidcConfiguration(config)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetOidcConfiguration(tenantId string) (storage.OidcConfiguration, error) {
+	span := traced.startSpan("GetOidcConfiguration")
+	result, err := traced.next.GetOidcConfiguration(tenantId)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) SetSamlConfiguration(config storage.SamlConfiguration) error {
+	span := traced.startSpan("SetSamlConfiguration")
+	err := traced.next.SetSamlConfiguration(config)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetSamlConfiguration(tenantId string) (storage.SamlConfiguration, error) {
+	span := traced.startSpan("GetSamlConfiguration")
+	result, err := traced.next.GetSamlConfiguration(tenantId)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) CreateSamlRequest(samlRequest storage.SamlRequest) error {
+	span := traced.startSpan("CreateSamlRequest")
+	err := traced.next.CreateSamlRequest(samlRequest)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) UseSamlRequest(samlRequest storage.SamlRequest) error {
+	span := traced.startSpan("UseSamlRequest")
+	err := traced.next.UseSamlRequest(samlRequest)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreateServiceAccount(serviceAccount storage.ServiceAccount) error {
+	span := traced.startSpan("CreateServiceAccount")
+	err := traced.next.CreateServiceAccount(serviceAccount)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreateApiToken(apiToken storage.ApiToken) error {
+	span := traced.startSpan("CreateApiToken")
+	err := traced.next.CreateApiToken(apiToken)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetApiTokens(filter storage.ApiToken) ([]storage.ApiToken, error) {
+	span := traced.startSpan("GetApiTokens")
+	result, err := traced.next.GetApiTokens(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) DeleteApiTokens(filter storage.ApiToken) (int64, error) {
+	span := traced.startSpan("DeleteApiTokens")
+	result, err := traced.next.DeleteApiTokens(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) GetUserSessions(filter storage.UserSession) ([]storage.UserSession, error) {
+	span := traced.startSpan("GetUserSessions")
+	result, err := traced.next.GetUserSessions(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) DeleteUserSessions(filter storage.UserSession) (int64, error) {
+	span := traced.startSpan("DeleteUserSessions")
+	result, err := traced.next.DeleteUserSessions(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) CreatePosition(position storage.Position) error {
+	span := traced.startSpan("CreatePosition")
+	err := traced.next.CreatePosition(position)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreatePositionAssignment(positionAssignment storage.PositionAssignment) error {
+	span := traced.startSpan("CreatePositionAssignment")
+	err := traced.next.CreatePositionAssignment(positionAssignment)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetUserPositions(userId string, filter storage.UserPosition) ([]storage.UserPosition, error) {
+	span := traced.startSpan("GetUserPositions")
+	result, err := traced.next.GetUserPositions(userId, filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) CreatePolicies(policies storage.Policies) error {
+	span := traced.startSpan("CreatePolicies")
+	err := traced.next.CreatePolicies(policies)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreateRoleAssignment(roleAssignment storage.RoleAssignment) error {
+	span := traced.startSpan("CreateRoleAssignment")
+	err := traced.next.CreateRoleAssignment(roleAssignment)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetPolicies(filter storage.Policies) ([]storage.Policies, error) {
+	span := traced.startSpan("GetPolicies")
+	result, err := traced.next.GetPolicies(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) ReplacePolicies(policies storage.Policies) error {
+	span := traced.startSpan("ReplacePolicies")
+	err := traced.next.ReplacePolicies(policies)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) DeletePolicies(filter storage.Policies) (int64, error) {
+	span := traced.startSpan("DeletePolicies")
+	result, err := traced.next.DeletePolicies(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) GetRoleAssignments(filter storage.RoleAssignment) ([]storage.RoleAssignment, error) {
+	span := traced.startSpan("GetRoleAssignments")
+	result, err := traced.next.GetRoleAssignments(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) ReplaceRoleAssignments(userId string, tenantId string, roles []string) error {
+	span := traced.startSpan("ReplaceRoleAssignments")
+	err := traced.next.ReplaceRoleAssignments(userId, tenantId, roles)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) DeleteRoleAssignments(filter storage.RoleAssignment) (int64, error) {
+	span := traced.startSpan("DeleteRoleAssignments")
+	result, err := traced.next.DeleteRoleAssignments(filter)
+	endSpan(span, err)
+	return result, err
+}
+
+func (traced tracedStorage) CreateJobRequisition(jobRequisition storage.JobRequisition, hrApproverPolicies storage.Policies) error {
+	span := traced.startSpan("CreateJobRequisition")
+	err := traced.next.CreateJobRequisition(jobRequisition, hrApproverPolicies)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetJobRequisitions(filter storage.JobRequisition, page storage.PageRequest) ([]storage.JobRequisition, string, error) {
+	span := traced.startSpan("GetJobRequisitions")
+	result, nextCursor, err := traced.next.GetJobRequisitions(filter, page)
+	endSpan(span, err)
+	return result, nextCursor, err
+}
+
+func (traced tracedStorage) UpdateJobRequisition(newValues storage.JobRequisition, filter storage.JobRequisition) error {
+	span := traced.startSpan("UpdateJobRequisition")
+	err := traced.next.UpdateJobRequisition(newValues, filter)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) HrApproveJobRequisition(jobRequisitionId string, tenantId string, hrApprover string, recruiter string, recruiterPolicies storage.Policies) error {
+	span := traced.startSpan("HrApproveJobRequisition")
+	err := traced.next.HrApproveJobRequisition(jobRequisitionId, tenantId, hrApprover, recruiter, recruiterPolicies)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) CreateJobApplication(jobApplication storage.JobApplication) error {
+	span := traced.startSpan("CreateJobApplication")
+	err := traced.next.CreateJobApplication(jobApplication)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) GetJobApplications(filter storage.JobApplication, page storage.PageRequest) ([]storage.JobApplication, string, error) {
+	span := traced.startSpan("GetJobApplications")
+	result, nextCursor, err := traced.next.GetJobApplications(filter, page)
+	endSpan(span, err)
+	return result, nextCursor, err
+}
+
+func (traced tracedStorage) UpdateJobApplication(newValues storage.JobApplication, filter storage.JobApplication) error {
+	span := traced.startSpan("UpdateJobApplication")
+	err := traced.next.UpdateJobApplication(newValues, filter)
+	endSpan(span, err)
+	return err
+}
+
+func (traced tracedStorage) OnboardNewHire(jobApplication storage.JobApplication, newUser storage.User, newUserPolicies storage.Policies) error {
+	span := traced.startSpan("OnboardNewHire")
+	err := traced.next.OnboardNewHire(jobApplication, newUser, newUserPolicies)
+	endSpan(span, err)
+	return err
+}
+
+// Wraps every file storage call of a request in a child span of the request's span
+type tracedFileStorage struct {
+	ctx  context.Context
+	next storage.FileStorage
+}
+
+func (router *Router) fileStorageFor(r *http.Request) storage.FileStorage {
+	return tracedFileStorage{ctx: r.Context(), next: router.fileStorage}
+}
+
+func (traced tracedFileStorage) Ping(ctx context.Context) error {
+	return traced.next.Ping(ctx)
+}
+
+func (traced tracedFileStorage) UploadResume(file io.Reader, jobApplicationId string, firstName string, lastName string, fileExt string) (string, error) {
+	_, span := getTracer().Start(traced.ctx, "fileStorage.UploadResume",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("job_application.id", jobApplicationId)),
+	)
+	url, err := traced.next.UploadResume(file, jobApplicationId, firstName, lastName, fileExt)
+	endSpan(span, err)
+	return url, err
+}
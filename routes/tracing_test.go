@@ -0,0 +1,176 @@
+package routes
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Records spans in memory until the returned function is called
+func useSpanRecorder() (*tracetest.SpanRecorder, func()) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder, func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{"Should not mark the span as failed because there is no error", nil, codes.Unset},
+		{"Should not mark the span as failed because of a client error", ErrUserUnauthorised, codes.Unset},
+		{"Should mark the span as failed because of an internal error", errors.New("connection refused"), codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, reset := useSpanRecorder()
+			defer reset()
+
+			_, span := getTracer().Start(httptest.NewRequest("GET", "/", nil).Context(), "test")
+			endSpan(span, test.err)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got %d", len(spans))
+			}
+			if got := spans[0].Status().Code; got != test.wantStatus {
+				t.Errorf("status = %v, want %v", got, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestEndServerSpan(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		wantStatusCode int
+		wantStatus     codes.Code
+	}{
+		{"Should default to 200 because the handler did not write a status", 0, http.StatusOK, codes.Unset},
+		{"Should not mark the span as failed because of a client error", http.StatusNotFound, http.StatusNotFound, codes.Unset},
+		{"Should mark the span as failed because of a server error", http.StatusServiceUnavailable, http.StatusServiceUnavailable, codes.Error},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder, reset := useSpanRecorder()
+			defer reset()
+
+			_, span := startServerSpan(httptest.NewRequest("GET", "/readyz", nil), "/readyz")
+			endServerSpan(span, test.status)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 ended span, got %d", len(spans))
+			}
+			if got := spans[0].Status().Code; got != test.wantStatus {
+				t.Errorf("status = %v, want %v", got, test.wantStatus)
+			}
+			if !slices.Contains(spans[0].Attributes(), attribute.Int("http.response.status_code", test.wantStatusCode)) {
+				t.Errorf("http.response.status_code should be %d, got attributes %v", test.wantStatusCode, spans[0].Attributes())
+			}
+		})
+	}
+}
+
+func (s *IntegrationTestSuite) TestTracing() {
+	recorder, reset := useSpanRecorder()
+	defer reset()
+
+	r, err := http.NewRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.addSessionCookieToRequest(r, s.defaultUser.Id, s.defaultUser.TenantId, s.defaultUser.Email)
+
+	s.logOutput.Reset()
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	spansByName := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spansByName[span.Name()] = span
+	}
+
+	// The server span is named after the route template, so that it does not contain ids
+	serverSpan, ok := spansByName["GET /api/tenants/{tenantId}/users/{userId}/sessions"]
+	s.Require().True(ok, "server span should have been recorded")
+	s.Contains(serverSpan.Attributes(), attribute.Int("http.response.status_code", 200))
+	traceId := serverSpan.SpanContext().TraceID()
+
+	for _, name := range []string{"casbin.Enforce", "storage.GetUserSessions"} {
+		span, ok := spansByName[name]
+		if s.True(ok, "%s span should have been recorded", name) {
+			s.Equal(traceId, span.SpanContext().TraceID())
+			s.Equal(serverSpan.SpanContext().SpanID(), span.Parent().SpanID())
+		}
+	}
+
+	reader := bufio.NewReader(s.logOutput)
+	s.expectNextLogToContain(reader, `"msg":"USER-AUTHORISED"`, fmt.Sprintf(`"traceId":"%s"`, traceId))
+}
+
+// API tokens are looked up before the request is authorised, which should be part of the request's trace too
+func (s *IntegrationTestSuite) TestTracingShouldIncludeApiTokenLookup() {
+	token := s.createTestApiToken("users/"+s.defaultUser.Id, map[string]any{"Name": "BI export", "Scopes": []string{"read"}})
+
+	recorder, reset := useSpanRecorder()
+	defer reset()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, s.newApiTokenRequest("GET", fmt.Sprintf("/api/tenants/%s/users/%s/sessions", s.defaultTenant.Id, s.defaultUser.Id), token))
+	s.expectHttpStatus(w, 200)
+
+	spansByName := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spansByName[span.Name()] = append(spansByName[span.Name()], span)
+	}
+
+	serverSpans := spansByName["GET /api/tenants/{tenantId}/users/{userId}/sessions"]
+	s.Require().Len(serverSpans, 1, "server span should have been recorded")
+	apiTokenSpans := spansByName["storage.GetApiTokens"]
+	s.Require().Len(apiTokenSpans, 1, "the API token lookup should have been traced once")
+	s.Equal(serverSpans[0].SpanContext().SpanID(), apiTokenSpans[0].Parent().SpanID())
+}
+
+// A caller that sends a traceparent header should see the request in its own trace
+func (s *IntegrationTestSuite) TestTracingShouldContinueCallerTrace() {
+	recorder, reset := useSpanRecorder()
+	defer reset()
+
+	callerTraceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	r, err := http.NewRequest("GET", "/healthz", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.Header.Set("traceparent", fmt.Sprintf("00-%s-00f067aa0ba902b7-01", callerTraceId))
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	s.expectHttpStatus(w, 200)
+
+	spans := recorder.Ended()
+	s.Require().Len(spans, 1)
+	s.Equal("GET /healthz", spans[0].Name())
+	s.Equal(callerTraceId, spans[0].SpanContext().TraceID().String())
+	s.Equal("00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
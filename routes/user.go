@@ -11,7 +11,6 @@ import (
 
 	"github.com/alexedwards/argon2id"
 	"github.com/gorilla/mux"
-	"github.com/pquerna/otp/totp"
 
 	"multi-tenant-HR-information-system-backend/httperror"
 	"multi-tenant-HR-information-system-backend/storage"
@@ -76,24 +75,47 @@ func generateRandomPassword(length int, minLower int, minUpper int, minNumber in
 	return string(runeString)
 }
 
-func generateDefaultCredentials(email string) (password string, hashedPassword string, totp_secret_key string, err error) {
+func generateDefaultPassword() (password string, hashedPassword string, err error) {
 	password = generateRandomPassword(12, 2, 2, 2, 2)
 	hashedPassword, err = argon2id.CreateHash(password, argon2id.DefaultParams)
 	if err != nil {
-		return "", "", "" , httperror.NewInternalServerError(err)
+		return "", "", httperror.NewInternalServerError(err)
 	}
 
-	key, err := totp.Generate(totp.GenerateOpts{
-		Issuer:      "HRIS.com",
-		AccountName: email,
-		SecretSize:  20,
-		Period:      30,
-	})
+	return password, hashedPassword, nil
+}
+
+// Creates a user with a generated password & returns the password
+// Users created by admins & users provisioned on their first SSO login are both created here, so they get the same default rights
+// from the employee policy template
+func (router *Router) provisionUser(r *http.Request, userId string, tenantId string, email string) (password string, err error) {
+	password, passwordHash, err := generateDefaultPassword()
 	if err != nil {
-		return "", "", "" , httperror.NewInternalServerError(err)
-	}	
+		return "", err
+	}
 
-	return password, hashedPassword, key.Secret(), nil
+	// The generated password is shared with the user by an admin, so it must be changed before the user can log in
+	user := storage.User{
+		Id:                 userId,
+		TenantId:           tenantId,
+		Email:              email,
+		Password:           passwordHash,
+		MustChangePassword: true,
+	}
+	err = router.storageFor(r).CreateUser(user, employeePolicyTemplate.instantiate(user.Id, user.TenantId))
+	if err != nil {
+		return "", err
+	}
+
+	requestLogger := getRequestLogger(r)
+	requestLogger.Info("USER-CREATED", "userId", user.Id, "tenantId", user.TenantId)
+
+	err = router.reloadAuthorizationPolicy(r)
+	if err != nil {
+		return "", err
+	}
+
+	return password, nil
 }
 
 func (router *Router) handleCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -101,9 +123,9 @@ func (router *Router) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		Email string
 	}
 
+	// The user enrolls in TOTP themselves, so only the generated password is returned
 	type responseBody struct {
-		Password      string `json:"password"`
-		TotpSecretKey string `json:"totpSecretKey"`
+		Password string `json:"password"`
 	}
 
 	var body requestBody
@@ -132,36 +154,17 @@ func (router *Router) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create default password + totpsecretkey
-	password, passwordHash, totp_secret_key, err := generateDefaultCredentials(input.Email)
-	if err != nil {
-		sendToErrorHandlingMiddleware(err, r)
-		return
-	}
-
-	// Make DB query
-	user := storage.User{
-		Id:            input.Id,
-		TenantId:      input.TenantId,
-		Email:         input.Email,
-		Password:      passwordHash,
-		TotpSecretKey: totp_secret_key,
-	}
-	err = router.storage.CreateUser(user)
+	password, err := router.provisionUser(r, input.Id, input.TenantId, input.Email)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
 	}
 
-	requestLogger := getRequestLogger(r)
-	requestLogger.Info("USER-CREATED", "userId", user.Id, "tenantId", user.TenantId)
-
 	w.WriteHeader(http.StatusCreated)
 	w.Header().Add("content-type", "application/json")
 
 	resBody := responseBody{
-		Password:      password,
-		TotpSecretKey: totp_secret_key,
+		Password: password,
 	}
 	json.NewEncoder(w).Encode(resBody)
 }
@@ -211,7 +214,7 @@ func (router *Router) handleCreatePosition(w http.ResponseWriter, r *http.Reques
 		DepartmentId:          input.DepartmentId,
 		SupervisorPositionIds: input.SupervisorPositionIds,
 	}
-	err = router.storage.CreatePosition(userPosition)
+	err = router.storageFor(r).CreatePosition(userPosition)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -265,7 +268,7 @@ func (router *Router) handleCreatePositionAssignment(w http.ResponseWriter, r *h
 		StartDate:  input.StartDate,
 		EndDate:    input.EndDate,
 	}
-	err = router.storage.CreatePositionAssignment(userPositionAssignment)
+	err = router.storageFor(r).CreatePositionAssignment(userPositionAssignment)
 	if err != nil {
 		sendToErrorHandlingMiddleware(err, r)
 		return
@@ -45,15 +45,33 @@ func (s *IntegrationTestSuite) TestCreateUser() {
 	s.expectSelectQueryToReturnOneRow(
 		"user_account",
 		map[string]any{
-			"id":        wantUser.Id,
-			"tenant_id": wantUser.TenantId,
-			"email":     wantUser.Email,
+			"id":                   wantUser.Id,
+			"tenant_id":            wantUser.TenantId,
+			"email":                wantUser.Email,
+			"must_change_password": true,
 		},
 	)
 
+	// The user is given the employee policies, which apply without a restart
+	for _, resource := range employeePolicyTemplate.instantiate(wantUser.Id, wantUser.TenantId).Resources {
+		s.expectSelectQueryToReturnOneRow(
+			"casbin_rule",
+			map[string]any{"Ptype": "p", "V0": wantUser.Id, "V1": wantUser.TenantId, "V2": resource.Path, "V3": resource.Method},
+		)
+	}
+	requestorPath := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor", wantUser.TenantId, wantUser.Id)
+	authorized, err := s.router.authEnforcer.Enforce(wantUser.Id, wantUser.TenantId, requestorPath, "GET")
+	s.Equal(nil, err)
+	s.True(authorized, "The new user should be able to list their job requisitions")
+	otherRequestorPath := fmt.Sprintf("/api/tenants/%s/users/%s/job-requisitions/role-requestor", wantUser.TenantId, s.defaultUser.Id)
+	authorized, err = s.router.authEnforcer.Enforce(wantUser.Id, wantUser.TenantId, otherRequestorPath, "GET")
+	s.Equal(nil, err)
+	s.False(authorized, "The new user should not be able to list another user's job requisitions")
+
 	reader := bufio.NewReader(s.logOutput)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-AUTHORISED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"USER-CREATED"`)
+	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"AUTHORIZATION-ENFORCER-RELOADED"`)
 	s.expectNextLogToContain(reader, `"level":"INFO"`, `"msg":"REQUEST-COMPLETED"`)
 }
 
@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+)
+
+// The most values that a message has, e.g. the entity & columns of a unique violation
+const maxErrorMessageArgs = 2
+
+// Translations of httperror messages by their Code. {0}, {1}, ... are the error's Args
+// English is not listed, as the messages are written in English. Codes without a translation are sent in English
+var errorMessageTranslations = map[string]map[string]string{
+	"zh": {
+		"INTERNAL-SERVER-ERROR":                     "出现错误。追踪ID：{0}",
+		"INPUT-VALIDATION-ERROR":                    "您的输入有一个或多个错误：",
+		"RESOURCE-NOT-FOUND-ERROR":                  "{0}不存在",
+		"UNIQUE-VIOLATION-ERROR":                    "具有相同{1}的{0}已存在",
+		"INVALID-FOREIGN-KEY-ERROR":                 "提供的{0}无效",
+		"INVALID-SORT-FIELD-ERROR":                  "结果无法按{0}排序，只能按{1}排序",
+		"INVALID-SORT-ORDER-ERROR":                  "排序顺序必须为ASC或DESC",
+		"INVALID-CURSOR-ERROR":                      "提供的游标无效",
+		"INVALID-JSON-ERROR":                        "请求正文中的JSON无效",
+		"FILE-TOO-BIG-ERROR":                        "上传的文件超过了大小限制",
+		"USER-UNAUTHENTICATED":                      "用户未经身份验证",
+		"USER-UNAUTHORISED":                         "用户无权执行此操作",
+		"CROSS-ORIGIN-REQUEST-ERROR":                "不允许跨域请求",
+		"PASSWORD-CHANGE-REQUIRED":                  "您必须先更改密码才能登录",
+		"INVALID-PASSWORD-RESET-TOKEN-ERROR":        "密码重置令牌无效、已过期或已被使用",
+		"LOGIN-LOCKED":                              "登录失败次数过多，请稍后再试",
+		"TOTP-ENROLLMENT-REQUIRED":                  "您必须先注册TOTP才能登录",
+		"TOTP-ALREADY-ENROLLED-ERROR":               "您已注册TOTP。如需重置，请联系管理员",
+		"INVALID-TOTP-ERROR":                        "TOTP无效",
+		"INVALID-TOTP-ENROLLMENT-TOKEN-ERROR":       "TOTP注册令牌无效或已过期，请联系管理员再次重置您的TOTP",
+		"INVALID-OIDC-STATE-ERROR":                  "登录请求无效或已过期，请重新登录",
+		"INVALID-SAML-RESPONSE-ERROR":               "登录请求无效或已过期，请重新登录",
+		"INVALID-SAML-METADATA-ERROR":               "身份提供商元数据无效",
+		"INVALID-SUPERVISOR-ERROR":                  "您提供的主管无效",
+		"INVALID-HR-APPROVER-ERROR":                 "您提供的人力资源审批人无效",
+		"INVALID-SUBORDINATE-SUPERVISOR-PAIR-ERROR": "下属职位与主管职位不能相同",
+		"MISSING-SUPERVISOR-APPROVAL-ERROR":         "缺少主管批准",
+		"MISSING-HR-APPROVAL-ERROR":                 "缺少人力资源批准",
+		"MISSING-RECRUITER-ASSIGNMENT-ERROR":        "缺少招聘人员分配",
+		"MISSING-RECRUITER-SHORTLIST-ERROR":         "招聘人员尚未将此候选人列入候选名单",
+		"MISSING-INTERVIEW-DATE-ERROR":              "尚未设定面试日期",
+		"MISSING-HIRING-MANAGER-OFFER-ERROR":        "招聘经理尚未向此候选人发出录用通知",
+		"JOB-REQUISITION-ALREADY-FILLED":            "该职位申请已被填补",
+	},
+	"ms": {
+		"INTERNAL-SERVER-ERROR":                     "Ralat telah berlaku. ID jejak: {0}",
+		"INPUT-VALIDATION-ERROR":                    "Terdapat satu atau lebih ralat pada input anda:",
+		"RESOURCE-NOT-FOUND-ERROR":                  "{0} tidak wujud",
+		"UNIQUE-VIOLATION-ERROR":                    "{0} dengan {1} yang diberikan sudah wujud",
+		"INVALID-FOREIGN-KEY-ERROR":                 "{0} yang diberikan tidak sah",
+		"INVALID-SORT-FIELD-ERROR":                  "Keputusan tidak boleh diisih mengikut {0}. Ia hanya boleh diisih mengikut {1}",
+		"INVALID-SORT-ORDER-ERROR":                  "Susunan isihan mesti sama ada ASC atau DESC",
+		"INVALID-CURSOR-ERROR":                      "Kursor yang diberikan tidak sah",
+		"INVALID-JSON-ERROR":                        "JSON yang tidak sah diberikan sebagai badan permintaan",
+		"FILE-TOO-BIG-ERROR":                        "Fail yang dimuat naik melebihi had saiz",
+		"USER-UNAUTHENTICATED":                      "Pengguna tidak disahkan",
+		"USER-UNAUTHORISED":                         "Pengguna tidak dibenarkan",
+		"CROSS-ORIGIN-REQUEST-ERROR":                "Permintaan rentas asal tidak dibenarkan",
+		"PASSWORD-CHANGE-REQUIRED":                  "Anda mesti menukar kata laluan anda sebelum boleh log masuk",
+		"INVALID-PASSWORD-RESET-TOKEN-ERROR":        "Token set semula kata laluan tidak sah, telah tamat tempoh atau telah digunakan",
+		"LOGIN-LOCKED":                              "Terlalu banyak percubaan log masuk yang gagal. Sila cuba lagi kemudian",
+		"TOTP-ENROLLMENT-REQUIRED":                  "Anda mesti mendaftar TOTP sebelum boleh log masuk",
+		"TOTP-ALREADY-ENROLLED-ERROR":               "Anda telah mendaftar TOTP. Sila hubungi pentadbir untuk menetapkannya semula",
+		"INVALID-TOTP-ERROR":                        "TOTP tidak sah",
+		"INVALID-TOTP-ENROLLMENT-TOKEN-ERROR":       "Token pendaftaran TOTP tidak sah atau telah tamat tempoh. Sila hubungi pentadbir untuk menetapkan semula TOTP anda sekali lagi",
+		"INVALID-OIDC-STATE-ERROR":                  "Permintaan log masuk tidak sah atau telah tamat tempoh. Sila cuba log masuk semula",
+		"INVALID-SAML-RESPONSE-ERROR":               "Permintaan log masuk tidak sah atau telah tamat tempoh. Sila cuba log masuk semula",
+		"INVALID-SAML-METADATA-ERROR":               "Metadata penyedia identiti tidak sah",
+		"INVALID-SUPERVISOR-ERROR":                  "Anda telah memberikan penyelia yang tidak sah",
+		"INVALID-HR-APPROVER-ERROR":                 "Anda telah memberikan pelulus HR yang tidak sah",
+		"INVALID-SUBORDINATE-SUPERVISOR-PAIR-ERROR": "Jawatan subordinat dan jawatan penyelia tidak boleh sama",
+		"MISSING-SUPERVISOR-APPROVAL-ERROR":         "Kelulusan penyelia tiada",
+		"MISSING-HR-APPROVAL-ERROR":                 "Kelulusan HR tiada",
+		"MISSING-RECRUITER-ASSIGNMENT-ERROR":        "Penugasan perekrut tiada",
+		"MISSING-RECRUITER-SHORTLIST-ERROR":         "Perekrut belum menyenarai pendek calon ini",
+		"MISSING-INTERVIEW-DATE-ERROR":              "Tarikh temu duga belum ditetapkan",
+		"MISSING-HIRING-MANAGER-OFFER-ERROR":        "Pengurus pengambilan belum membuat tawaran kepada calon ini",
+		"JOB-REQUISITION-ALREADY-FILLED":            "Permintaan jawatan ini telah diisi",
+	},
+}
+
+func registerErrorMessageTranslations(universalTranslator *ut.UniversalTranslator) error {
+	for locale, templates := range errorMessageTranslations {
+		translator, found := universalTranslator.GetTranslator(locale)
+		if !found {
+			continue
+		}
+
+		for code, template := range templates {
+			err := translator.Add(code, template, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the error's message in the translator's language, or its original message if there is no translation
+// Validation errors keep their field messages, as they were translated by the validator
+func translateErrorMessage(translator ut.Translator, err *httperror.Error) string {
+	if translator == nil {
+		return err.Message
+	}
+
+	// Missing values are left blank rather than causing a panic in the translator
+	args := make([]string, maxErrorMessageArgs)
+	copy(args, err.Args)
+
+	message, translateErr := translator.T(err.Code, args...)
+	if translateErr != nil {
+		return err.Message
+	}
+
+	if err.Code == "INPUT-VALIDATION-ERROR" {
+		messages := []string{message}
+		for _, fieldError := range err.Errors {
+			messages = append(messages, fieldError.Message)
+		}
+		message = strings.Join(messages, "\n")
+	}
+
+	return message
+}
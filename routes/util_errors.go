@@ -10,6 +10,7 @@ var Err404NotFound = &httperror.Error{
 	Status:  404,
 	Message: "Not found",
 	Code:    "RESOURCE-NOT-FOUND-ERROR",
+	Args:    []string{"resource"},
 }
 
 var ErrInvalidJSON = &httperror.Error{
@@ -36,12 +37,85 @@ var ErrUserUnauthorised = &httperror.Error{
 	Code:    "USER-UNAUTHORISED",
 }
 
+var ErrPasswordChangeRequired = &httperror.Error{
+	Status:  http.StatusForbidden,
+	Message: "Your password must be changed before you can log in",
+	Code:    "PASSWORD-CHANGE-REQUIRED",
+}
+
+var ErrLoginLocked = &httperror.Error{
+	Status:  http.StatusTooManyRequests,
+	Message: "Too many failed login attempts. Please try again later",
+	Code:    "LOGIN-LOCKED",
+}
+
+// Also returned if the email or TOTP is wrong, so that password resets cannot be used to find accounts or guess TOTPs
+var ErrInvalidPasswordResetToken = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The password reset token is invalid, has expired or has already been used",
+	Code:    "INVALID-PASSWORD-RESET-TOKEN-ERROR",
+}
+
+var ErrTotpEnrollmentRequired = &httperror.Error{
+	Status:  http.StatusForbidden,
+	Message: "You must enroll in TOTP before you can log in",
+	Code:    "TOTP-ENROLLMENT-REQUIRED",
+}
+
+var ErrTotpAlreadyEnrolled = &httperror.Error{
+	Status:  http.StatusConflict,
+	Message: "You have already enrolled in TOTP. Please contact an admin to reset it",
+	Code:    "TOTP-ALREADY-ENROLLED-ERROR",
+}
+
+var ErrInvalidTotp = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The TOTP is invalid",
+	Code:    "INVALID-TOTP-ERROR",
+}
+
+var ErrInvalidTotpEnrollmentToken = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The TOTP enrollment token is invalid or has expired. Please contact an admin to reset your TOTP again",
+	Code:    "INVALID-TOTP-ENROLLMENT-TOKEN-ERROR",
+}
+
+var ErrInvalidOidcState = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The sign in request is invalid or has expired. Please try signing in again",
+	Code:    "INVALID-OIDC-STATE-ERROR",
+}
+
+var ErrInvalidSamlMetadata = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The identity provider metadata is invalid",
+	Code:    "INVALID-SAML-METADATA-ERROR",
+}
+
+var ErrInvalidSamlResponse = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The sign in request is invalid or has expired. Please try signing in again",
+	Code:    "INVALID-SAML-RESPONSE-ERROR",
+}
+
+var ErrCrossOriginRequest = &httperror.Error{
+	Status:  http.StatusForbidden,
+	Message: "Cross-origin requests are not allowed",
+	Code:    "CROSS-ORIGIN-REQUEST-ERROR",
+}
+
 var ErrInvalidSupervisor = &httperror.Error{
 	Status:  http.StatusBadRequest,
 	Message: "You have provided an invalid supervisor",
 	Code:    "INVALID-SUPERVISOR-ERROR",
 }
 
+var ErrInvalidHrApprover = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "You have provided an invalid HR approver",
+	Code:    "INVALID-HR-APPROVER-ERROR",
+}
+
 var ErrMissingSupervisorApproval = &httperror.Error{
 	Status:  403,
 	Message: "Supervisor approval is missing",
@@ -0,0 +1,76 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Parses the pagination & sorting query parameters (limit, cursor, offset, sortBy & sortOrder) of a request
+// The limit must be between 1 & maxPageLimit. The storage layer validates the sort field & cursor
+func (router *Router) getPageRequest(r *http.Request) (storage.PageRequest, error) {
+	query := r.URL.Query()
+
+	type Input struct {
+		Limit     string `validate:"omitempty,number" name:"limit"`
+		Cursor    string `name:"cursor"`
+		Offset    string `validate:"omitempty,number,excluded_with=Cursor" name:"offset"`
+		SortBy    string `name:"sort by"`
+		SortOrder string `validate:"omitempty,oneof=asc desc" name:"sort order"`
+	}
+	input := Input{
+		Limit:     query.Get("limit"),
+		Cursor:    query.Get("cursor"),
+		Offset:    query.Get("offset"),
+		SortBy:    query.Get("sortBy"),
+		SortOrder: strings.ToLower(query.Get("sortOrder")),
+	}
+	translator := getTranslator(r)
+	err := validateStruct(router.validate, translator, input)
+	if err != nil {
+		return storage.PageRequest{}, err
+	}
+
+	page := storage.PageRequest{
+		Limit:     defaultPageLimit,
+		Cursor:    input.Cursor,
+		SortBy:    input.SortBy,
+		SortOrder: input.SortOrder,
+	}
+
+	// The inputs are numbers, so Atoi can only fail if they are out of range, in which case it returns the maximum int
+	if input.Limit != "" {
+		limit, _ := strconv.Atoi(input.Limit)
+
+		type LimitInput struct {
+			Limit int `validate:"min=1" name:"limit"`
+		}
+		err = validateStruct(router.validate, translator, LimitInput{Limit: limit})
+		if err != nil {
+			return storage.PageRequest{}, err
+		}
+		if limit > maxPageLimit {
+			// The message is translated with the validator's template, so that it reads like the other validation errors
+			message, err := translator.T("max-number", "limit", strconv.Itoa(maxPageLimit))
+			if err != nil {
+				return storage.PageRequest{}, httperror.NewInternalServerError(err)
+			}
+			return storage.PageRequest{}, NewInputValidationError([]httperror.FieldError{{Field: "limit", Tag: "max", Message: message}})
+		}
+		page.Limit = limit
+	}
+
+	if input.Offset != "" {
+		page.Offset, _ = strconv.Atoi(input.Offset)
+	}
+
+	return page, nil
+}
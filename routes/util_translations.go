@@ -0,0 +1,227 @@
+package routes
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ms"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	zhtranslations "github.com/go-playground/validator/v10/translations/zh"
+)
+
+// English is the fallback for clients that do not accept any of the supported languages
+var supportedLocales = []locales.Translator{en.New(), zh.New(), ms.New()}
+
+// The validator has no built-in Malay translations, so every tag that we use is translated in tagTranslations instead
+var builtInValidatorTranslations = map[string]func(validate *validator.Validate, translator ut.Translator) error{
+	"en": entranslations.RegisterDefaultTranslations,
+	"zh": zhtranslations.RegisterDefaultTranslations,
+}
+
+// Templates of our custom validation tags. {0} is the field's name
+var customTagTranslations = map[string]map[string]string{
+	"en": {
+		"notBlank-string":                 "The {0} cannot be blank",
+		"notBlank-items":                  "You did not provide any {0}",
+		"notBlank-exist":                  "You must provide a {0}",
+		"notBlank-valid":                  "You provided an invalid {0}",
+		"isIsoDate":                       `The {0} must follow the "yyyy-mm-dd" format`,
+		"validPositionAssignmentDuration": "The end date must be at least {0} days after the start date",
+		"validRoleAssignmentPeriod":       "The {0} must be after the role assignment's start",
+	},
+	"zh": {
+		"notBlank-string":                 "{0}不能为空",
+		"notBlank-items":                  "您没有提供任何{0}",
+		"notBlank-exist":                  "您必须提供{0}",
+		"notBlank-valid":                  "您提供的{0}无效",
+		"isIsoDate":                       `{0}必须符合"yyyy-mm-dd"格式`,
+		"validPositionAssignmentDuration": "结束日期必须在开始日期之后至少{0}天",
+		"validRoleAssignmentPeriod":       "{0}必须晚于角色分配的开始时间",
+	},
+	"ms": {
+		"notBlank-string":                 "{0} tidak boleh kosong",
+		"notBlank-items":                  "Anda tidak memberikan sebarang {0}",
+		"notBlank-exist":                  "Anda mesti memberikan {0}",
+		"notBlank-valid":                  "Anda telah memberikan {0} yang tidak sah",
+		"isIsoDate":                       `{0} mesti mengikut format "yyyy-mm-dd"`,
+		"validPositionAssignmentDuration": "Tarikh tamat mesti sekurang-kurangnya {0} hari selepas tarikh mula",
+		"validRoleAssignmentPeriod":       "{0} mesti selepas permulaan penugasan peranan",
+	},
+}
+
+// Templates of the validator's tags that have no built-in translation for a locale. {0} is the field's name & {1} is the tag's parameter
+// Tags that depend on the field's kind have a template per kind (string, items, number or time), e.g. max-string
+var tagTranslations = map[string]map[string]string{
+	"en": {
+		"required_with":        "{0} is a required field",
+		"required_without":     "{0} is a required field",
+		"required_without_all": "{0} is a required field",
+		"excluded_with":        "{0} must not be provided together with {1}",
+	},
+	"zh": {
+		"excluded_with": "{0}不能与{1}同时提供",
+	},
+	"ms": {
+		"required":             "{0} wajib diisi",
+		"required_if":          "{0} wajib diisi",
+		"required_with":        "{0} wajib diisi",
+		"required_without":     "{0} wajib diisi",
+		"required_without_all": "{0} wajib diisi",
+		"excluded_with":        "{0} tidak boleh diberikan bersama {1}",
+		"alpha":                "{0} hanya boleh mengandungi huruf",
+		"email":                "{0} mesti alamat e-mel yang sah",
+		"number":               "{0} mesti nombor yang sah",
+		"url":                  "{0} mesti URL yang sah",
+		"uuid":                 "{0} mesti UUID yang sah",
+		"oneof":                "{0} mesti salah satu daripada [{1}]",
+		"nefield":              "{0} tidak boleh sama dengan {1}",
+		"max-string":           "{0} tidak boleh melebihi {1} aksara",
+		"max-items":            "{0} tidak boleh mengandungi lebih daripada {1} item",
+		"max-number":           "{0} mesti {1} atau kurang",
+		"min-string":           "{0} mesti sekurang-kurangnya {1} aksara",
+		"min-items":            "{0} mesti mengandungi sekurang-kurangnya {1} item",
+		"min-number":           "{0} mesti {1} atau lebih",
+		"gt-string":            "{0} mesti lebih panjang daripada {1} aksara",
+		"gt-items":             "{0} mesti mengandungi lebih daripada {1} item",
+		"gt-number":            "{0} mesti lebih besar daripada {1}",
+		"gt-time":              "{0} mesti selepas tarikh & masa semasa",
+	},
+}
+
+// Registers the validator's translations for every supported locale. Built-in translations are registered first, so that
+// tagTranslations only fill in the gaps
+func registerValidatorTranslations(validate *validator.Validate, universalTranslator *ut.UniversalTranslator) error {
+	for _, locale := range supportedLocales {
+		translator, _ := universalTranslator.GetTranslator(locale.Locale())
+
+		if registerBuiltIn, ok := builtInValidatorTranslations[locale.Locale()]; ok {
+			err := registerBuiltIn(validate, translator)
+			if err != nil {
+				return err
+			}
+		}
+
+		templates := tagTranslations[locale.Locale()]
+		tags := map[string]bool{}
+		for key := range templates {
+			tag, _, _ := strings.Cut(key, "-")
+			tags[tag] = true
+		}
+		for tag := range tags {
+			err := validate.RegisterTranslation(tag, translator, registerTagTranslations(tag, templates), executeTagTranslations)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func registerTagTranslations(tag string, templates map[string]string) validator.RegisterTranslationsFunc {
+	return func(translator ut.Translator) error {
+		for key, template := range templates {
+			if key == tag || strings.HasPrefix(key, tag+"-") {
+				err := translator.Add(key, template, true)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// Uses the template for the field's kind if there is one, otherwise the tag's template
+func executeTagTranslations(translator ut.Translator, fieldError validator.FieldError) string {
+	var kind string
+	switch fieldError.Kind() {
+	case reflect.String:
+		kind = "string"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		kind = "items"
+	case reflect.Struct:
+		if fieldError.Type() == reflect.TypeOf(time.Time{}) {
+			kind = "time"
+		}
+	default:
+		kind = "number"
+	}
+
+	message, err := translator.T(fieldError.Tag()+"-"+kind, fieldError.Field(), fieldError.Param())
+	if err != nil {
+		message, err = translator.T(fieldError.Tag(), fieldError.Field(), fieldError.Param())
+	}
+	if err != nil {
+		message = fieldError.Tag() + " translation failed"
+	}
+
+	return message
+}
+
+// Returns the languages in an Accept-Language header (e.g. "zh-CN,zh;q=0.9,en;q=0.8"), from the most to the least preferred
+// Languages with a quality of 0 are not acceptable, so they are left out
+func parseAcceptLanguage(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+
+	languages := []language{}
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if name == "q" {
+				parsed, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					parsed = 0
+				}
+				quality = parsed
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		languages = append(languages, language{tag, quality})
+	}
+
+	// Languages with the same quality keep the order of the header
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := []string{}
+	for _, language := range languages {
+		tags = append(tags, language.tag)
+	}
+	return tags
+}
+
+// Returns the translator of the most preferred language that is supported, e.g. zh for zh-CN. Otherwise, returns the fallback
+func findTranslator(universalTranslator *ut.UniversalTranslator, acceptLanguageHeader string) ut.Translator {
+	candidates := []string{}
+	for _, tag := range parseAcceptLanguage(acceptLanguageHeader) {
+		tag = strings.ReplaceAll(tag, "-", "_")
+		base, _, _ := strings.Cut(tag, "_")
+		candidates = append(candidates, tag, base)
+	}
+
+	translator, _ := universalTranslator.FindTranslator(candidates...)
+	return translator
+}
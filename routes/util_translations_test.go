@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"zh-CN", []string{"zh-CN"}},
+		{"en;q=0.5, ms;q=0.9, zh", []string{"zh", "ms", "en"}},
+		{"ms-MY,ms;q=0.9,en-US;q=0.8,en;q=0.8", []string{"ms-MY", "ms", "en-US", "en"}},
+		{"zh;q=0, en", []string{"en"}},
+		{"*, ms;q=0.1", []string{"ms"}},
+		{"en;q=abc, zh", []string{"zh"}},
+	}
+
+	for _, test := range tests {
+		got := parseAcceptLanguage(test.header)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseAcceptLanguage(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestFindTranslator(t *testing.T) {
+	universalTranslator := NewUniversalTranslator()
+
+	tests := map[string]string{
+		"":                          "en",
+		"fr":                        "en",
+		"zh-CN":                     "zh",
+		"fr, ms-MY;q=0.8, en;q=0.5": "ms",
+		"en;q=0.1, zh;q=0.2":        "zh",
+	}
+
+	for header, want := range tests {
+		if got := findTranslator(universalTranslator, header).Locale(); got != want {
+			t.Errorf("findTranslator(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestErrorMessageTranslationsShouldHaveSameCodes(t *testing.T) {
+	for locale, templates := range errorMessageTranslations {
+		for otherLocale, otherTemplates := range errorMessageTranslations {
+			for code := range templates {
+				if _, ok := otherTemplates[code]; !ok {
+					t.Errorf("%s is translated to %s but not to %s", code, locale, otherLocale)
+				}
+			}
+		}
+	}
+}
+
+// Every tag used by the routes should be translated, otherwise the validator returns its raw (English) error
+func TestValidatorTranslations(t *testing.T) {
+	universalTranslator := NewUniversalTranslator()
+	validate, err := NewValidator(universalTranslator)
+	if err != nil {
+		t.Fatalf("Validator failed to instantiate: %s", err.Error())
+	}
+
+	type Input struct {
+		Required   string     `validate:"required" name:"required"`
+		NotBlank   string     `validate:"notBlank" name:"not blank"`
+		Uuid       string     `validate:"uuid" name:"uuid"`
+		Alpha      string     `validate:"alpha" name:"alpha"`
+		Email      string     `validate:"email" name:"email"`
+		Number     string     `validate:"number" name:"number"`
+		Url        string     `validate:"url" name:"url"`
+		OneOf      string     `validate:"oneof=a b" name:"one of"`
+		Max        string     `validate:"max=1" name:"max"`
+		Min        []string   `validate:"min=2" name:"min"`
+		Gt         *time.Time `validate:"gt" name:"gt"`
+		Date       string     `validate:"isIsoDate" name:"date"`
+		With       string     `validate:"required_with=Alpha" name:"with"`
+		Without    string     `validate:"required_without=Required" name:"without"`
+		WithoutAll string     `validate:"required_without_all=Required" name:"without all"`
+		Excluded   string     `validate:"excluded_with=Alpha" name:"excluded"`
+	}
+	past := time.Now().Add(-time.Hour)
+	input := Input{NotBlank: " ", Uuid: "1", Alpha: "1", Email: "a", Number: "a", Url: "a", OneOf: "c", Max: "ab", Min: []string{"a"},
+		Gt: &past, Date: "2024/01/01", Excluded: "a"}
+
+	for _, locale := range []string{"en", "zh", "ms"} {
+		translator, _ := universalTranslator.GetTranslator(locale)
+
+		err := validateStruct(validate, translator, input)
+		httpErr, ok := err.(*httperror.Error)
+		if !ok {
+			t.Fatalf("validateStruct() = %v, want a httperror.Error", err)
+		}
+		if len(httpErr.Errors) != 16 {
+			t.Errorf("%s: every field should be invalid, got %+v", locale, httpErr.Errors)
+		}
+
+		for _, fieldError := range httpErr.Errors {
+			if strings.Contains(fieldError.Message, "Key: ") || strings.Contains(fieldError.Message, "translation failed") {
+				t.Errorf("%s: the %s message %q was not translated", locale, fieldError.Tag, fieldError.Message)
+			}
+		}
+	}
+}
+
+func TestErrorHandlingShouldTranslateMessages(t *testing.T) {
+	universalTranslator := NewUniversalTranslator()
+	handler := setRequestLogger(NewRootLogger(new(bytes.Buffer)))(setTranslator(universalTranslator)(errorHandling(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sendToErrorHandlingMiddleware(ErrLoginLocked, r)
+		}),
+	)))
+
+	tests := []struct {
+		acceptLanguage string
+		wantLanguage   string
+		wantDetail     string
+	}{
+		{"", "en", ErrLoginLocked.Message},
+		{"zh-CN,zh;q=0.9", "zh", "登录失败次数过多，请稍后再试"},
+		{"en;q=0.5, ms", "ms", "Terlalu banyak percubaan log masuk yang gagal. Sila cuba lagi kemudian"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api/session", nil)
+		r.Header.Set("Accept-Language", test.acceptLanguage)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		var body errorResponseBody
+		err := json.NewDecoder(w.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body.Detail != test.wantDetail || body.Code != "LOGIN-LOCKED" {
+			t.Errorf("Accept-Language %q: got %+v, want the detail %q", test.acceptLanguage, body, test.wantDetail)
+		}
+		if got := w.Header().Get("Content-Language"); got != test.wantLanguage {
+			t.Errorf("Accept-Language %q: Content-Language = %s, want %s", test.acceptLanguage, got, test.wantLanguage)
+		}
+	}
+}
+
+// The page limit is checked against maxPageLimit outside of the validator, so its message should still be translated
+func TestGetPageRequestShouldTranslateErrors(t *testing.T) {
+	universalTranslator := NewUniversalTranslator()
+	validate, err := NewValidator(universalTranslator)
+	if err != nil {
+		t.Fatalf("Validator failed to instantiate: %s", err.Error())
+	}
+	router := &Router{validate: validate}
+
+	tests := []struct {
+		acceptLanguage string
+		query          string
+		wantMessage    string
+	}{
+		{"", "limit=101", "limit must be 100 or less"},
+		{"", "limit=99999999999999999999", "limit must be 100 or less"},
+		{"zh", "limit=101", "limit必须小于或等于100"},
+		{"ms", "limit=101", "limit mesti 100 atau kurang"},
+	}
+
+	for _, test := range tests {
+		var err error
+		handler := setTranslator(universalTranslator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err = router.getPageRequest(r)
+		}))
+		r := httptest.NewRequest("GET", "/?"+test.query, nil)
+		r.Header.Set("Accept-Language", test.acceptLanguage)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		httpErr, ok := err.(*httperror.Error)
+		if !ok || len(httpErr.Errors) != 1 {
+			t.Fatalf("%s %s: getPageRequest() = %v, want an input validation error", test.acceptLanguage, test.query, err)
+		}
+		if got := httpErr.Errors[0]; got.Field != "limit" || got.Tag != "max" || got.Message != test.wantMessage {
+			t.Errorf("%s %s: got %+v, want the message %q", test.acceptLanguage, test.query, got, test.wantMessage)
+		}
+	}
+}
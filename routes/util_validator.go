@@ -1,16 +1,15 @@
 package routes
 
 import (
-	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	validators "github.com/go-playground/validator/v10/non-standard/validators" // convention is for aliases to be 1 word long
-	validatortranslations "github.com/go-playground/validator/v10/translations/en"
 
 	"multi-tenant-HR-information-system-backend/httperror"
 )
@@ -20,20 +19,26 @@ import (
 // Therefore, you should order your validation tags from broadest (e.g. required) to most specific (e.g. notBlank, then isIsoDate)
 // In other words, every subsequent validation tag must test a subset of the "valid" scenarios of the previous tag
 
+// Every supported locale has a translator, with English as the fallback
+// The error messages are added to the translators here, as they are not specific to the validator
 func NewUniversalTranslator() *ut.UniversalTranslator {
-	enTranslator := en.New()
-	return ut.New(enTranslator, enTranslator)
+	universalTranslator := ut.New(supportedLocales[0], supportedLocales...)
+
+	// The catalog is static, so failing to add it is a programming error
+	err := registerErrorMessageTranslations(universalTranslator)
+	if err != nil {
+		panic(err)
+	}
+
+	return universalTranslator
 }
 
 func NewValidator(universalTranslator *ut.UniversalTranslator) (*validator.Validate, error) {
-	// Fetch all valid translators
-	englishTranslator, _ := universalTranslator.GetTranslator("en")
-
 	// Initialise a validator with default validation checks
 	validate := validator.New(validator.WithRequiredStructEnabled())
 
-	// Attach the default error message templates & translation functions to the validator
-	err := validatortranslations.RegisterDefaultTranslations(validate, englishTranslator)
+	// Attach the default error message templates & translation functions of every supported locale to the validator
+	err := registerValidatorTranslations(validate, universalTranslator)
 	if err != nil {
 		return nil, err
 	}
@@ -44,29 +49,43 @@ func NewValidator(universalTranslator *ut.UniversalTranslator) (*validator.Valid
 		return nil, err
 	}
 
-	err = validate.RegisterTranslation("notBlank", englishTranslator, registerNotBlankTranslations, executeNotBlankTranslations)
-	if err != nil {
-		return nil, err
-	}
-
 	err = validate.RegisterValidation("isIsoDate", isIsoDate)
 	if err != nil {
 		return nil, err
 	}
 
-	err = validate.RegisterTranslation("isIsoDate", englishTranslator, registerIsIsoDateTranslations, executeIsIsoDateTranslations)
+	err = validate.RegisterValidation("validPositionAssignmentDuration", validPositionAssignmentDuration)
 	if err != nil {
 		return nil, err
 	}
 
-	err = validate.RegisterValidation("validPositionAssignmentDuration", validPositionAssignmentDuration)
+	err = validate.RegisterValidation("validRoleAssignmentPeriod", validRoleAssignmentPeriod)
 	if err != nil {
 		return nil, err
 	}
 
-	err = validate.RegisterTranslation("validPositionAssignmentDuration", englishTranslator, registerValidPositionAssignmentDurationTranslations, executeValidPositionAssignmentDurationTranslations)
-	if err != nil {
-		return nil, err
+	for _, locale := range supportedLocales {
+		translator, _ := universalTranslator.GetTranslator(locale.Locale())
+
+		err = validate.RegisterTranslation("notBlank", translator, registerNotBlankTranslations, executeNotBlankTranslations)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validate.RegisterTranslation("isIsoDate", translator, registerIsIsoDateTranslations, executeIsIsoDateTranslations)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validate.RegisterTranslation("validPositionAssignmentDuration", translator, registerValidPositionAssignmentDurationTranslations, executeValidPositionAssignmentDurationTranslations)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validate.RegisterTranslation("validRoleAssignmentPeriod", translator, registerValidRoleAssignmentPeriodTranslations, executeValidRoleAssignmentPeriodTranslations)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Add a tag name function so that way the validator can use the struct tag names in its error messages instead
@@ -77,16 +96,17 @@ func NewValidator(universalTranslator *ut.UniversalTranslator) (*validator.Valid
 	return validate, nil
 }
 
-func NewInputValidationError(validationErrors map[string]string) *httperror.Error {
+func NewInputValidationError(fieldErrors []httperror.FieldError) *httperror.Error {
 	message := "There are one or more errors with your input(s):"
-	for _, errorMessage := range validationErrors {
-		message = message + "\n" + errorMessage
+	for _, fieldError := range fieldErrors {
+		message = message + "\n" + fieldError.Message
 	}
 
 	return &httperror.Error{
 		Status:  http.StatusBadRequest,
 		Message: message,
 		Code:    "INPUT-VALIDATION-ERROR",
+		Errors:  fieldErrors,
 	}
 }
 
@@ -95,25 +115,41 @@ func validateStruct(validate *validator.Validate, translator ut.Translator, s in
 	err := validate.Struct(s)
 	if err != nil {
 		validationErrors := err.(validator.ValidationErrors)
-		errorMessages := validationErrors.Translate(translator)
-		return NewInputValidationError(errorMessages)
+
+		fieldErrors := []httperror.FieldError{}
+		for _, validationError := range validationErrors {
+			fieldErrors = append(fieldErrors, httperror.FieldError{
+				Field:   getInputFieldPath(validationError),
+				Tag:     validationError.Tag(),
+				Message: validationError.Translate(translator),
+			})
+		}
+		return NewInputValidationError(fieldErrors)
 	}
 
 	return nil
 }
 
-func registerNotBlankTranslations(translator ut.Translator) error {
-	if err := translator.Add("notBlank-string", "The {0} cannot be blank", false); err != nil {
-		return err
-	}
-	if err := translator.Add("notBlank-items", "You did not provide any {0}", false); err != nil {
-		return err
-	}
-	if err := translator.Add("notBlank-exist", `You must provide a {0}`, false); err != nil {
-		return err
+// The name tags are used in messages, so the field path is built from the struct field names instead
+// E.g. Input.Scopes[0] becomes scopes[0], which matches the field in the request body as JSON keys are matched case-insensitively
+func getInputFieldPath(fieldError validator.FieldError) string {
+	_, path, _ := strings.Cut(fieldError.StructNamespace(), ".")
+
+	segments := strings.Split(path, ".")
+	for i, segment := range segments {
+		if segment != "" {
+			segments[i] = strings.ToLower(segment[:1]) + segment[1:]
+		}
 	}
-	if err := translator.Add("notBlank-valid", `You provided an invalid {0}`, false); err != nil {
-		return err
+	return strings.Join(segments, ".")
+}
+
+func registerNotBlankTranslations(translator ut.Translator) error {
+	templates := customTagTranslations[translator.Locale()]
+	for _, key := range []string{"notBlank-string", "notBlank-items", "notBlank-exist", "notBlank-valid"} {
+		if err := translator.Add(key, templates[key], false); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -157,7 +193,7 @@ func isIsoDate(fl validator.FieldLevel) bool {
 }
 
 func registerIsIsoDateTranslations(translator ut.Translator) error {
-	err := translator.Add("isIsoDate", `The {0} must follow the "yyyy-mm-dd" format`, false)
+	err := translator.Add("isIsoDate", customTagTranslations[translator.Locale()]["isIsoDate"], false)
 	return err
 }
 
@@ -214,16 +250,48 @@ func validPositionAssignmentDuration(fl validator.FieldLevel) bool {
 }
 
 func registerValidPositionAssignmentDurationTranslations(translator ut.Translator) error {
-	message := fmt.Sprintf("The end date must be at least %v days after the start date", minimumPositionAssignmentDurationDays)
-	err := translator.Add("validPositionAssignmentDuration", message, false)
+	err := translator.Add("validPositionAssignmentDuration", customTagTranslations[translator.Locale()]["validPositionAssignmentDuration"], false)
 	return err
 }
 
 func executeValidPositionAssignmentDurationTranslations(translator ut.Translator, fieldError validator.FieldError) string {
-	msg, err := translator.T("validPositionAssignmentDuration")
+	msg, err := translator.T("validPositionAssignmentDuration", strconv.Itoa(minimumPositionAssignmentDurationDays))
 	if err != nil {
 		msg = "validPositionAssignmentDuration translation failed"
 	}
 
 	return msg
 }
+
+// Returns true if the role assignment's end (i.e. the field) is after its start (i.e. ValidFrom), or if either is not provided
+func validRoleAssignmentPeriod(fl validator.FieldLevel) bool {
+	validUntil, ok := fl.Field().Interface().(time.Time)
+	if !ok {
+		return false
+	}
+
+	validFromField, kind, _, ok := fl.GetStructFieldOKAdvanced2(fl.Parent(), "ValidFrom")
+	if !ok || (kind == reflect.Ptr && validFromField.IsNil()) {
+		return true
+	}
+	validFrom, ok := validFromField.Interface().(time.Time)
+	if !ok {
+		return false
+	}
+
+	return validUntil.After(validFrom)
+}
+
+func registerValidRoleAssignmentPeriodTranslations(translator ut.Translator) error {
+	err := translator.Add("validRoleAssignmentPeriod", customTagTranslations[translator.Locale()]["validRoleAssignmentPeriod"], false)
+	return err
+}
+
+func executeValidRoleAssignmentPeriodTranslations(translator ut.Translator, fieldError validator.FieldError) string {
+	msg, err := translator.T("validRoleAssignmentPeriod", fieldError.Field())
+	if err != nil {
+		msg = "validRoleAssignmentPeriod translation failed"
+	}
+
+	return msg
+}
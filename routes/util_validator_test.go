@@ -3,9 +3,13 @@ package routes
 import (
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+
+	"multi-tenant-HR-information-system-backend/httperror"
 )
 
 // Common definitions
@@ -84,3 +88,55 @@ func TestValidPositionAssignmentDuration(t *testing.T) {
 
 	runValidationTest(t, tests)
 }
+
+func TestValidRoleAssignmentPeriod(t *testing.T) {
+	type Test struct {
+		ValidFrom  *time.Time
+		ValidUntil *time.Time `validate:"omitempty,validRoleAssignmentPeriod"`
+	}
+
+	start := time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	tests := []validationTestCase{
+		{"Period should be valid because it ends after it starts", Test{&start, &end}, []fieldTagPair{}},
+		{"Period should be valid because it does not start later", Test{nil, &end}, []fieldTagPair{}},
+		{"Period should be valid because it does not expire", Test{&start, nil}, []fieldTagPair{}},
+		{"Period should be invalid because it ends when it starts", Test{&start, &start}, []fieldTagPair{{"ValidUntil", "validRoleAssignmentPeriod"}}},
+		{"Period should be invalid because it ends before it starts", Test{&end, &start}, []fieldTagPair{{"ValidUntil", "validRoleAssignmentPeriod"}}},
+	}
+
+	runValidationTest(t, tests)
+}
+
+func TestValidateStructShouldListFieldErrors(t *testing.T) {
+	ut := NewUniversalTranslator()
+	validate, err := NewValidator(ut)
+	if err != nil {
+		t.Fatalf("Validator failed to instantiate: %s", err.Error())
+	}
+	translator, _ := ut.GetTranslator("en")
+
+	type Input struct {
+		TenantId string   `validate:"required,notBlank,uuid" name:"tenant id"`
+		Scopes   []string `validate:"required,notBlank,dive,oneof=read write" name:"scopes"`
+	}
+
+	err = validateStruct(validate, translator, Input{TenantId: "", Scopes: []string{"read", "admin"}})
+	httpErr, ok := err.(*httperror.Error)
+	if !ok {
+		t.Fatalf("validateStruct() = %v, want a httperror.Error", err)
+	}
+
+	// Fields are named as in the request, whereas messages use the name tags
+	want := []httperror.FieldError{
+		{Field: "tenantId", Tag: "required", Message: "tenant id is a required field"},
+		{Field: "scopes[1]", Tag: "oneof", Message: "scopes[1] must be one of [read write]"},
+	}
+	if !reflect.DeepEqual(httpErr.Errors, want) {
+		t.Errorf("Errors = %+v, want %+v", httpErr.Errors, want)
+	}
+	if httpErr.Code != "INPUT-VALIDATION-ERROR" || !strings.Contains(httpErr.Message, want[0].Message) {
+		t.Errorf("the message should still list every error, got %q", httpErr.Message)
+	}
+}
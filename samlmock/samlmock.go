@@ -0,0 +1,155 @@
+// A minimal SAML 2.0 identity provider for testing SSO locally, without a real IdP
+// Every authentication request is approved immediately on behalf of the configured user
+package samlmock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/logger"
+)
+
+type Server struct {
+	*httptest.Server
+
+	mu               sync.Mutex
+	nameId           string
+	attributes       map[string]string
+	serviceProviders map[string]*saml.EntityDescriptor // Keyed by entity id
+	idp              *saml.IdentityProvider
+}
+
+// Starts an identity provider with a self-signed signing certificate. Close it once it is no longer needed
+func NewServer() (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "samlmock"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		attributes:       map[string]string{},
+		serviceProviders: map[string]*saml.EntityDescriptor{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) { server.idp.ServeMetadata(w, r) })
+	mux.HandleFunc("/sso", func(w http.ResponseWriter, r *http.Request) { server.idp.ServeSSO(w, r) })
+	server.Server = httptest.NewServer(mux)
+
+	metadataUrl, _ := url.Parse(server.URL + "/metadata")
+	ssoUrl, _ := url.Parse(server.URL + "/sso")
+	server.idp = &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             cert,
+		Logger:                  logger.DefaultLogger,
+		MetadataURL:             *metadataUrl,
+		SSOURL:                  *ssoUrl,
+		ServiceProviderProvider: server,
+		SessionProvider:         server,
+	}
+
+	return server, nil
+}
+
+// Returns the identity provider's metadata XML, which is uploaded to the service provider
+func (server *Server) Metadata() ([]byte, error) {
+	return xml.MarshalIndent(server.idp.Metadata(), "", "  ")
+}
+
+// Registers a service provider with its metadata XML. Requests from unregistered service providers are rejected
+func (server *Server) AddServiceProvider(metadata []byte) error {
+	var serviceProvider saml.EntityDescriptor
+	err := xml.Unmarshal(metadata, &serviceProvider)
+	if err != nil {
+		return err
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.serviceProviders[serviceProvider.EntityID] = &serviceProvider
+	return nil
+}
+
+// Sets the user who is signed in. The NameID is sent in the emailAddress format, & the attributes as string attributes
+func (server *Server) SetUser(nameId string, attributes map[string]string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	server.nameId = nameId
+	server.attributes = attributes
+}
+
+// Implements saml.ServiceProviderProvider
+func (server *Server) GetServiceProvider(r *http.Request, serviceProviderId string) (*saml.EntityDescriptor, error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	serviceProvider, ok := server.serviceProviders[serviceProviderId]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return serviceProvider, nil
+}
+
+// Implements saml.SessionProvider
+func (server *Server) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *saml.Session {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if server.nameId == "" {
+		http.Error(w, "no user is signed in", http.StatusUnauthorized)
+		return nil
+	}
+
+	session := &saml.Session{
+		ID:           randomId(),
+		CreateTime:   time.Now(),
+		ExpireTime:   time.Now().Add(time.Hour),
+		Index:        randomId(),
+		NameID:       server.nameId,
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+	}
+	for name, value := range server.attributes {
+		session.CustomAttributes = append(session.CustomAttributes, saml.Attribute{
+			Name:       name,
+			NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic",
+			Values:     []saml.AttributeValue{{Type: "xs:string", Value: value}},
+		})
+	}
+
+	return session
+}
+
+func randomId() string {
+	randomBytes := make([]byte, 16)
+	rand.Read(randomBytes)
+	return big.NewInt(0).SetBytes(randomBytes).Text(36)
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+func (postgres *postgresStorage) CreateServiceAccount(serviceAccount storage.ServiceAccount) error {
+	// All queries must be conditional on the tenantId
+	if serviceAccount.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := "INSERT INTO service_account (id, tenant_id, name) VALUES ($1, $2, $3)"
+	_, err := postgres.db.Exec(query, serviceAccount.Id, serviceAccount.TenantId, serviceAccount.Name)
+
+	if pgErr, ok := err.(*pq.Error); ok {
+		switch pgErr.Code {
+		case "23505":
+			// Unique Violation
+			return NewUniqueViolationError("service account", pgErr)
+		case "23503":
+			// Foreign Key Violation
+			return NewInvalidForeignKeyError(pgErr)
+		default:
+			return httperror.NewInternalServerError(pgErr)
+		}
+	} else if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+func (postgres *postgresStorage) CreateApiToken(apiToken storage.ApiToken) error {
+	// All queries must be conditional on the tenantId
+	if apiToken.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	var expiresAt sql.NullTime
+	if !apiToken.ExpiresAt.IsZero() {
+		expiresAt = sql.NullTime{Time: apiToken.ExpiresAt, Valid: true}
+	}
+
+	// The owner must belong to the same tenant as the token
+	query := `
+		INSERT INTO api_token (id, tenant_id, user_account_id, service_account_id, name, token_hash, scopes, expires_at)
+		SELECT $1::UUID, $2::UUID, NULLIF($3, '')::UUID, NULLIF($4, '')::UUID, $5, $6, $7::TEXT[], $8::TIMESTAMPTZ
+		WHERE EXISTS (SELECT 1 FROM user_account WHERE id = NULLIF($3, '')::UUID AND tenant_id = $2::UUID)
+			OR EXISTS (SELECT 1 FROM service_account WHERE id = NULLIF($4, '')::UUID AND tenant_id = $2::UUID)`
+	result, err := postgres.db.Exec(query, apiToken.Id, apiToken.TenantId, apiToken.UserId, apiToken.ServiceAccountId, apiToken.Name,
+		apiToken.TokenHash, pq.Array(apiToken.Scopes), expiresAt)
+
+	if pgErr, ok := err.(*pq.Error); ok {
+		switch pgErr.Code {
+		case "23505":
+			// Unique Violation
+			return NewUniqueViolationError("API token", pgErr)
+		case "23503":
+			// Foreign Key Violation
+			return NewInvalidForeignKeyError(pgErr)
+		default:
+			return httperror.NewInternalServerError(pgErr)
+		}
+	} else if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		if apiToken.ServiceAccountId != "" {
+			return New404NotFoundError("service account")
+		}
+		return New404NotFoundError("user")
+	}
+
+	return nil
+}
+
+// Expired tokens are included, so that their use can be distinguished from the use of invalid tokens
+func (postgres *postgresStorage) GetApiTokens(filter storage.ApiToken) ([]storage.ApiToken, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newApiTokenConditions(filter)
+	query := NewQueryWithFilter(`
+		SELECT id, tenant_id, COALESCE(user_account_id::TEXT, ''), COALESCE(service_account_id::TEXT, ''), name, token_hash, scopes,
+			expires_at, created_at, updated_at
+		FROM api_token`, conditions) + " ORDER BY created_at DESC"
+
+	rows, err := postgres.db.Query(query, values...)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	apiTokens := []storage.ApiToken{}
+	for rows.Next() {
+		var apiToken storage.ApiToken
+		var expiresAt sql.NullTime
+		err := rows.Scan(&apiToken.Id, &apiToken.TenantId, &apiToken.UserId, &apiToken.ServiceAccountId, &apiToken.Name,
+			&apiToken.TokenHash, pq.Array(&apiToken.Scopes), &expiresAt, &apiToken.CreatedAt, &apiToken.UpdatedAt)
+		if err != nil {
+			return nil, httperror.NewInternalServerError(err)
+		}
+		apiToken.ExpiresAt = expiresAt.Time
+
+		apiTokens = append(apiTokens, apiToken)
+	}
+
+	return apiTokens, nil
+}
+
+// Deletes the tokens matching the filter, which immediately prevents clients from using them
+func (postgres *postgresStorage) DeleteApiTokens(filter storage.ApiToken) (int64, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return 0, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newApiTokenConditions(filter)
+	query := NewQueryWithFilter("DELETE FROM api_token", conditions)
+
+	result, err := postgres.db.Exec(query, values...)
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	return deleted, nil
+}
+
+func newApiTokenConditions(filter storage.ApiToken) ([]string, []any) {
+	conditions := []string{"tenant_id = $1"}
+	values := []any{filter.TenantId}
+
+	if filter.Id != "" {
+		values = append(values, filter.Id)
+		conditions = append(conditions, fmt.Sprintf("id = $%v", len(values)))
+	}
+
+	if filter.UserId != "" {
+		values = append(values, filter.UserId)
+		conditions = append(conditions, fmt.Sprintf("user_account_id = $%v", len(values)))
+	}
+
+	if filter.ServiceAccountId != "" {
+		values = append(values, filter.ServiceAccountId)
+		conditions = append(conditions, fmt.Sprintf("service_account_id = $%v", len(values)))
+	}
+
+	if filter.TokenHash != "" {
+		values = append(values, filter.TokenHash)
+		conditions = append(conditions, fmt.Sprintf("token_hash = $%v", len(values)))
+	}
+
+	return conditions, values
+}
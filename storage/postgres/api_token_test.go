@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"time"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+const testTokenHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func (s *IntegrationTestSuite) TestCreateServiceAccountShouldValidateUniqueness() {
+	serviceAccount := storage.ServiceAccount{
+		Id:       "6b1c1b8e-3f63-4c1c-9a3b-0f5d0c7f4f10",
+		TenantId: s.defaultTenant.Id,
+		Name:     "Payroll sync",
+	}
+	err := s.postgres.CreateServiceAccount(serviceAccount)
+	s.Equal(nil, err)
+
+	serviceAccount.Id = "c7a0a3f2-5d0e-4a0a-8a3e-2d7b6f2b7c11"
+	err = s.postgres.CreateServiceAccount(serviceAccount)
+	s.expectErrorCode(err, "UNIQUE-VIOLATION-ERROR")
+
+	// The columns of the constraint are reported, so that clients can tell which inputs are taken
+	if httpErr, ok := err.(*httperror.Error); ok {
+		s.Len(httpErr.Errors, 2)
+		for i, field := range []string{"tenantId", "name"} {
+			s.Equal(field, httpErr.Errors[i].Field)
+			s.Equal("unique", httpErr.Errors[i].Tag)
+		}
+	}
+}
+
+func (s *IntegrationTestSuite) TestCreateApiToken() {
+	serviceAccount := storage.ServiceAccount{
+		Id:       "6b1c1b8e-3f63-4c1c-9a3b-0f5d0c7f4f10",
+		TenantId: s.defaultTenant.Id,
+		Name:     "Payroll sync",
+	}
+	err := s.postgres.CreateServiceAccount(serviceAccount)
+	s.Equal(nil, err)
+
+	want := storage.ApiToken{
+		Id:               "0d3c5b9a-1e2f-4a5b-8c7d-6e5f4a3b2c1d",
+		TenantId:         s.defaultTenant.Id,
+		ServiceAccountId: serviceAccount.Id,
+		Name:             "Nightly export",
+		TokenHash:        testTokenHash,
+		Scopes:           []string{"read"},
+		ExpiresAt:        time.Now().Add(time.Hour).Truncate(time.Microsecond),
+	}
+	err = s.postgres.CreateApiToken(want)
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetApiTokens(storage.ApiToken{TenantId: s.defaultTenant.Id, TokenHash: testTokenHash})
+	s.Equal(nil, err)
+	s.Equal(1, len(got))
+	if len(got) == 1 {
+		s.Equal(want.ServiceAccountId, got[0].ServiceAccountId)
+		s.Equal("", got[0].UserId)
+		s.Equal(want.Scopes, got[0].Scopes)
+		s.Equal(true, want.ExpiresAt.Equal(got[0].ExpiresAt))
+	}
+
+	deleted, err := s.postgres.DeleteApiTokens(storage.ApiToken{TenantId: s.defaultTenant.Id, ServiceAccountId: serviceAccount.Id})
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+}
+
+func (s *IntegrationTestSuite) TestCreateApiTokenWithoutExpiry() {
+	err := s.postgres.CreateApiToken(storage.ApiToken{
+		Id:        "0d3c5b9a-1e2f-4a5b-8c7d-6e5f4a3b2c1d",
+		TenantId:  s.defaultTenant.Id,
+		UserId:    s.defaultUser.Id,
+		Name:      "Personal token",
+		TokenHash: testTokenHash,
+		Scopes:    []string{"read", "write"},
+	})
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetApiTokens(storage.ApiToken{TenantId: s.defaultTenant.Id, UserId: s.defaultUser.Id})
+	s.Equal(nil, err)
+	s.Equal(1, len(got))
+	if len(got) == 1 {
+		s.Equal(true, got[0].ExpiresAt.IsZero(), "The token should not expire")
+	}
+}
+
+func (s *IntegrationTestSuite) TestCreateApiTokenShouldValidateOwner() {
+	// The owner must belong to the token's tenant
+	err := s.postgres.CreateApiToken(storage.ApiToken{
+		Id:        "0d3c5b9a-1e2f-4a5b-8c7d-6e5f4a3b2c1d",
+		TenantId:  "a9f998c6-ba2e-4359-b308-e56404534974",
+		UserId:    s.defaultUser.Id,
+		Name:      "Personal token",
+		TokenHash: testTokenHash,
+		Scopes:    []string{"read"},
+	})
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+
+	err = s.postgres.CreateApiToken(storage.ApiToken{
+		Id:               "0d3c5b9a-1e2f-4a5b-8c7d-6e5f4a3b2c1d",
+		TenantId:         s.defaultTenant.Id,
+		ServiceAccountId: "6b1c1b8e-3f63-4c1c-9a3b-0f5d0c7f4f10",
+		Name:             "Nightly export",
+		TokenHash:        testTokenHash,
+		Scopes:           []string{"read"},
+	})
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
@@ -39,10 +39,17 @@ func (postgres *postgresStorage) CreateJobApplication(jobApplication storage.Job
 	return nil
 }
 
-func (postgres *postgresStorage) GetJobApplications(filter storage.JobApplication) ([]storage.JobApplication, error) {
+var jobApplicationSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"firstName": "first_name",
+	"lastName":  "last_name",
+}
+
+func (postgres *postgresStorage) GetJobApplications(filter storage.JobApplication, page storage.PageRequest) ([]storage.JobApplication, string, error) {
 	// All queries must be conditional on the tenantId
 	if filter.TenantId == "" {
-		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+		return nil, "", httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
 	}
 	conditions := []string{"tenant_id = $1"}
 	filterByValues := []any{filter.TenantId}
@@ -100,18 +107,30 @@ func (postgres *postgresStorage) GetJobApplications(filter storage.JobApplicatio
 		filterByValues = append(filterByValues, filter.ApplicantDecision)
 	}
 
-	query := NewQueryWithFilter("SELECT * FROM job_application", conditions)
-	rows, err := postgres.db.Query(query, filterByValues...)
+	columns := []string{
+		"id", "tenant_id", "job_requisition_id", "first_name", "last_name", "country_code", "phone_number", "email", "resume_s3_url",
+		"recruiter_decision", "interview_date", "hiring_manager_decision", "offer_start_date", "offer_end_date", "applicant_decision",
+		"created_at", "updated_at",
+	}
+	query, values, err := NewPaginatedQuery(columns, "job_application", conditions, filterByValues, page, jobApplicationSortColumns, "createdAt")
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := postgres.db.Query(query, values...)
 	if err != nil {
-		return nil, httperror.NewInternalServerError(err)
+		return nil, "", httperror.NewInternalServerError(err)
 	}
+	defer rows.Close()
 
 	jobApplications := []storage.JobApplication{}
+	sortValues := []string{}
 	for rows.Next() {
 		var jobApplication storage.JobApplication
 		var interviewDate sql.NullString
 		var offerStartDate sql.NullString
-		var offerEndDate sql.NullString				
+		var offerEndDate sql.NullString
+		var sortValue string
 
 		err := rows.Scan(
 			&jobApplication.Id,
@@ -131,19 +150,23 @@ func (postgres *postgresStorage) GetJobApplications(filter storage.JobApplicatio
 			&jobApplication.ApplicantDecision,
 			&jobApplication.CreatedAt,
 			&jobApplication.UpdatedAt,
+			&sortValue,
 		)
 
 		if err != nil {
-			return nil, httperror.NewInternalServerError(err)
+			return nil, "", httperror.NewInternalServerError(err)
 		}
 
 		jobApplication.InterviewDate = interviewDate.String
 		jobApplication.OfferStartDate = offerStartDate.String
 		jobApplication.OfferEndDate = offerEndDate.String				
 		jobApplications = append(jobApplications, jobApplication)
+		sortValues = append(sortValues, sortValue)
 	}
 
-	return jobApplications, nil
+	jobApplications, nextCursor := NewPage(page, "createdAt", jobApplications, sortValues, func(row storage.JobApplication) string { return row.Id })
+
+	return jobApplications, nextCursor, nil
 }
 
 func (postgres *postgresStorage) UpdateJobApplication(updatedValues storage.JobApplication, filter storage.JobApplication) error {
@@ -236,16 +259,17 @@ func (postgres *postgresStorage) UpdateJobApplication(updatedValues storage.JobA
 	return nil
 }
 
-// Creates the new user account, assigns the position to it, updates the job req to reflect that it is filled,
+// Creates the new user account with its policies, assigns the position to it, updates the job req to reflect that it is filled,
 // and updates the job application to reflect that the applicant has accepted it
-func (postgres *postgresStorage) OnboardNewHire(jobApplication storage.JobApplication, newUser storage.User) error {
+func (postgres *postgresStorage) OnboardNewHire(jobApplication storage.JobApplication, newUser storage.User, newUserPolicies storage.Policies) error {
 	tx, err := postgres.db.Begin()
 	if err != nil {
 		return httperror.NewInternalServerError(err)
 	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
 
-	createUser := "INSERT INTO user_account (id, tenant_id, email, password, totp_secret_key) VALUES ($1, $2, $3, $4, $5)"
-	_, err = tx.Exec(createUser, newUser.Id, newUser.TenantId, newUser.Email, newUser.Password, newUser.TotpSecretKey)		
+	createUser := "INSERT INTO user_account (id, tenant_id, email, password, totp_secret_key, must_change_password) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)"
+	_, err = tx.Exec(createUser, newUser.Id, newUser.TenantId, newUser.Email, newUser.Password, newUser.TotpSecretKey, newUser.MustChangePassword)		
 	if pgErr, ok := err.(*pq.Error); ok {	
 		switch pgErr.Code {
 		case "23505":
@@ -261,6 +285,11 @@ func (postgres *postgresStorage) OnboardNewHire(jobApplication storage.JobApplic
 		return httperror.NewInternalServerError(err)
 	}
 
+	err = insertPolicies(tx, newUserPolicies)
+	if err != nil {
+		return err
+	}
+
 	var positionId string
 	getPositionId := "SELECT position_id FROM job_requisition WHERE id = $1 AND tenant_id = $2"
 	err = tx.QueryRow(getPositionId, jobApplication.JobRequisitionId, jobApplication.TenantId).Scan(&positionId)
@@ -14,13 +14,19 @@ import (
 	"multi-tenant-HR-information-system-backend/storage"
 )
 
-func (postgres *postgresStorage) CreateJobRequisition(jobRequisition storage.JobRequisition) error {
-	var err error
+// Creates the job requisition together with the policies that its HR approver needs to approve it
+func (postgres *postgresStorage) CreateJobRequisition(jobRequisition storage.JobRequisition, hrApproverPolicies storage.Policies) error {
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
 	if jobRequisition.PositionId == "" {
 		query := `INSERT INTO job_requisition (id, tenant_id, title, department_id, supervisor_position_ids,  
 			job_description, job_requirements, requestor, supervisor, hr_approver)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-		_, err = postgres.db.Exec(query,
+		_, err = tx.Exec(query,
 			jobRequisition.Id, jobRequisition.TenantId, jobRequisition.Title, jobRequisition.DepartmentId,
 			pq.Array(jobRequisition.SupervisorPositionIds), jobRequisition.JobDescription,
 			jobRequisition.JobRequirements, jobRequisition.Requestor, jobRequisition.Supervisor, jobRequisition.HrApprover,
@@ -29,7 +35,7 @@ func (postgres *postgresStorage) CreateJobRequisition(jobRequisition storage.Job
 		query := `INSERT INTO job_requisition (id, tenant_id, position_id,
 			job_description, job_requirements, requestor, supervisor, hr_approver)
 			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-		_, err = postgres.db.Exec(query,
+		_, err = tx.Exec(query,
 			jobRequisition.Id, jobRequisition.TenantId, jobRequisition.PositionId, jobRequisition.JobDescription,
 			jobRequisition.JobRequirements, jobRequisition.Requestor, jobRequisition.Supervisor, jobRequisition.HrApprover,
 		)
@@ -50,13 +56,28 @@ func (postgres *postgresStorage) CreateJobRequisition(jobRequisition storage.Job
 		return httperror.NewInternalServerError(err)
 	}
 
+	err = insertPolicies(tx, hrApproverPolicies)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
 	return nil
 }
 
-func (postgres *postgresStorage) GetJobRequisitions(filter storage.JobRequisition) ([]storage.JobRequisition, error) {
+var jobRequisitionSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
+func (postgres *postgresStorage) GetJobRequisitions(filter storage.JobRequisition, page storage.PageRequest) ([]storage.JobRequisition, string, error) {
 	// All queries must be conditional on the tenantId
 	if filter.TenantId == "" {
-		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+		return nil, "", httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
 	}
 
 	conditions := []string{"tenant_id = $1"}
@@ -107,14 +128,24 @@ func (postgres *postgresStorage) GetJobRequisitions(filter storage.JobRequisitio
 		values = append(values, filter.FilledBy)
 	}
 
-	query := NewQueryWithFilter("SELECT * FROM job_requisition", conditions)
+	columns := []string{
+		"id", "tenant_id", "position_id", "title", "department_id", "supervisor_position_ids", "job_description", "job_requirements",
+		"requestor", "supervisor", "supervisor_decision", "hr_approver", "hr_approver_decision", "recruiter", "filled_by", "filled_at",
+		"created_at", "updated_at",
+	}
+	query, values, err := NewPaginatedQuery(columns, "job_requisition", conditions, values, page, jobRequisitionSortColumns, "createdAt")
+	if err != nil {
+		return nil, "", err
+	}
+
 	rows, err := postgres.db.Query(query, values...)
 	if err != nil {
-		return nil, httperror.NewInternalServerError(err)
+		return nil, "", httperror.NewInternalServerError(err)
 	}
 	defer rows.Close()
 
 	jobRequisitions := []storage.JobRequisition{}
+	sortValues := []string{}
 
 	for rows.Next() {
 		var jobRequisition storage.JobRequisition
@@ -125,6 +156,7 @@ func (postgres *postgresStorage) GetJobRequisitions(filter storage.JobRequisitio
 		var recruiter sql.NullString
 		var filledBy sql.NullString
 		var filledAt sql.NullTime
+		var sortValue string
 
 		err := rows.Scan(
 			&jobRequisition.Id,
@@ -145,10 +177,11 @@ func (postgres *postgresStorage) GetJobRequisitions(filter storage.JobRequisitio
 			&filledAt,
 			&jobRequisition.CreatedAt,
 			&jobRequisition.UpdatedAt,
+			&sortValue,
 		)
 
 		if err != nil {
-			return nil, httperror.NewInternalServerError(err)
+			return nil, "", httperror.NewInternalServerError(err)
 		}
 
 		jobRequisition.PositionId = positionId.String
@@ -159,9 +192,12 @@ func (postgres *postgresStorage) GetJobRequisitions(filter storage.JobRequisitio
 		jobRequisition.FilledAt = filledAt.Time
 
 		jobRequisitions = append(jobRequisitions, jobRequisition)
+		sortValues = append(sortValues, sortValue)
 	}
 
-	return jobRequisitions, nil
+	jobRequisitions, nextCursor := NewPage(page, "createdAt", jobRequisitions, sortValues, func(row storage.JobRequisition) string { return row.Id })
+
+	return jobRequisitions, nextCursor, nil
 }
 
 func (postgres *postgresStorage) UpdateJobRequisition(updatedValues storage.JobRequisition, filter storage.JobRequisition) error {
@@ -341,7 +377,8 @@ func (postgres *postgresStorage) UpdateJobRequisition(updatedValues storage.JobR
 }
 
 // Creates a new position if it doesn't exist, then adds hr approval & recruiter to the requisition
-func (postgres *postgresStorage) HrApproveJobRequisition(jobRequisitionId string, tenantId string, hrApprover string, recruiter string) error {
+// The recruiter's policies are added too, so that the recruiter can work on the job requisition once it is approved
+func (postgres *postgresStorage) HrApproveJobRequisition(jobRequisitionId string, tenantId string, hrApprover string, recruiter string, recruiterPolicies storage.Policies) error {
 	checkConstraints := map[string]*httperror.Error{
 		"ck_hr_approval_only_with_supervisor_approval":     ErrMissingSupervisorApproval,
 		"ck_recruiter_assignment_made_if_have_hr_approval": ErrMissingRecruiterAssignment,
@@ -456,6 +493,11 @@ func (postgres *postgresStorage) HrApproveJobRequisition(jobRequisitionId string
 		return httperror.NewInternalServerError(err)
 	}
 
+	err = insertPolicies(tx, recruiterPolicies)
+	if err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return httperror.NewInternalServerError(err)
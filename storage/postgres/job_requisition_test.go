@@ -22,9 +22,32 @@ func (s *IntegrationTestSuite) TestCreateJobRequisition() {
 		HrApprover:            s.defaultHrApprover.Id,
 	}
 
-	err := s.postgres.CreateJobRequisition(want)
+	hrApproverPolicies := storage.Policies{
+		Subject:   want.HrApprover,
+		TenantId:  want.TenantId,
+		Resources: []storage.Resource{{Path: "/api/tenants/*/users/" + want.HrApprover + "/job-requisitions/role-hr-approver", Method: "GET"}},
+	}
+
+	err := s.postgres.CreateJobRequisition(want, hrApproverPolicies)
 	s.Equal(nil, err)
 
+	// The HR approver may already have the policies from another job requisition, which must not cause a unique violation
+	want2 := want
+	want2.Id = "4a3ec6e3-7f19-4bd5-a1cf-5a8a8b3f9f6c"
+	err = s.postgres.CreateJobRequisition(want2, hrApproverPolicies)
+	s.Equal(nil, err)
+
+	s.expectSelectQueryToReturnOneRow(
+		"casbin_rule",
+		map[string]any{
+			"Ptype": "p",
+			"V0":    hrApproverPolicies.Subject,
+			"V1":    hrApproverPolicies.TenantId,
+			"V2":    hrApproverPolicies.Resources[0].Path,
+			"V3":    hrApproverPolicies.Resources[0].Method,
+		},
+	)
+
 	s.expectSelectQueryToReturnOneRow(
 		"job_requisition",
 		map[string]any{
@@ -128,7 +151,7 @@ func (s *IntegrationTestSuite) TestCreateJobRequisitionShouldHaveForeignKeyConst
 	}
 
 	for _, test := range tests {
-		err := s.postgres.CreateJobRequisition(test.input)
+		err := s.postgres.CreateJobRequisition(test.input, storage.Policies{})
 		s.expectErrorCode(err, "INVALID-FOREIGN-KEY-ERROR")
 
 		s.expectSelectQueryToReturnNoRows(
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Returns an empty LoginAttempt if there have been no failed logins since the last successful login
+func (postgres *postgresStorage) GetLoginAttempt(tenantId string, email string) (storage.LoginAttempt, error) {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return storage.LoginAttempt{}, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		SELECT tenant_id, email, failed_count, locked_until, last_failed_at, created_at, updated_at
+		FROM login_attempt WHERE tenant_id = $1 AND email = $2`
+	loginAttempt, err := scanLoginAttempt(postgres.db.QueryRow(query, tenantId, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.LoginAttempt{TenantId: tenantId, Email: email}, nil
+	}
+	if err != nil {
+		return storage.LoginAttempt{}, httperror.NewInternalServerError(err)
+	}
+
+	return loginAttempt, nil
+}
+
+// Increments the failed count atomically, so that concurrent attempts across instances are all counted
+func (postgres *postgresStorage) RecordFailedLogin(tenantId string, email string) (storage.LoginAttempt, error) {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return storage.LoginAttempt{}, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		INSERT INTO login_attempt (tenant_id, email, failed_count, last_failed_at) VALUES ($1, $2, 1, now())
+		ON CONFLICT (tenant_id, email) DO UPDATE
+		SET failed_count = login_attempt.failed_count + 1, last_failed_at = now(), updated_at = now()
+		RETURNING tenant_id, email, failed_count, locked_until, last_failed_at, created_at, updated_at`
+	loginAttempt, err := scanLoginAttempt(postgres.db.QueryRow(query, tenantId, email))
+	if err != nil {
+		return storage.LoginAttempt{}, httperror.NewInternalServerError(err)
+	}
+
+	return loginAttempt, nil
+}
+
+func (postgres *postgresStorage) LockLogin(tenantId string, email string, lockedUntil time.Time) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := "UPDATE login_attempt SET locked_until = $1, updated_at = now() WHERE tenant_id = $2 AND email = $3"
+	result, err := postgres.db.Exec(query, lockedUntil, tenantId, email)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("login attempt")
+	}
+
+	return nil
+}
+
+// Clears the failed count after a successful login
+func (postgres *postgresStorage) DeleteLoginAttempt(tenantId string, email string) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := "DELETE FROM login_attempt WHERE tenant_id = $1 AND email = $2"
+	_, err := postgres.db.Exec(query, tenantId, email)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// Deletes the login attempts of tenants & emails that have not failed to log in since failedBefore & are not locked, and returns
+// the number deleted. As attempts are recorded for any tenant id & email, this keeps the table from growing without limit
+func (postgres *postgresStorage) DeleteStaleLoginAttempts(failedBefore time.Time) (int64, error) {
+	query := "DELETE FROM login_attempt WHERE last_failed_at <= $1 AND (locked_until IS NULL OR locked_until <= now())"
+	result, err := postgres.db.Exec(query, failedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Periodically deletes the login attempts that have not failed for staleAfter in the background until stop is called
+// onCleanup is called after every cleanup so that the caller can log the result
+func (postgres *postgresStorage) StartLoginAttemptCleanup(interval time.Duration, staleAfter time.Duration, onCleanup func(deleted int64, err error)) (stop func()) {
+	quit := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := postgres.DeleteStaleLoginAttempts(time.Now().Add(-staleAfter))
+				onCleanup(deleted, err)
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+	}
+}
+
+func scanLoginAttempt(row *sql.Row) (storage.LoginAttempt, error) {
+	var loginAttempt storage.LoginAttempt
+	var lockedUntil, lastFailedAt sql.NullTime // NULL if logins have never been locked or failed
+
+	err := row.Scan(
+		&loginAttempt.TenantId,
+		&loginAttempt.Email,
+		&loginAttempt.FailedCount,
+		&lockedUntil,
+		&lastFailedAt,
+		&loginAttempt.CreatedAt,
+		&loginAttempt.UpdatedAt,
+	)
+	if err != nil {
+		return storage.LoginAttempt{}, err
+	}
+
+	loginAttempt.LockedUntil = lockedUntil.Time
+	loginAttempt.LastFailedAt = lastFailedAt.Time
+
+	return loginAttempt, nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"time"
+)
+
+func (s *IntegrationTestSuite) TestRecordFailedLogin() {
+	loginAttempt, err := s.postgres.GetLoginAttempt(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+	s.Equal(0, loginAttempt.FailedCount, "There should be no failed logins")
+
+	for i := 1; i <= 3; i++ {
+		loginAttempt, err = s.postgres.RecordFailedLogin(s.defaultUser.TenantId, s.defaultUser.Email)
+		s.Equal(nil, err)
+		s.Equal(i, loginAttempt.FailedCount)
+		s.Equal(true, loginAttempt.LockedUntil.IsZero())
+		s.Equal(false, loginAttempt.LastFailedAt.IsZero())
+	}
+
+	// Emails that do not belong to a user are tracked too
+	loginAttempt, err = s.postgres.RecordFailedLogin(s.defaultUser.TenantId, "unknown@gmail.com")
+	s.Equal(nil, err)
+	s.Equal(1, loginAttempt.FailedCount)
+
+	loginAttempt, err = s.postgres.GetLoginAttempt(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+	s.Equal(3, loginAttempt.FailedCount)
+}
+
+func (s *IntegrationTestSuite) TestLockLogin() {
+	lockedUntil := time.Now().Add(time.Minute)
+
+	err := s.postgres.LockLogin(s.defaultUser.TenantId, s.defaultUser.Email, lockedUntil)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+
+	_, err = s.postgres.RecordFailedLogin(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+
+	err = s.postgres.LockLogin(s.defaultUser.TenantId, s.defaultUser.Email, lockedUntil)
+	s.Equal(nil, err)
+
+	loginAttempt, err := s.postgres.GetLoginAttempt(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+	s.WithinDuration(lockedUntil, loginAttempt.LockedUntil, time.Millisecond)
+}
+
+func (s *IntegrationTestSuite) TestDeleteLoginAttempt() {
+	_, err := s.postgres.RecordFailedLogin(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+
+	err = s.postgres.DeleteLoginAttempt(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+
+	loginAttempt, err := s.postgres.GetLoginAttempt(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+	s.Equal(0, loginAttempt.FailedCount, "The failed count should have been cleared")
+}
+
+func (s *IntegrationTestSuite) TestDeleteStaleLoginAttempts() {
+	_, err := s.postgres.RecordFailedLogin(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+	_, err = s.postgres.RecordFailedLogin(s.defaultUser.TenantId, "stale@gmail.com")
+	s.Equal(nil, err)
+	_, err = s.postgres.RecordFailedLogin(s.defaultUser.TenantId, "locked@gmail.com")
+	s.Equal(nil, err)
+	err = s.postgres.LockLogin(s.defaultUser.TenantId, "locked@gmail.com", time.Now().Add(time.Hour))
+	s.Equal(nil, err)
+	_, err = s.dbRootConn.Exec("UPDATE login_attempt SET last_failed_at = now() - interval '2 days' WHERE email <> $1", s.defaultUser.Email)
+	s.Equal(nil, err)
+
+	deleted, err := s.postgres.DeleteStaleLoginAttempts(time.Now().Add(-24 * time.Hour))
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+
+	loginAttempt, err := s.postgres.GetLoginAttempt(s.defaultUser.TenantId, "stale@gmail.com")
+	s.Equal(nil, err)
+	s.Equal(0, loginAttempt.FailedCount, "The stale login attempt should have been deleted")
+
+	loginAttempt, err = s.postgres.GetLoginAttempt(s.defaultUser.TenantId, "locked@gmail.com")
+	s.Equal(nil, err)
+	s.Equal(1, loginAttempt.FailedCount, "Locked logins should not be deleted")
+
+	loginAttempt, err = s.postgres.GetLoginAttempt(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+	s.Equal(1, loginAttempt.FailedCount, "Recent login attempts should not be deleted")
+}
+
+func (s *IntegrationTestSuite) TestLoginAttemptShouldBeConditionalOnTenant() {
+	_, err := s.postgres.RecordFailedLogin(s.defaultUser.TenantId, s.defaultUser.Email)
+	s.Equal(nil, err)
+
+	loginAttempt, err := s.postgres.GetLoginAttempt("a9f998c6-ba2e-4359-b308-e56404534974", s.defaultUser.Email)
+	s.Equal(nil, err)
+	s.Equal(0, loginAttempt.FailedCount)
+}
@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration files are named <version>_<name>.<up|down>.sql, e.g. 000001_initial_schema.up.sql
+// Every migration must have both an up & a down file. Versions must be unique but do not need to be contiguous
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileNameRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Arbitrary key used to prevent migrations from being run concurrently
+const migrationLockKey = 7264917350
+
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// Returns all migrations sorted by ascending version
+func loadMigrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsByVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		matches := migrationFileNameRegex.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(matches[1])
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := migrationsByVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrationsByVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == "up" {
+			migration.up = string(contents)
+		} else {
+			migration.down = string(contents)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range migrationsByVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Returns the schema version that the code expects, i.e. the version of the latest migration
+func ExpectedSchemaVersion() (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Returns the version of the latest migration applied to the database, or 0 if no migrations have been applied
+func GetSchemaVersion(db *sql.DB) (int, error) {
+	var exists bool
+	err := db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Applies up to steps pending migrations in ascending order (all of them if steps is 0) & returns the migrations that were applied
+// db must belong to a user that is allowed to modify the schema. Each migration is applied in its own transaction
+func MigrateUp(db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate(db, migrations, steps, true)
+}
+
+// Reverts up to steps applied migrations in descending order (all of them if steps is 0) & returns the migrations that were reverted
+func MigrateDown(db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reverse the order so that the latest migration is reverted first
+	for i, j := 0, len(migrations)-1; i < j; i, j = i+1, j-1 {
+		migrations[i], migrations[j] = migrations[j], migrations[i]
+	}
+
+	return migrate(db, migrations, steps, false)
+}
+
+func migrate(db *sql.DB, migrations []Migration, steps int, isUp bool) ([]Migration, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY NOT NULL,
+			name VARCHAR(300) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	if err != nil {
+		return nil, err
+	}
+
+	completed := []Migration{}
+	for _, migration := range migrations {
+		if steps > 0 && len(completed) == steps {
+			break
+		}
+
+		ok, err := runMigration(db, migration, isUp)
+		if err != nil {
+			return completed, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if ok {
+			completed = append(completed, migration)
+		}
+	}
+
+	return completed, nil
+}
+
+// Applies or reverts a single migration. Returns false if there was nothing to do (i.e. it was already applied/reverted)
+func runMigration(db *sql.DB, migration Migration, isUp bool) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// The lock is held until the transaction ends, so other instances wait & then see the updated schema_migrations table
+	_, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationLockKey)
+	if err != nil {
+		return false, err
+	}
+
+	var isApplied bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", migration.Version).Scan(&isApplied)
+	if err != nil {
+		return false, err
+	}
+	if isApplied == isUp {
+		return false, nil
+	}
+
+	if isUp {
+		if _, err := tx.Exec(migration.up); err != nil {
+			return false, err
+		}
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+	} else {
+		if _, err := tx.Exec(migration.down); err != nil {
+			return false, err
+		}
+		_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("Migrations could not be loaded: %s", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("There should be at least 1 migration")
+	}
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i-1].Version >= migrations[i].Version {
+			t.Errorf("Migrations should be sorted by ascending version, got %d before %d", migrations[i-1].Version, migrations[i].Version)
+		}
+	}
+}
+
+func (s *IntegrationTestSuite) TestMigrateDownAndUp() {
+	expectedVersion, err := ExpectedSchemaVersion()
+	s.Equal(nil, err)
+
+	version, err := GetSchemaVersion(s.dbRootConn)
+	s.Equal(nil, err)
+	s.Equal(expectedVersion, version, "The schema should be up to date")
+
+	// Revert every migration
+	reverted, err := MigrateDown(s.dbRootConn, 0)
+	s.Equal(nil, err)
+	s.NotEqual(0, len(reverted), "Migrations should have been reverted")
+
+	version, err = GetSchemaVersion(s.dbRootConn)
+	s.Equal(nil, err)
+	s.Equal(0, version, "No migrations should be applied")
+	s.expectTableToNotExist("tenant")
+
+	// Re-apply every migration
+	applied, err := MigrateUp(s.dbRootConn, 0)
+	s.Equal(nil, err)
+	s.Equal(len(reverted), len(applied), "Every reverted migration should have been re-applied")
+
+	version, err = GetSchemaVersion(s.dbRootConn)
+	s.Equal(nil, err)
+	s.Equal(expectedVersion, version, "The schema should be up to date")
+
+	// Applying the migrations again should do nothing
+	applied, err = MigrateUp(s.dbRootConn, 0)
+	s.Equal(nil, err)
+	s.Equal(0, len(applied), "No migrations should be pending")
+}
+
+func (s *IntegrationTestSuite) expectTableToNotExist(table string) {
+	var exists bool
+	err := s.dbRootConn.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	s.Equal(nil, err)
+	s.False(exists, "Table %s should not exist", table)
+}
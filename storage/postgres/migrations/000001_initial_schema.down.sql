@@ -0,0 +1,15 @@
+-- Tables are dropped in the reverse order of their creation so that foreign keys are dropped before the tables they reference
+DROP TABLE IF EXISTS casbin_rule;
+DROP TABLE IF EXISTS job_application;
+DROP TYPE IF EXISTS ACCEPT_STATUS;
+DROP TYPE IF EXISTS OFFER_STATUS;
+DROP TYPE IF EXISTS SHORTLIST_STATUS;
+DROP TABLE IF EXISTS job_requisition;
+DROP TYPE IF EXISTS APPROVAL_STATUS;
+DROP TABLE IF EXISTS subordinate_supervisor_relationship;
+DROP TABLE IF EXISTS position_assignment;
+DROP TABLE IF EXISTS position;
+DROP TABLE IF EXISTS user_account;
+DROP TABLE IF EXISTS department;
+DROP TABLE IF EXISTS division;
+DROP TABLE IF EXISTS tenant;
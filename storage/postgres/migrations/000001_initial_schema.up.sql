@@ -0,0 +1,209 @@
+-- Import necessary extensions
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Instantiate the Tables
+CREATE TABLE IF NOT EXISTS tenant (
+    id UUID PRIMARY KEY NOT NULL,
+    name VARCHAR(300) UNIQUE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS division (
+    id UUID PRIMARY KEY NOT NULL,    
+    tenant_id UUID NOT NULL,
+    name VARCHAR(300) NOT NULL,    
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (tenant_id, name),
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id)
+);
+
+CREATE TABLE IF NOT EXISTS department (
+    id UUID PRIMARY KEY NOT NULL,    
+    tenant_id UUID NOT NULL,    
+    division_id UUID NOT NULL,
+    name VARCHAR(300) NOT NULL,    
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),  
+
+    UNIQUE (division_id, name),
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    FOREIGN KEY (division_id) REFERENCES division(id)
+);
+
+CREATE TABLE IF NOT EXISTS user_account (
+    id UUID PRIMARY KEY NOT NULL, -- ID used as PK to enable changes to email
+    tenant_id UUID NOT NULL,    
+    email VARCHAR(300) NOT NULL,
+    password TEXT NOT NULL,
+    totp_secret_key CHAR(32) NOT NULL, --TOTP key is recommended to have 160 bits, which is 32 base32 characters
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_login TIMESTAMPTZ,
+
+    UNIQUE(tenant_id, email),
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id)
+);
+
+CREATE TABLE IF NOT EXISTS position (
+    id UUID PRIMARY KEY NOT NULL,  
+    tenant_id UUID NOT NULL,      
+    title VARCHAR(300) NOT NULL,
+    department_id UUID NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),    
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),    
+    FOREIGN KEY (department_id) REFERENCES department(id) -- Every position must correspond to a department
+);
+
+CREATE TABLE IF NOT EXISTS position_assignment (
+    tenant_id UUID NOT NULL,      
+    position_id UUID NOT NULL,
+    user_account_id UUID NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL DEFAULT'9999-12-31',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),    
+
+    PRIMARY KEY (position_id, user_account_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),    
+    FOREIGN KEY (position_id) REFERENCES position(id),
+    FOREIGN KEY (user_account_id) REFERENCES user_account(id)
+);
+
+CREATE TABLE IF NOT EXISTS subordinate_supervisor_relationship (
+    subordinate_position_id UUID NOT NULL,
+    supervisor_position_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),      
+
+    PRIMARY KEY (subordinate_position_id, supervisor_position_id),
+    FOREIGN KEY (subordinate_position_id) REFERENCES position(id),
+    FOREIGN KEY (supervisor_position_id) REFERENCES position(id),
+
+    CHECK (subordinate_position_id <> supervisor_position_id)
+);
+
+CREATE TYPE APPROVAL_STATUS AS ENUM (
+    'PENDING',
+    'APPROVED',
+    'REJECTED'
+);
+
+CREATE TABLE IF NOT EXISTS job_requisition(
+    id UUID PRIMARY KEY NOT NULL,
+    tenant_id UUID NOT NULL,
+    position_id UUID, -- Null if the job requisition is for a new position. Only filled in after the new position has been created
+    title VARCHAR(300), -- Null if the job requisition is for an existing position
+    department_id UUID, -- Null if the job requisition is for an existing position
+    supervisor_position_ids UUID[], -- Null if the job requisition is for an existing position
+    job_description TEXT NOT NULL,
+    job_requirements TEXT NOT NULL,
+    requestor UUID NOT NULL, 
+    supervisor UUID NOT NULL, 
+    supervisor_decision APPROVAL_STATUS NOT NULL DEFAULT 'PENDING',
+    hr_approver UUID NOT NULL,
+    hr_approver_decision APPROVAL_STATUS NOT NULL DEFAULT 'PENDING',    
+    recruiter UUID,
+    filled_by UUID, 
+    filled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    FOREIGN KEY (department_id) REFERENCES department(id),
+    FOREIGN KEY (position_id) REFERENCES position(id),
+    FOREIGN KEY (requestor) REFERENCES user_account(id),
+    FOREIGN KEY (supervisor) REFERENCES user_account(id),
+    FOREIGN KEY (hr_approver) REFERENCES user_account(id),    
+    FOREIGN KEY (recruiter) REFERENCES user_account(id),      
+    FOREIGN KEY (filled_by) REFERENCES user_account(id),    
+
+    -- Prevents hr from approving if supervisor has not approved/has rejected
+    CONSTRAINT ck_hr_approval_only_with_supervisor_approval 
+        CHECK ( NOT (supervisor_decision <> 'APPROVED' AND hr_approver_decision = 'APPROVED')),
+    -- Ensures that recruiter is provided if HR has approved    
+    CONSTRAINT ck_recruiter_assignment_made_if_have_hr_approval
+        CHECK ( NOT (hr_approver_decision = 'APPROVED' AND recruiter IS NULL) ),      
+    -- Prevents job aquisition from being filled if hr has not approved    
+    CONSTRAINT ck_req_filled_only_with_hr_approval 
+        CHECK ( NOT (hr_approver_decision <> 'APPROVED' AND filled_by IS NOT NULL) ),      
+    CONSTRAINT ck_req_filled_at_only_with_hr_approval 
+        CHECK ( NOT (hr_approver_decision <> 'APPROVED' AND filled_at IS NOT NULL) )         
+);
+
+CREATE TYPE SHORTLIST_STATUS AS ENUM (
+    'PENDING',
+    'SHORTLISTED',
+    'REJECTED'
+);
+
+CREATE TYPE OFFER_STATUS AS ENUM (
+    'PENDING',
+    'OFFERED',
+    'REJECTED',
+    'RESCINDED'
+);
+
+CREATE TYPE ACCEPT_STATUS AS ENUM (
+    'PENDING',
+    'ACCEPTED',
+    'REJECTED',
+    'RESCINDED'
+);
+
+CREATE TABLE IF NOT EXISTS job_application (
+    id UUID PRIMARY KEY NOT NULL,
+    tenant_id UUID NOT NULL,
+    job_requisition_id UUID NOT NULL,    
+    first_name VARCHAR(300) NOT NULL,
+    last_name VARCHAR(300) NOT NULL,
+    country_code INTEGER NOT NULL,
+    phone_number INTEGER NOT NULL,
+    email VARCHAR(300) NOT NULL,
+    resume_s3_url TEXT NOT NULL, 
+    recruiter_decision SHORTLIST_STATUS NOT NULL DEFAULT 'PENDING',
+    interview_date DATE,
+    hiring_manager_decision OFFER_STATUS NOT NULL DEFAULT 'PENDING',
+    offer_start_date DATE,
+    offer_end_date DATE,    
+    applicant_decision ACCEPT_STATUS NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    UNIQUE (email, job_requisition_id),
+    UNIQUE (country_code, phone_number, job_requisition_id),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    FOREIGN KEY (job_requisition_id) REFERENCES job_requisition(id),
+
+    -- Prevents the interview date from being set without recruiter shortlisting
+    CONSTRAINT ck_recruiter_shortlist_before_setting_interview_date
+        CHECK ( NOT (recruiter_decision <> 'SHORTLISTED' AND interview_date IS NOT NULL)),
+    -- Prevents the supervisor from making an offer before the interview date has been set
+    CONSTRAINT ck_interview_date_set_before_hiring_manager_offer
+        CHECK ( NOT ( interview_date IS NULL AND hiring_manager_decision = 'OFFERED')),   
+    -- Prevents the applicant from accepting the offer before the hiring manager has made the offer
+    CONSTRAINT ck_hiring_manager_offer_before_applicant_acceptance
+        CHECK ( NOT ( hiring_manager_decision <> 'OFFERED' AND hiring_manager_decision <> 'RESCINDED' AND applicant_decision = 'ACCEPTED'))
+);
+
+-- Authorization Rule table
+CREATE TABLE IF NOT EXISTS casbin_rule (
+    ID UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    Ptype VARCHAR(300) CHECK (Ptype IN ('p', 'g')),
+    V0 VARCHAR(300),
+    V1 VARCHAR(300),
+    V2 VARCHAR(300),
+    V3 VARCHAR(300),
+    V4 VARCHAR(300),
+    V5 VARCHAR(300),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),        
+
+    UNIQUE NULLS NOT DISTINCT (Ptype, V0, V1, V2, V3, V4, V5)
+);
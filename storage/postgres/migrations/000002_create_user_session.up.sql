@@ -0,0 +1,14 @@
+-- Authenticated sessions. The session data is gob-encoded by the session store
+-- tenant_id & user_account_id are copied out of the session data so that sessions can be looked up by user
+CREATE TABLE IF NOT EXISTS user_session (
+    id VARCHAR(100) PRIMARY KEY NOT NULL,
+    tenant_id VARCHAR(300),
+    user_account_id VARCHAR(300),
+    data BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS ix_user_session_tenant_id_user_account_id ON user_session (tenant_id, user_account_id);
+CREATE INDEX IF NOT EXISTS ix_user_session_expires_at ON user_session (expires_at);
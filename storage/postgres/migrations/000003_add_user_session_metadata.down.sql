@@ -0,0 +1,4 @@
+ALTER TABLE user_session
+    DROP COLUMN IF EXISTS public_id,
+    DROP COLUMN IF EXISTS client_ip,
+    DROP COLUMN IF EXISTS user_agent;
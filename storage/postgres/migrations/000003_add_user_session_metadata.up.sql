@@ -0,0 +1,5 @@
+-- The session id doubles as the session's secret, so a separate public id is used to refer to sessions in the API
+ALTER TABLE user_session
+    ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    ADD COLUMN IF NOT EXISTS client_ip VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS user_agent TEXT;
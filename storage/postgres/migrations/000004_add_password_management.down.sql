@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS password_reset_token;
+ALTER TABLE user_account DROP COLUMN IF EXISTS must_change_password;
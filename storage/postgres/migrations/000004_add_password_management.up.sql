@@ -0,0 +1,18 @@
+-- Set for accounts that are still using the default password generated on account creation
+ALTER TABLE user_account ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- One-time tokens issued by admins so that users can reset their password
+-- Only a hash of the token is stored, so a leaked table cannot be used to reset passwords
+CREATE TABLE IF NOT EXISTS password_reset_token (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL,
+    user_account_id UUID NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE, -- Hex encoded SHA-256 hash
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    FOREIGN KEY (user_account_id) REFERENCES user_account(id)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE user_account DROP COLUMN IF EXISTS totp_enrollment_token_expires_at;
+ALTER TABLE user_account DROP COLUMN IF EXISTS totp_enrollment_token_hash;
+ALTER TABLE user_account DROP COLUMN IF EXISTS totp_pending_secret_key;
+
+-- Fails if any user is pending enrollment, as they do not have a TOTP secret key
+ALTER TABLE user_account ALTER COLUMN totp_secret_key SET NOT NULL;
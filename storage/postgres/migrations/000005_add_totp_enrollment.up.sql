@@ -0,0 +1,9 @@
+-- A NULL totp_secret_key means that the user has yet to enroll (or has been reset by an admin & must re-enroll)
+ALTER TABLE user_account ALTER COLUMN totp_secret_key DROP NOT NULL;
+
+-- Secret shown to the user during enrollment. It only replaces totp_secret_key once the user confirms it with a valid code
+ALTER TABLE user_account ADD COLUMN IF NOT EXISTS totp_pending_secret_key CHAR(32);
+
+-- Set when an admin resets the user's TOTP. The user must provide the token to enroll again. Only its SHA-256 hash is stored
+ALTER TABLE user_account ADD COLUMN IF NOT EXISTS totp_enrollment_token_hash CHAR(64);
+ALTER TABLE user_account ADD COLUMN IF NOT EXISTS totp_enrollment_token_expires_at TIMESTAMPTZ;
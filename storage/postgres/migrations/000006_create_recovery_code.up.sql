@@ -0,0 +1,18 @@
+-- Single-use codes that can be used in place of a TOTP, e.g. if the user has lost their authenticator
+-- Codes are hashed with argon2id as they are effectively passwords. The lookup hash (the first 16 bits of the code's SHA-256)
+-- finds the code being used, so that only 1 argon2id hash has to be compared
+CREATE TABLE IF NOT EXISTS recovery_code (
+    id UUID PRIMARY KEY NOT NULL DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL,
+    user_account_id UUID NOT NULL,
+    lookup_hash CHAR(4) NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    FOREIGN KEY (user_account_id) REFERENCES user_account(id)
+);
+
+CREATE INDEX IF NOT EXISTS recovery_code_user_account_id_idx ON recovery_code (user_account_id);
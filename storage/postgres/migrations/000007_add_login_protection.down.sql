@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS login_attempt;
+
+ALTER TABLE user_account DROP COLUMN IF EXISTS totp_last_used_step;
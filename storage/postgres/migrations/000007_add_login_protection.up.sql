@@ -0,0 +1,16 @@
+-- The time step (unix time / 30s period) of the last TOTP that the user used. TOTPs of the same or earlier steps are rejected so that a code cannot be replayed
+ALTER TABLE user_account ADD COLUMN IF NOT EXISTS totp_last_used_step BIGINT;
+
+-- Failed logins are tracked per tenant & email instead of per user so that attempts on emails which do not exist are also limited
+-- As such, there are no foreign keys
+CREATE TABLE IF NOT EXISTS login_attempt (
+    tenant_id UUID NOT NULL,
+    email VARCHAR(300) NOT NULL,
+    failed_count INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMPTZ,
+    last_failed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (tenant_id, email)
+);
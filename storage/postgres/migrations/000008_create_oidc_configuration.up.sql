@@ -0,0 +1,14 @@
+-- Each tenant can sign its users in with its own OpenID Connect identity provider
+-- The client secret is stored as is, because it must be sent to the identity provider
+CREATE TABLE IF NOT EXISTS oidc_configuration (
+    tenant_id UUID PRIMARY KEY NOT NULL,
+    issuer TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    client_secret TEXT NOT NULL,
+    redirect_url TEXT NOT NULL,
+    email_claim VARCHAR(100) NOT NULL DEFAULT 'email', -- The ID token claim that is matched against user_account.email
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id)
+);
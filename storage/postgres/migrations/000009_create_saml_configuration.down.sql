@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS saml_request;
+DROP TABLE IF EXISTS saml_configuration;
@@ -0,0 +1,23 @@
+-- Each tenant can sign its users in with its own SAML 2.0 identity provider
+CREATE TABLE IF NOT EXISTS saml_configuration (
+    tenant_id UUID PRIMARY KEY NOT NULL,
+    entity_id TEXT NOT NULL, -- The service provider's entity id, which the identity provider sends assertions to
+    acs_url TEXT NOT NULL,
+    idp_metadata TEXT NOT NULL, -- The identity provider's metadata XML, which contains its signing certificates
+    email_attribute VARCHAR(300) NOT NULL DEFAULT '', -- The assertion attribute matched against user_account.email. The NameID is used if it is empty
+    jit_provisioning BOOLEAN NOT NULL DEFAULT FALSE, -- Whether users that do not exist are created when they first sign in
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id)
+);
+
+-- Outstanding authentication requests. Responses must be to one of these requests & each request can only be used once
+CREATE TABLE IF NOT EXISTS saml_request (
+    id TEXT PRIMARY KEY NOT NULL,
+    tenant_id UUID NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id)
+);
@@ -0,0 +1,32 @@
+-- Non-human principals (e.g. integrations) that authenticate with API tokens instead of sessions
+-- Their ids are used as casbin subjects, so they are granted policies & roles in the same way as users
+CREATE TABLE IF NOT EXISTS service_account (
+    id UUID PRIMARY KEY NOT NULL,
+    tenant_id UUID NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    UNIQUE (tenant_id, name)
+);
+
+-- Bearer tokens issued to either a user (i.e. a personal token) or a service account
+-- Only a hash of the token is stored, so a leaked table cannot be used to authenticate
+CREATE TABLE IF NOT EXISTS api_token (
+    id UUID PRIMARY KEY NOT NULL,
+    tenant_id UUID NOT NULL,
+    user_account_id UUID,
+    service_account_id UUID,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE, -- Hex encoded SHA-256 hash
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ, -- Tokens without an expiry are valid until they are revoked
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+
+    FOREIGN KEY (tenant_id) REFERENCES tenant(id),
+    FOREIGN KEY (user_account_id) REFERENCES user_account(id),
+    FOREIGN KEY (service_account_id) REFERENCES service_account(id),
+    CHECK ((user_account_id IS NULL) <> (service_account_id IS NULL)) -- Each token belongs to exactly one principal
+);
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS casbin_rule_changed ON casbin_rule;
+DROP FUNCTION IF EXISTS notify_casbin_rule_change();
@@ -0,0 +1,12 @@
+-- Notifies every listening instance when the policies or role assignments change, so that each can reload its enforcer
+-- The trigger fires once per statement rather than per row, as a reload picks up every row that the statement changed
+CREATE OR REPLACE FUNCTION notify_casbin_rule_change() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('casbin_rule_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER casbin_rule_changed
+    AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON casbin_rule
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_casbin_rule_change();
@@ -0,0 +1,7 @@
+DROP VIEW IF EXISTS active_casbin_rule;
+DROP INDEX IF EXISTS casbin_rule_valid_from_idx;
+DROP INDEX IF EXISTS casbin_rule_valid_until_idx;
+ALTER TABLE casbin_rule
+    DROP CONSTRAINT IF EXISTS casbin_rule_validity_check,
+    DROP COLUMN IF EXISTS valid_until,
+    DROP COLUMN IF EXISTS valid_from;
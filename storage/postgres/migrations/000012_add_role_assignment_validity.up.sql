@@ -0,0 +1,19 @@
+-- Role assignments (i.e. g rules) can start & end at a given time, e.g. for contractors & acting managers
+-- NULL means that the assignment applies immediately or does not expire
+ALTER TABLE casbin_rule
+    ADD COLUMN valid_from TIMESTAMPTZ,
+    ADD COLUMN valid_until TIMESTAMPTZ,
+    ADD CONSTRAINT casbin_rule_validity_check CHECK (
+        (Ptype = 'g' OR (valid_from IS NULL AND valid_until IS NULL)) -- Policies (i.e. p rules) always apply
+        AND (valid_from IS NULL OR valid_until IS NULL OR valid_from < valid_until)
+    );
+
+-- The casbin adapter loads the policy from this view, so that the enforcer ignores role assignments outside of their validity
+-- The enforcer is reloaded when an assignment becomes valid & when an expired assignment is deleted
+CREATE VIEW active_casbin_rule AS
+    SELECT ID, Ptype, V0, V1, V2, V3, V4, V5
+    FROM casbin_rule
+    WHERE (valid_from IS NULL OR valid_from <= now()) AND (valid_until IS NULL OR valid_until > now());
+
+CREATE INDEX casbin_rule_valid_until_idx ON casbin_rule (valid_until) WHERE valid_until IS NOT NULL;
+CREATE INDEX casbin_rule_valid_from_idx ON casbin_rule (valid_from) WHERE valid_from IS NOT NULL;
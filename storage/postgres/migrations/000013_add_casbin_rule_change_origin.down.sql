@@ -0,0 +1,6 @@
+CREATE OR REPLACE FUNCTION notify_casbin_rule_change() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('casbin_rule_changed', TG_OP);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,8 @@
+-- The notification also carries the application name of the connection that changed casbin_rule, so that an instance can
+-- tell its own changes apart. Each instance has already reloaded its enforcer after its own changes
+CREATE OR REPLACE FUNCTION notify_casbin_rule_change() RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('casbin_rule_changed', TG_OP || ' ' || current_setting('application_name'));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
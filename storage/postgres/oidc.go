@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Creates the tenant's OIDC configuration or replaces the existing one
+func (postgres *postgresStorage) SetOidcConfiguration(config storage.OidcConfiguration) error {
+	// All queries must be conditional on the tenantId
+	if config.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		INSERT INTO oidc_configuration (tenant_id, issuer, client_id, client_secret, redirect_url, email_claim)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET issuer = EXCLUDED.issuer, client_id = EXCLUDED.client_id, client_secret = EXCLUDED.client_secret,
+			redirect_url = EXCLUDED.redirect_url, email_claim = EXCLUDED.email_claim, updated_at = now()`
+	_, err := postgres.db.Exec(query, config.TenantId, config.Issuer, config.ClientId, config.ClientSecret, config.RedirectUrl, config.EmailClaim)
+
+	if pgErr, ok := err.(*pq.Error); ok {
+		// 23503 corresponds to the Invalid Foreign Key error
+		if pgErr.Code == "23503" {
+			return NewInvalidForeignKeyError(pgErr)
+		} else {
+			return httperror.NewInternalServerError(pgErr)
+		}
+	} else if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+func (postgres *postgresStorage) GetOidcConfiguration(tenantId string) (storage.OidcConfiguration, error) {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return storage.OidcConfiguration{}, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		SELECT tenant_id, issuer, client_id, client_secret, redirect_url, email_claim, created_at, updated_at
+		FROM oidc_configuration WHERE tenant_id = $1`
+
+	var config storage.OidcConfiguration
+	err := postgres.db.QueryRow(query, tenantId).Scan(
+		&config.TenantId,
+		&config.Issuer,
+		&config.ClientId,
+		&config.ClientSecret,
+		&config.RedirectUrl,
+		&config.EmailClaim,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.OidcConfiguration{}, New404NotFoundError("OIDC configuration")
+	}
+	if err != nil {
+		return storage.OidcConfiguration{}, httperror.NewInternalServerError(err)
+	}
+
+	return config, nil
+}
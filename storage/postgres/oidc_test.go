@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+func (s *IntegrationTestSuite) TestSetOidcConfiguration() {
+	want := storage.OidcConfiguration{
+		TenantId:     s.defaultTenant.Id,
+		Issuer:       "https://idp.example.com",
+		ClientId:     "hris",
+		ClientSecret: "secret",
+		RedirectUrl:  "https://hris.example.com/api/tenants/" + s.defaultTenant.Id + "/sso/oidc/callback",
+		EmailClaim:   "email",
+	}
+
+	err := s.postgres.SetOidcConfiguration(want)
+	s.Equal(nil, err)
+
+	// The existing configuration is replaced
+	want.ClientSecret = "rotated-secret"
+	want.EmailClaim = "upn"
+	err = s.postgres.SetOidcConfiguration(want)
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetOidcConfiguration(s.defaultTenant.Id)
+	s.Equal(nil, err)
+	s.Equal(want.Issuer, got.Issuer)
+	s.Equal(want.ClientId, got.ClientId)
+	s.Equal(want.ClientSecret, got.ClientSecret)
+	s.Equal(want.RedirectUrl, got.RedirectUrl)
+	s.Equal(want.EmailClaim, got.EmailClaim)
+}
+
+func (s *IntegrationTestSuite) TestSetOidcConfigurationShouldValidateTenant() {
+	err := s.postgres.SetOidcConfiguration(storage.OidcConfiguration{
+		TenantId:     "a9f998c6-ba2e-4359-b308-e56404534974",
+		Issuer:       "https://idp.example.com",
+		ClientId:     "hris",
+		ClientSecret: "secret",
+		RedirectUrl:  "https://hris.example.com/callback",
+		EmailClaim:   "email",
+	})
+	s.expectErrorCode(err, "INVALID-FOREIGN-KEY-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestGetOidcConfigurationShouldValidateExistence() {
+	_, err := s.postgres.GetOidcConfiguration(s.defaultTenant.Id)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
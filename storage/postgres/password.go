@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Sets the user's password & clears the must_change_password flag
+func (postgres *postgresStorage) ChangePassword(userId string, tenantId string, passwordHash string) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		UPDATE user_account SET password = $1, must_change_password = FALSE, updated_at = now()
+		WHERE id = $2 AND tenant_id = $3`
+	result, err := postgres.db.Exec(query, passwordHash, userId, tenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("user")
+	}
+
+	return nil
+}
+
+// Replaces any unused reset token of the user, so that only the latest token issued can be redeemed
+func (postgres *postgresStorage) CreatePasswordResetToken(token storage.PasswordResetToken) error {
+	// All queries must be conditional on the tenantId
+	if token.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
+	query := "DELETE FROM password_reset_token WHERE tenant_id = $1 AND user_account_id = $2 AND used_at IS NULL"
+	_, err = tx.Exec(query, token.TenantId, token.UserId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	query = `
+		INSERT INTO password_reset_token (tenant_id, user_account_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`
+	_, err = tx.Exec(query, token.TenantId, token.UserId, token.TokenHash, token.ExpiresAt)
+	if pgErr, ok := err.(*pq.Error); ok {
+		switch pgErr.Code {
+		case "23505":
+			// Unique Violation
+			return NewUniqueViolationError("password reset token", pgErr)
+		case "23503":
+			// Foreign Key Violation
+			return NewInvalidForeignKeyError(pgErr)
+		default:
+			return httperror.NewInternalServerError(pgErr)
+		}
+	} else if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// Returns whether the token belongs to the user, and has not been used or expired. The token is not used up
+func (postgres *postgresStorage) IsPasswordResetTokenValid(token storage.PasswordResetToken) (bool, error) {
+	// All queries must be conditional on the tenantId
+	if token.TenantId == "" {
+		return false, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM password_reset_token
+			WHERE token_hash = $1 AND tenant_id = $2 AND user_account_id = $3 AND used_at IS NULL AND expires_at > now()
+		)`
+	var valid bool
+	err := postgres.db.QueryRow(query, token.TokenHash, token.TenantId, token.UserId).Scan(&valid)
+	if err != nil {
+		return false, httperror.NewInternalServerError(err)
+	}
+
+	return valid, nil
+}
+
+// Marks the token as used & sets the user's new password
+// The token must belong to the user, and must not have been used or have expired
+func (postgres *postgresStorage) RedeemPasswordResetToken(token storage.PasswordResetToken, passwordHash string) error {
+	// All queries must be conditional on the tenantId
+	if token.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
+	query := `
+		UPDATE password_reset_token SET used_at = now(), updated_at = now()
+		WHERE token_hash = $1 AND tenant_id = $2 AND user_account_id = $3 AND used_at IS NULL AND expires_at > now()`
+	result, err := tx.Exec(query, token.TokenHash, token.TenantId, token.UserId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrInvalidPasswordResetToken
+	}
+
+	query = `
+		UPDATE user_account SET password = $1, must_change_password = FALSE, updated_at = now()
+		WHERE id = $2 AND tenant_id = $3`
+	_, err = tx.Exec(query, passwordHash, token.UserId, token.TenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
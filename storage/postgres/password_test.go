@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"time"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+func (s *IntegrationTestSuite) TestChangePassword() {
+	_, err := s.dbRootConn.Exec("UPDATE user_account SET must_change_password = TRUE WHERE id = $1", s.defaultUser.Id)
+	s.Equal(nil, err)
+
+	err = s.postgres.ChangePassword(s.defaultUser.Id, s.defaultUser.TenantId, "new-hash")
+	s.Equal(nil, err)
+
+	s.expectSelectQueryToReturnOneRow(
+		"user_account",
+		map[string]any{
+			"id":                   s.defaultUser.Id,
+			"password":             "new-hash",
+			"must_change_password": false,
+		},
+	)
+
+	// Passwords cannot be changed across tenants
+	err = s.postgres.ChangePassword(s.defaultUser.Id, "a9f998c6-ba2e-4359-b308-e56404534974", "other-hash")
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestRedeemPasswordResetToken() {
+	token := storage.PasswordResetToken{
+		TenantId:  s.defaultUser.TenantId,
+		UserId:    s.defaultUser.Id,
+		TokenHash: "0f4c8a3cf7f2e5c1b0f6a7d9e3b2c1a0f4c8a3cf7f2e5c1b0f6a7d9e3b2c1a0f",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	err := s.postgres.CreatePasswordResetToken(token)
+	s.Equal(nil, err)
+
+	// Checking the token does not use it up
+	valid, err := s.postgres.IsPasswordResetTokenValid(token)
+	s.Equal(nil, err)
+	s.Equal(true, valid)
+
+	// Tokens cannot be used across tenants
+	otherTenantToken := token
+	otherTenantToken.TenantId = "a9f998c6-ba2e-4359-b308-e56404534974"
+	valid, err = s.postgres.IsPasswordResetTokenValid(otherTenantToken)
+	s.Equal(nil, err)
+	s.Equal(false, valid)
+
+	err = s.postgres.RedeemPasswordResetToken(token, "new-hash")
+	s.Equal(nil, err)
+
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultUser.Id, "password": "new-hash"})
+
+	// A token can only be redeemed once
+	valid, err = s.postgres.IsPasswordResetTokenValid(token)
+	s.Equal(nil, err)
+	s.Equal(false, valid)
+	err = s.postgres.RedeemPasswordResetToken(token, "other-hash")
+	s.expectErrorCode(err, "INVALID-PASSWORD-RESET-TOKEN-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestCreatePasswordResetTokenShouldReplaceUnusedTokens() {
+	firstToken := storage.PasswordResetToken{
+		TenantId:  s.defaultUser.TenantId,
+		UserId:    s.defaultUser.Id,
+		TokenHash: "1111111111111111111111111111111111111111111111111111111111111111",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	secondToken := firstToken
+	secondToken.TokenHash = "2222222222222222222222222222222222222222222222222222222222222222"
+
+	err := s.postgres.CreatePasswordResetToken(firstToken)
+	s.Equal(nil, err)
+	err = s.postgres.CreatePasswordResetToken(secondToken)
+	s.Equal(nil, err)
+
+	s.expectSelectQueryToReturnNoRows("password_reset_token", map[string]any{"token_hash": firstToken.TokenHash})
+	s.expectSelectQueryToReturnOneRow("password_reset_token", map[string]any{"token_hash": secondToken.TokenHash})
+
+	err = s.postgres.RedeemPasswordResetToken(firstToken, "new-hash")
+	s.expectErrorCode(err, "INVALID-PASSWORD-RESET-TOKEN-ERROR")
+}
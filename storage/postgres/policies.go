@@ -1,8 +1,11 @@
 package postgres
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"multi-tenant-HR-information-system-backend/httperror"
 	"multi-tenant-HR-information-system-backend/storage"
@@ -37,9 +40,24 @@ func (postgres *postgresStorage) CreatePolicies(policies storage.Policies) error
 	return nil
 }
 
+// Inserts the policies in the transaction, e.g. the policies that a user is given when they take on a workflow role
+// Policies that already exist are skipped, so that a user can take on the same role more than once
+func insertPolicies(tx *sql.Tx, policies storage.Policies) error {
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2, V3) VALUES ('p', $1, $2, $3, $4) ON CONFLICT DO NOTHING"
+	for _, resource := range policies.Resources {
+		_, err := tx.Exec(query, policies.Subject, policies.TenantId, resource.Path, resource.Method)
+		if err != nil {
+			return httperror.NewInternalServerError(err)
+		}
+	}
+
+	return nil
+}
+
 func (postgres *postgresStorage) CreateRoleAssignment(roleAssignment storage.RoleAssignment) error {
-	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2) VALUES ('g', $1, $2, $3)"
-	_, err := postgres.db.Exec(query, roleAssignment.UserId, roleAssignment.Role, roleAssignment.TenantId)
+	query := "INSERT INTO casbin_rule (Ptype, V0, V1, V2, valid_from, valid_until) VALUES ('g', $1, $2, $3, $4, $5)"
+	_, err := postgres.db.Exec(query, roleAssignment.UserId, roleAssignment.Role, roleAssignment.TenantId,
+		newNullTime(roleAssignment.ValidFrom), newNullTime(roleAssignment.ValidUntil))
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok {
 			if pgErr.Code == "23505" {
@@ -53,3 +71,398 @@ func (postgres *postgresStorage) CreateRoleAssignment(roleAssignment storage.Rol
 
 	return nil
 }
+
+// Zero times are stored as NULL, e.g. a role assignment without an expiry
+func newNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// Returns the policies of each subject in the tenant, with the subject's resources sorted by path & method
+// Policies of every tenant (e.g. the PUBLIC policies) are not included, as their domain is not the tenant's id
+func (postgres *postgresStorage) GetPolicies(filter storage.Policies) ([]storage.Policies, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newPolicyConditions(filter)
+	query := NewQueryWithFilter(`
+		SELECT V0, V1, array_agg(V2 ORDER BY V2, V3), array_agg(V3 ORDER BY V2, V3), MIN(created_at), MAX(updated_at)
+		FROM casbin_rule`, conditions) + " GROUP BY V0, V1 ORDER BY V0"
+
+	rows, err := postgres.db.Query(query, values...)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	policiesBySubject := []storage.Policies{}
+	for rows.Next() {
+		var policies storage.Policies
+		var paths, methods []string
+		err := rows.Scan(&policies.Subject, &policies.TenantId, pq.Array(&paths), pq.Array(&methods), &policies.CreatedAt, &policies.UpdatedAt)
+		if err != nil {
+			return nil, httperror.NewInternalServerError(err)
+		}
+
+		policies.Resources = []storage.Resource{}
+		for i := range paths {
+			policies.Resources = append(policies.Resources, storage.Resource{Path: paths[i], Method: methods[i]})
+		}
+
+		policiesBySubject = append(policiesBySubject, policies)
+	}
+
+	return policiesBySubject, nil
+}
+
+// Replaces every policy of the subject with the given resources. If a resource is given twice, no policy is replaced
+func (postgres *postgresStorage) ReplacePolicies(policies storage.Policies) error {
+	// All queries must be conditional on the tenantId
+	if policies.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
+	query := "DELETE FROM casbin_rule WHERE Ptype = 'p' AND V0 = $1 AND V1 = $2"
+	_, err = tx.Exec(query, policies.Subject, policies.TenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	query = "INSERT INTO casbin_rule (Ptype, V0, V1, V2, V3) VALUES ('p', $1, $2, $3, $4)"
+	for _, resource := range policies.Resources {
+		_, err = tx.Exec(query, policies.Subject, policies.TenantId, resource.Path, resource.Method)
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return NewUniqueViolationError("policy", pgErr)
+			}
+			return httperror.NewInternalServerError(pgErr)
+		} else if err != nil {
+			return httperror.NewInternalServerError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// Deletes the policies matching the filter. If the filter has resources, only the policies of those resources are deleted
+func (postgres *postgresStorage) DeletePolicies(filter storage.Policies) (int64, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return 0, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newPolicyConditions(filter)
+	query := NewQueryWithFilter("DELETE FROM casbin_rule", conditions)
+
+	result, err := postgres.db.Exec(query, values...)
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	return deleted, nil
+}
+
+// Policies are stored as (p, subject, tenantId, path, method)
+func newPolicyConditions(filter storage.Policies) ([]string, []any) {
+	conditions := []string{"Ptype = 'p'", "V1 = $1"}
+	values := []any{filter.TenantId}
+
+	if filter.Subject != "" {
+		values = append(values, filter.Subject)
+		conditions = append(conditions, fmt.Sprintf("V0 = $%v", len(values)))
+	}
+
+	if len(filter.Resources) > 0 {
+		resourceConditions := []string{}
+		for _, resource := range filter.Resources {
+			values = append(values, resource.Path, resource.Method)
+			resourceConditions = append(resourceConditions, fmt.Sprintf("(V2 = $%v AND V3 = $%v)", len(values)-1, len(values)))
+		}
+		conditions = append(conditions, "("+strings.Join(resourceConditions, " OR ")+")")
+	}
+
+	return conditions, values
+}
+
+func (postgres *postgresStorage) GetRoleAssignments(filter storage.RoleAssignment) ([]storage.RoleAssignment, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newRoleAssignmentConditions(filter)
+	query := NewQueryWithFilter("SELECT V0, V1, V2, valid_from, valid_until, created_at, updated_at FROM casbin_rule", conditions) + " ORDER BY V0, V1"
+
+	rows, err := postgres.db.Query(query, values...)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	return scanRoleAssignments(rows)
+}
+
+// Scans rows of V0, V1, V2, valid_from, valid_until, created_at & updated_at
+func scanRoleAssignments(rows *sql.Rows) ([]storage.RoleAssignment, error) {
+	roleAssignments := []storage.RoleAssignment{}
+	for rows.Next() {
+		var roleAssignment storage.RoleAssignment
+		var validFrom, validUntil sql.NullTime
+		err := rows.Scan(&roleAssignment.UserId, &roleAssignment.Role, &roleAssignment.TenantId, &validFrom, &validUntil, &roleAssignment.CreatedAt, &roleAssignment.UpdatedAt)
+		if err != nil {
+			return nil, httperror.NewInternalServerError(err)
+		}
+		roleAssignment.ValidFrom = validFrom.Time
+		roleAssignment.ValidUntil = validUntil.Time
+
+		roleAssignments = append(roleAssignments, roleAssignment)
+	}
+
+	return roleAssignments, nil
+}
+
+// Replaces every role of the user in the tenant with the given roles. If a role is given twice, no role is replaced
+// Roles that the user already had keep their validity, so that replacing the roles does not extend a temporary role
+func (postgres *postgresStorage) ReplaceRoleAssignments(userId string, tenantId string, roles []string) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
+	query := "DELETE FROM casbin_rule WHERE Ptype = 'g' AND V0 = $1 AND V2 = $2 RETURNING V1, valid_from, valid_until"
+	rows, err := tx.Query(query, userId, tenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	type validity struct {
+		from  sql.NullTime
+		until sql.NullTime
+	}
+	validities := map[string]validity{}
+	for rows.Next() {
+		var role string
+		var roleValidity validity
+		err := rows.Scan(&role, &roleValidity.from, &roleValidity.until)
+		if err != nil {
+			return httperror.NewInternalServerError(err)
+		}
+		validities[role] = roleValidity
+	}
+	if err := rows.Err(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	query = "INSERT INTO casbin_rule (Ptype, V0, V1, V2, valid_from, valid_until) VALUES ('g', $1, $2, $3, $4, $5)"
+	for _, role := range roles {
+		roleValidity := validities[role]
+		_, err = tx.Exec(query, userId, role, tenantId, roleValidity.from, roleValidity.until)
+		if pgErr, ok := err.(*pq.Error); ok {
+			if pgErr.Code == "23505" {
+				return NewUniqueViolationError("role assignment", pgErr)
+			}
+			return httperror.NewInternalServerError(pgErr)
+		} else if err != nil {
+			return httperror.NewInternalServerError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+func (postgres *postgresStorage) DeleteRoleAssignments(filter storage.RoleAssignment) (int64, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return 0, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newRoleAssignmentConditions(filter)
+	query := NewQueryWithFilter("DELETE FROM casbin_rule", conditions)
+
+	result, err := postgres.db.Exec(query, values...)
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	return deleted, nil
+}
+
+// Deletes the role assignments that have expired in every tenant & returns them, so that the caller can log them
+// Every instance's enforcer is reloaded by the policy watcher, as casbin_rule is changed
+func (postgres *postgresStorage) DeleteExpiredRoleAssignments() ([]storage.RoleAssignment, error) {
+	query := `
+		DELETE FROM casbin_rule
+		WHERE Ptype = 'g' AND valid_until <= now()
+		RETURNING V0, V1, V2, valid_from, valid_until, created_at, updated_at`
+
+	rows, err := postgres.db.Query(query)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	return scanRoleAssignments(rows)
+}
+
+// Returns whether a role assignment has become valid after since, along with the database's current time, which should be
+// passed as since to the next call. The database's time is used as it decides which role assignments are loaded
+func (postgres *postgresStorage) HasActivatedRoleAssignments(since time.Time) (activated bool, now time.Time, err error) {
+	query := "SELECT now(), EXISTS (SELECT 1 FROM casbin_rule WHERE Ptype = 'g' AND valid_from > $1 AND valid_from <= now())"
+
+	err = postgres.db.QueryRow(query, since).Scan(&now, &activated)
+	if err != nil {
+		return false, time.Time{}, httperror.NewInternalServerError(err)
+	}
+
+	return activated, now, nil
+}
+
+// Returns how long it is until the next role assignment becomes valid or expires, going by the database's time, and false
+// if no role assignment is due to change
+func (postgres *postgresStorage) TimeUntilNextRoleAssignmentChange() (time.Duration, bool, error) {
+	// LEAST ignores NULLs, so it is only NULL if neither column has a future value
+	query := `
+		SELECT EXTRACT(EPOCH FROM LEAST(
+			MIN(valid_from) FILTER (WHERE valid_from > now()),
+			MIN(valid_until) FILTER (WHERE valid_until > now())
+		) - now())
+		FROM casbin_rule
+		WHERE Ptype = 'g'`
+
+	var seconds sql.NullFloat64
+	err := postgres.db.QueryRow(query).Scan(&seconds)
+	if err != nil {
+		return 0, false, httperror.NewInternalServerError(err)
+	}
+	if !seconds.Valid {
+		return 0, false, nil
+	}
+
+	return time.Duration(seconds.Float64 * float64(time.Second)), true, nil
+}
+
+// Deletes expired role assignments in the background until stop is called. Sweeps are scheduled for when the next role
+// assignment becomes valid or expires, and at least every interval in case scheduling failed
+// Unlike an expiry, a role assignment becoming valid does not change casbin_rule, so the policy watcher is not notified.
+// Instead, onSweep is told that a role assignment was activated, so that the caller can reload its enforcer. The expired
+// role assignments are passed to onSweep even if checking for activated ones failed
+// reschedule should be called whenever role assignments are created or changed, so that the next sweep is not scheduled
+// after their valid_from or valid_until
+func (postgres *postgresStorage) StartRoleAssignmentSweeper(interval time.Duration, onSweep func(expired []storage.RoleAssignment, activated bool, err error)) (reschedule func(), stop func()) {
+	quit := make(chan struct{})
+	// Buffered so that rescheduling does not block during a sweep, and repeated calls are merged
+	rescheduled := make(chan struct{}, 1)
+
+	go func() {
+		timer := time.NewTimer(postgres.nextSweepDelay(interval))
+		defer timer.Stop()
+
+		// The enforcer has already loaded the role assignments that are valid when the sweeper starts, so only the database's
+		// current time is needed
+		_, since, err := postgres.HasActivatedRoleAssignments(time.Now())
+		if err != nil {
+			since = time.Now()
+		}
+
+		for {
+			select {
+			case <-timer.C:
+				expired, err := postgres.DeleteExpiredRoleAssignments()
+				if err != nil {
+					onSweep(nil, false, err)
+					timer.Reset(interval)
+					continue
+				}
+
+				activated, now, err := postgres.HasActivatedRoleAssignments(since)
+				if err == nil {
+					since = now
+				}
+				onSweep(expired, activated, err)
+				timer.Reset(postgres.nextSweepDelay(interval))
+			case <-rescheduled:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(postgres.nextSweepDelay(interval))
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	reschedule = func() {
+		select {
+		case rescheduled <- struct{}{}:
+		default:
+		}
+	}
+	stop = func() {
+		close(quit)
+	}
+	return reschedule, stop
+}
+
+// Returns the time until the next role assignment changes, up to interval
+// Errors are not returned, as the sweep after interval reports them
+func (postgres *postgresStorage) nextSweepDelay(interval time.Duration) time.Duration {
+	untilChange, ok, err := postgres.TimeUntilNextRoleAssignmentChange()
+	if err != nil || !ok || untilChange > interval {
+		return interval
+	}
+
+	return untilChange
+}
+
+// Role assignments are stored as (g, userId, role, tenantId)
+func newRoleAssignmentConditions(filter storage.RoleAssignment) ([]string, []any) {
+	conditions := []string{"Ptype = 'g'", "V2 = $1"}
+	values := []any{filter.TenantId}
+
+	if filter.UserId != "" {
+		values = append(values, filter.UserId)
+		conditions = append(conditions, fmt.Sprintf("V0 = $%v", len(values)))
+	}
+
+	if filter.Role != "" {
+		values = append(values, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("V1 = $%v", len(values)))
+	}
+
+	return conditions, values
+}
@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"time"
+
 	"multi-tenant-HR-information-system-backend/storage"
 )
 
@@ -194,3 +196,229 @@ func (s *IntegrationTestSuite) TestCreatePoliciesDoesNotViolateUniqueConstraint(
 		})
 	}
 }
+
+func (s *IntegrationTestSuite) TestGetPolicies() {
+	got, err := s.postgres.GetPolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Require().Len(got, 1)
+	s.Equal(s.defaultPolicies.Subject, got[0].Subject)
+	s.ElementsMatch(s.defaultPolicies.Resources, got[0].Resources)
+
+	// The PUBLIC policies apply to every tenant, so they are not the tenant's policies
+	got, err = s.postgres.GetPolicies(storage.Policies{Subject: "PUBLIC", TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Empty(got)
+
+	_, err = s.postgres.GetPolicies(storage.Policies{Subject: s.defaultPolicies.Subject})
+	s.expectErrorCode(err, "INTERNAL-SERVER-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestReplacePolicies() {
+	newResources := []storage.Resource{{Path: "/api/test", Method: "GET"}}
+
+	err := s.postgres.ReplacePolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id, Resources: newResources})
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetPolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Require().Len(got, 1)
+	s.Equal(newResources, got[0].Resources)
+
+	// Nothing is replaced if the resources cannot all be inserted
+	err = s.postgres.ReplacePolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id,
+		Resources: []storage.Resource{{Path: "/api/other", Method: "GET"}, {Path: "/api/other", Method: "GET"}}})
+	s.expectErrorCode(err, "UNIQUE-VIOLATION-ERROR")
+
+	got, err = s.postgres.GetPolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Require().Len(got, 1)
+	s.Equal(newResources, got[0].Resources)
+}
+
+func (s *IntegrationTestSuite) TestDeletePolicies() {
+	resource := s.defaultPolicies.Resources[0]
+
+	deleted, err := s.postgres.DeletePolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id,
+		Resources: []storage.Resource{resource}})
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+	s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{
+		"Ptype": "p", "V0": s.defaultPolicies.Subject, "V1": s.defaultTenant.Id, "V2": resource.Path, "V3": resource.Method,
+	})
+
+	deleted, err = s.postgres.DeletePolicies(storage.Policies{Subject: s.defaultPolicies.Subject, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Equal(int64(len(s.defaultPolicies.Resources)-1), deleted)
+
+	// The PUBLIC policies are not the tenant's, so they cannot be deleted through it
+	deleted, err = s.postgres.DeletePolicies(storage.Policies{Subject: "PUBLIC", TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Equal(int64(0), deleted)
+}
+
+func (s *IntegrationTestSuite) TestReplaceRoleAssignments() {
+	roles := []string{"TENANT_ROLE_ADMIN", "TENANT_ROLE_AUDITOR"}
+
+	err := s.postgres.ReplaceRoleAssignments(s.defaultUser.Id, s.defaultTenant.Id, roles)
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetRoleAssignments(storage.RoleAssignment{UserId: s.defaultUser.Id, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	gotRoles := []string{}
+	for _, roleAssignment := range got {
+		gotRoles = append(gotRoles, roleAssignment.Role)
+	}
+	s.Equal(roles, gotRoles)
+
+	// Nothing is replaced if a role is given twice
+	err = s.postgres.ReplaceRoleAssignments(s.defaultUser.Id, s.defaultTenant.Id, []string{"TENANT_ROLE_AUDITOR", "TENANT_ROLE_AUDITOR"})
+	s.expectErrorCode(err, "UNIQUE-VIOLATION-ERROR")
+	s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{"Ptype": "g", "V0": s.defaultUser.Id, "V1": "TENANT_ROLE_ADMIN"})
+}
+
+func (s *IntegrationTestSuite) TestDeleteRoleAssignments() {
+	filter := storage.RoleAssignment{UserId: s.defaultRoleAssignment.UserId, Role: s.defaultRoleAssignment.Role, TenantId: s.defaultRoleAssignment.TenantId}
+
+	deleted, err := s.postgres.DeleteRoleAssignments(filter)
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+
+	got, err := s.postgres.GetRoleAssignments(filter)
+	s.Equal(nil, err)
+	s.Empty(got)
+
+	// The PUBLIC role assignment is not the tenant's
+	s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{"Ptype": "g", "V0": "*", "V1": "PUBLIC"})
+}
+
+func (s *IntegrationTestSuite) TestCreateRoleAssignmentForPeriod() {
+	now := time.Now().UTC().Truncate(time.Second)
+	roleAssignments := []storage.RoleAssignment{
+		{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_EXPIRED", TenantId: s.defaultTenant.Id, ValidUntil: now.Add(-time.Hour)},
+		{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_SCHEDULED", TenantId: s.defaultTenant.Id, ValidFrom: now.Add(time.Hour)},
+		{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_TEMPORARY", TenantId: s.defaultTenant.Id, ValidFrom: now.Add(-time.Hour), ValidUntil: now.Add(time.Hour)},
+	}
+	for _, roleAssignment := range roleAssignments {
+		err := s.postgres.CreateRoleAssignment(roleAssignment)
+		s.Equal(nil, err)
+	}
+
+	got, err := s.postgres.GetRoleAssignments(storage.RoleAssignment{UserId: s.defaultUser.Id, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Require().Len(got, 4)
+	for i, want := range roleAssignments {
+		s.Equal(want.Role, got[i+1].Role)
+		s.True(want.ValidFrom.Equal(got[i+1].ValidFrom), "The role assignment should start at %s, got %s", want.ValidFrom, got[i+1].ValidFrom)
+		s.True(want.ValidUntil.Equal(got[i+1].ValidUntil), "The role assignment should expire at %s, got %s", want.ValidUntil, got[i+1].ValidUntil)
+	}
+
+	// The enforcer loads the policy from the view, which only has the role assignments that are currently valid
+	rows, err := s.dbRootConn.Query("SELECT V1 FROM active_casbin_rule WHERE Ptype = 'g' AND V0 = $1 ORDER BY V1", s.defaultUser.Id)
+	s.Require().Equal(nil, err)
+	defer rows.Close()
+	activeRoles := []string{}
+	for rows.Next() {
+		var role string
+		s.Equal(nil, rows.Scan(&role))
+		activeRoles = append(activeRoles, role)
+	}
+	s.Equal([]string{s.defaultRoleAssignment.Role, "TENANT_ROLE_TEMPORARY"}, activeRoles)
+
+	// A role assignment cannot expire before it starts
+	err = s.postgres.CreateRoleAssignment(storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_INVALID", TenantId: s.defaultTenant.Id,
+		ValidFrom: now.Add(time.Hour), ValidUntil: now})
+	s.expectErrorCode(err, "INTERNAL-SERVER-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestReplaceRoleAssignmentsShouldKeepValidity() {
+	validUntil := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	err := s.postgres.CreateRoleAssignment(storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_TEMPORARY", TenantId: s.defaultTenant.Id, ValidUntil: validUntil})
+	s.Equal(nil, err)
+
+	err = s.postgres.ReplaceRoleAssignments(s.defaultUser.Id, s.defaultTenant.Id, []string{"TENANT_ROLE_AUDITOR", "TENANT_ROLE_TEMPORARY"})
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetRoleAssignments(storage.RoleAssignment{UserId: s.defaultUser.Id, TenantId: s.defaultTenant.Id})
+	s.Equal(nil, err)
+	s.Require().Len(got, 2)
+	s.True(got[0].ValidUntil.IsZero(), "A new role assignment should not expire")
+	s.True(validUntil.Equal(got[1].ValidUntil), "A kept role assignment should keep its expiry")
+}
+
+func (s *IntegrationTestSuite) TestDeleteExpiredRoleAssignments() {
+	now := time.Now().UTC().Truncate(time.Second)
+	expired := storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_EXPIRED", TenantId: s.defaultTenant.Id, ValidUntil: now.Add(-time.Minute)}
+	err := s.postgres.CreateRoleAssignment(expired)
+	s.Equal(nil, err)
+	err = s.postgres.CreateRoleAssignment(storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_TEMPORARY", TenantId: s.defaultTenant.Id, ValidUntil: now.Add(time.Hour)})
+	s.Equal(nil, err)
+
+	got, err := s.postgres.DeleteExpiredRoleAssignments()
+	s.Equal(nil, err)
+	s.Require().Len(got, 1)
+	s.Equal(expired.Role, got[0].Role)
+	s.Equal(expired.UserId, got[0].UserId)
+	s.Equal(expired.TenantId, got[0].TenantId)
+	s.True(expired.ValidUntil.Equal(got[0].ValidUntil))
+
+	s.expectSelectQueryToReturnNoRows("casbin_rule", map[string]any{"Ptype": "g", "V0": s.defaultUser.Id, "V1": expired.Role})
+	s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{"Ptype": "g", "V0": s.defaultUser.Id, "V1": "TENANT_ROLE_TEMPORARY"})
+	s.expectSelectQueryToReturnOneRow("casbin_rule", map[string]any{"Ptype": "g", "V0": s.defaultRoleAssignment.UserId, "V1": s.defaultRoleAssignment.Role})
+}
+
+func (s *IntegrationTestSuite) TestHasActivatedRoleAssignments() {
+	activated, since, err := s.postgres.HasActivatedRoleAssignments(time.Now().Add(-time.Hour))
+	s.Equal(nil, err)
+	s.False(activated)
+
+	_, err = s.dbRootConn.Exec("INSERT INTO casbin_rule (Ptype, V0, V1, V2, valid_from) VALUES ('g', $1, $2, $3, now())",
+		s.defaultUser.Id, "TENANT_ROLE_SCHEDULED", s.defaultTenant.Id)
+	s.Equal(nil, err)
+
+	activated, since, err = s.postgres.HasActivatedRoleAssignments(since)
+	s.Equal(nil, err)
+	s.True(activated, "The role assignment became valid after the previous call")
+
+	activated, _, err = s.postgres.HasActivatedRoleAssignments(since)
+	s.Equal(nil, err)
+	s.False(activated, "The role assignment should only be reported once")
+}
+
+func (s *IntegrationTestSuite) TestTimeUntilNextRoleAssignmentChange() {
+	_, ok, err := s.postgres.TimeUntilNextRoleAssignmentChange()
+	s.Equal(nil, err)
+	s.False(ok, "No role assignment has a period")
+
+	now := time.Now().UTC()
+	err = s.postgres.CreateRoleAssignment(storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_TEMPORARY", TenantId: s.defaultTenant.Id, ValidUntil: now.Add(time.Hour)})
+	s.Equal(nil, err)
+	err = s.postgres.CreateRoleAssignment(storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_SCHEDULED", TenantId: s.defaultTenant.Id, ValidFrom: now.Add(time.Minute), ValidUntil: now.Add(2 * time.Hour)})
+	s.Equal(nil, err)
+
+	untilChange, ok, err := s.postgres.TimeUntilNextRoleAssignmentChange()
+	s.Equal(nil, err)
+	s.True(ok)
+	s.InDelta(time.Minute.Seconds(), untilChange.Seconds(), 5, "The scheduled role assignment becomes valid first")
+}
+
+func (s *IntegrationTestSuite) TestRoleAssignmentSweeperShouldSweepWhenRoleAssignmentsExpire() {
+	sweeps := make(chan []storage.RoleAssignment, 10)
+	// The interval is long enough that only a scheduled sweep can happen during the test
+	reschedule, stop := s.postgres.StartRoleAssignmentSweeper(time.Hour, func(expired []storage.RoleAssignment, activated bool, err error) {
+		s.Equal(nil, err)
+		sweeps <- expired
+	})
+	defer stop()
+
+	err := s.postgres.CreateRoleAssignment(storage.RoleAssignment{UserId: s.defaultUser.Id, Role: "TENANT_ROLE_TEMPORARY", TenantId: s.defaultTenant.Id, ValidUntil: time.Now().Add(time.Second)})
+	s.Equal(nil, err)
+	reschedule()
+
+	select {
+	case expired := <-sweeps:
+		s.Require().Len(expired, 1)
+		s.Equal("TENANT_ROLE_TEMPORARY", expired[0].Role)
+	case <-time.After(5 * time.Second):
+		s.Fail("The role assignment should have been swept once it expired")
+	}
+}
@@ -1,17 +1,33 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/google/uuid"
 	_ "github.com/lib/pq" // Import pq for its side effects (driver install)
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"multi-tenant-HR-information-system-backend/storage"
 )
 
 type postgresStorage struct {
-	db *sql.DB
+	db              *sql.DB
+	connStr         string
+	applicationName string
 }
 
+// connStr must be in the keyword/value format (e.g. "host=localhost port=5432"), as the application name is appended to it
 func NewPostgresStorage(connStr string) (*postgresStorage, error) {
+	// Every instance connects with its own application name, which the policy watcher uses to tell apart the instance's own changes
+	applicationName := "hris-" + uuid.NewString()
+	connStr = connStr + " application_name=" + applicationName
+
 	db, err := sql.Open("postgres", connStr)
 
 	if err != nil {
@@ -22,11 +38,41 @@ func NewPostgresStorage(connStr string) (*postgresStorage, error) {
 		return nil, err
 	}
 
+	// Refuse to start against a schema that the code was not written for
+	expectedVersion, err := ExpectedSchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	version, err := GetSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if version != expectedVersion {
+		return nil, fmt.Errorf("database schema is at version %d but version %d is expected, run the migrate subcommand", version, expectedVersion)
+	}
+
 	return &postgresStorage{
-		db: db,
+		db:              db,
+		connStr:         connStr,
+		applicationName: applicationName,
 	}, nil
 }
 
+// Checks that the database can be reached, e.g. for readiness probes
+func (postgres *postgresStorage) Ping(ctx context.Context) error {
+	return postgres.db.PingContext(ctx)
+}
+
+// Exports the connection pool's stats (e.g. open & idle connections, time spent waiting for a connection)
+func (postgres *postgresStorage) MetricsCollector() prometheus.Collector {
+	return collectors.NewDBStatsCollector(postgres.db, "hr_information_system")
+}
+
+// Closes the connection pool. Queries in progress are allowed to complete
+func (postgres *postgresStorage) Close() error {
+	return postgres.db.Close()
+}
+
 func NewQueryWithFilter(baseQuery string, conditions []string) string {
 	// TODO: change columnsToFilter to conditions and expect the caller to return the full condition.
 	// They can do so by implementing their own counter
@@ -63,3 +109,103 @@ func NewUpdateQuery(table string, columnsToUpdate []string, columnsToFilterBy []
 
 	return query
 }
+
+// Contents of a pagination cursor. The sort field & order are included so that a cursor cannot be used with a different sort
+type cursor struct {
+	SortBy    string `json:"sortBy"`
+	SortOrder string `json:"sortOrder"`
+	SortValue string `json:"sortValue"`
+	Id        string `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// Builds a SELECT query that returns a single page of rows
+// sortColumns maps the fields a model can be sorted by to their columns. defaultSortBy is used if the page does not specify a field
+// The sort column is appended (as text) to the selected columns so that the caller can build the next page's cursor from the last row.
+// The id column is used as a tie breaker to keep the order deterministic. One row more than the limit is fetched to detect whether a next page exists
+func NewPaginatedQuery(columns []string, table string, conditions []string, values []any, page storage.PageRequest, sortColumns map[string]string, defaultSortBy string) (string, []any, error) {
+	sortBy := page.SortBy
+	if sortBy == "" {
+		sortBy = defaultSortBy
+	}
+	sortColumn, ok := sortColumns[sortBy]
+	if !ok {
+		return "", nil, NewInvalidSortFieldError(sortBy, sortColumns)
+	}
+
+	sortOrder := strings.ToUpper(page.SortOrder)
+	if sortOrder == "" {
+		sortOrder = "ASC"
+	}
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		return "", nil, ErrInvalidSortOrder
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil || c.SortBy != sortBy || c.SortOrder != sortOrder {
+			return "", nil, ErrInvalidCursor
+		}
+
+		comparator := ">"
+		if sortOrder == "DESC" {
+			comparator = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%v, $%v)", sortColumn, comparator, len(values)+1, len(values)+2))
+		values = append(values, c.SortValue, c.Id)
+	}
+
+	baseQuery := fmt.Sprintf("SELECT %s, %s::text FROM %s", strings.Join(columns, ", "), sortColumn, table)
+	query := NewQueryWithFilter(baseQuery, conditions)
+	query = query + fmt.Sprintf(" ORDER BY %s %s, id %s", sortColumn, sortOrder, sortOrder)
+
+	if page.Limit > 0 {
+		query = query + fmt.Sprintf(" LIMIT %v", page.Limit+1)
+	}
+	if page.Cursor == "" && page.Offset > 0 {
+		query = query + fmt.Sprintf(" OFFSET %v", page.Offset)
+	}
+
+	return query, values, nil
+}
+
+// Trims the extra row fetched by a query built with NewPaginatedQuery, and returns the rows of the current page with the cursor
+// of the page after it. The cursor is an empty string if the current page is the last
+// sortValues are the rows' sort column values, and getId returns a row's id
+func NewPage[T any](page storage.PageRequest, defaultSortBy string, rows []T, sortValues []string, getId func(T) string) ([]T, string) {
+	if page.Limit <= 0 || len(rows) <= page.Limit {
+		return rows, ""
+	}
+
+	sortBy := page.SortBy
+	if sortBy == "" {
+		sortBy = defaultSortBy
+	}
+	sortOrder := strings.ToUpper(page.SortOrder)
+	if sortOrder == "" {
+		sortOrder = "ASC"
+	}
+
+	nextCursor := encodeCursor(cursor{
+		SortBy:    sortBy,
+		SortOrder: sortOrder,
+		SortValue: sortValues[page.Limit-1],
+		Id:        getId(rows[page.Limit-1]),
+	})
+
+	return rows[:page.Limit], nextCursor
+}
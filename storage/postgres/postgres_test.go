@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/lib/pq"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/suite"
 
 	"multi-tenant-HR-information-system-backend/httperror"
@@ -232,7 +234,13 @@ func (s *IntegrationTestSuite) SetupSuite() {
 		log.Fatalf("Could not connect to the docker postgres instance: %s", err)
 	}
 
-	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public'"
+	// Bring the schema up to date. The migrations table is excluded from the tables to be cleared so that the schema version is kept
+	_, err = MigrateUp(s.dbRootConn, 0)
+	if err != nil {
+		log.Fatalf("Could not migrate the database: %s", err)
+	}
+
+	query := "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' AND table_type = 'BASE TABLE' AND table_name <> 'schema_migrations'"
 	rows, err := s.dbRootConn.Query(query)
 	if err != nil {
 		log.Fatalf("Could not fetch database tables: %s", err)
@@ -482,6 +490,13 @@ func (s *IntegrationTestSuite) TearDownTest() {
 	}
 }
 
+func (s *IntegrationTestSuite) TestPingAndMetricsCollector() {
+	s.Equal(nil, s.postgres.Ping(context.Background()))
+
+	// The pool stats are exported as go_sql_* metrics labelled with the database name
+	s.Greater(testutil.CollectAndCount(s.postgres.MetricsCollector(), "go_sql_open_connections"), 0)
+}
+
 func (s *IntegrationTestSuite) expectSelectQueryToReturnNoRows(table string, filter map[string]any) {
 	// Convert the string slice to an any slice
 	conditions := []string{}
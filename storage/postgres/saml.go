@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Creates the tenant's SAML configuration or replaces the existing one
+func (postgres *postgresStorage) SetSamlConfiguration(config storage.SamlConfiguration) error {
+	// All queries must be conditional on the tenantId
+	if config.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		INSERT INTO saml_configuration (tenant_id, entity_id, acs_url, idp_metadata, email_attribute, jit_provisioning)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET entity_id = EXCLUDED.entity_id, acs_url = EXCLUDED.acs_url, idp_metadata = EXCLUDED.idp_metadata,
+			email_attribute = EXCLUDED.email_attribute, jit_provisioning = EXCLUDED.jit_provisioning, updated_at = now()`
+	_, err := postgres.db.Exec(query, config.TenantId, config.EntityId, config.AcsUrl, config.IdpMetadata, config.EmailAttribute,
+		config.JitProvisioning)
+
+	if pgErr, ok := err.(*pq.Error); ok {
+		// 23503 corresponds to the Invalid Foreign Key error
+		if pgErr.Code == "23503" {
+			return NewInvalidForeignKeyError(pgErr)
+		} else {
+			return httperror.NewInternalServerError(pgErr)
+		}
+	} else if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+func (postgres *postgresStorage) GetSamlConfiguration(tenantId string) (storage.SamlConfiguration, error) {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return storage.SamlConfiguration{}, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		SELECT tenant_id, entity_id, acs_url, idp_metadata, email_attribute, jit_provisioning, created_at, updated_at
+		FROM saml_configuration WHERE tenant_id = $1`
+
+	var config storage.SamlConfiguration
+	err := postgres.db.QueryRow(query, tenantId).Scan(
+		&config.TenantId,
+		&config.EntityId,
+		&config.AcsUrl,
+		&config.IdpMetadata,
+		&config.EmailAttribute,
+		&config.JitProvisioning,
+		&config.CreatedAt,
+		&config.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.SamlConfiguration{}, New404NotFoundError("SAML configuration")
+	}
+	if err != nil {
+		return storage.SamlConfiguration{}, httperror.NewInternalServerError(err)
+	}
+
+	return config, nil
+}
+
+func (postgres *postgresStorage) CreateSamlRequest(samlRequest storage.SamlRequest) error {
+	// All queries must be conditional on the tenantId
+	if samlRequest.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := "INSERT INTO saml_request (id, tenant_id, expires_at) VALUES ($1, $2, $3)"
+	_, err := postgres.db.Exec(query, samlRequest.Id, samlRequest.TenantId, samlRequest.ExpiresAt)
+
+	if pgErr, ok := err.(*pq.Error); ok {
+		switch pgErr.Code {
+		case "23505":
+			// Unique Violation
+			return NewUniqueViolationError("SAML request", pgErr)
+		case "23503":
+			// Foreign Key Violation
+			return NewInvalidForeignKeyError(pgErr)
+		default:
+			return httperror.NewInternalServerError(pgErr)
+		}
+	} else if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// Deletes the request so that it cannot be responded to again
+// Returns a 404 error if it does not exist, has expired or has already been used (e.g. by a concurrent request)
+func (postgres *postgresStorage) UseSamlRequest(samlRequest storage.SamlRequest) error {
+	// All queries must be conditional on the tenantId
+	if samlRequest.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := "DELETE FROM saml_request WHERE id = $1 AND tenant_id = $2 AND expires_at > now()"
+	result, err := postgres.db.Exec(query, samlRequest.Id, samlRequest.TenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("SAML request")
+	}
+
+	return nil
+}
+
+// Deletes the requests that expired before being responded to (e.g. the user abandoned the login) & returns the number deleted
+func (postgres *postgresStorage) DeleteExpiredSamlRequests() (int64, error) {
+	result, err := postgres.db.Exec("DELETE FROM saml_request WHERE expires_at <= now()")
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Periodically deletes expired SAML requests in the background until stop is called
+// onCleanup is called after every cleanup so that the caller can log the result
+func (postgres *postgresStorage) StartSamlRequestCleanup(interval time.Duration, onCleanup func(deleted int64, err error)) (stop func()) {
+	quit := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := postgres.DeleteExpiredSamlRequests()
+				onCleanup(deleted, err)
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+	}
+}
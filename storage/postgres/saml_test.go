@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"time"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+func (s *IntegrationTestSuite) TestSetSamlConfiguration() {
+	want := storage.SamlConfiguration{
+		TenantId:    s.defaultTenant.Id,
+		EntityId:    "https://hris.example.com/api/tenants/" + s.defaultTenant.Id + "/sso/saml/metadata",
+		AcsUrl:      "https://hris.example.com/api/tenants/" + s.defaultTenant.Id + "/sso/saml/acs",
+		IdpMetadata: "<EntityDescriptor></EntityDescriptor>",
+	}
+
+	err := s.postgres.SetSamlConfiguration(want)
+	s.Equal(nil, err)
+
+	// The existing configuration is replaced
+	want.EmailAttribute = "mail"
+	want.JitProvisioning = true
+	err = s.postgres.SetSamlConfiguration(want)
+	s.Equal(nil, err)
+
+	got, err := s.postgres.GetSamlConfiguration(s.defaultTenant.Id)
+	s.Equal(nil, err)
+	s.Equal(want.EntityId, got.EntityId)
+	s.Equal(want.AcsUrl, got.AcsUrl)
+	s.Equal(want.IdpMetadata, got.IdpMetadata)
+	s.Equal(want.EmailAttribute, got.EmailAttribute)
+	s.Equal(want.JitProvisioning, got.JitProvisioning)
+}
+
+func (s *IntegrationTestSuite) TestSetSamlConfigurationShouldValidateTenant() {
+	err := s.postgres.SetSamlConfiguration(storage.SamlConfiguration{
+		TenantId:    "a9f998c6-ba2e-4359-b308-e56404534974",
+		EntityId:    "https://hris.example.com/metadata",
+		AcsUrl:      "https://hris.example.com/acs",
+		IdpMetadata: "<EntityDescriptor></EntityDescriptor>",
+	})
+	s.expectErrorCode(err, "INVALID-FOREIGN-KEY-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestGetSamlConfigurationShouldValidateExistence() {
+	_, err := s.postgres.GetSamlConfiguration(s.defaultTenant.Id)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestUseSamlRequest() {
+	samlRequest := storage.SamlRequest{
+		Id:        "id-3f2a9c",
+		TenantId:  s.defaultTenant.Id,
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	}
+	err := s.postgres.CreateSamlRequest(samlRequest)
+	s.Equal(nil, err)
+
+	// The request must belong to the tenant
+	err = s.postgres.UseSamlRequest(storage.SamlRequest{Id: samlRequest.Id, TenantId: "a9f998c6-ba2e-4359-b308-e56404534974"})
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+
+	err = s.postgres.UseSamlRequest(samlRequest)
+	s.Equal(nil, err)
+
+	// Each request can only be used once
+	err = s.postgres.UseSamlRequest(samlRequest)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestUseSamlRequestShouldValidateExpiry() {
+	samlRequest := storage.SamlRequest{
+		Id:        "id-7b1e04",
+		TenantId:  s.defaultTenant.Id,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	err := s.postgres.CreateSamlRequest(samlRequest)
+	s.Equal(nil, err)
+
+	err = s.postgres.UseSamlRequest(samlRequest)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) TestDeleteExpiredSamlRequests() {
+	expired := storage.SamlRequest{Id: "id-5d8c21", TenantId: s.defaultTenant.Id, ExpiresAt: time.Now().Add(-time.Minute)}
+	err := s.postgres.CreateSamlRequest(expired)
+	s.Equal(nil, err)
+	pending := storage.SamlRequest{Id: "id-9a4f73", TenantId: s.defaultTenant.Id, ExpiresAt: time.Now().Add(10 * time.Minute)}
+	err = s.postgres.CreateSamlRequest(pending)
+	s.Equal(nil, err)
+
+	deleted, err := s.postgres.DeleteExpiredSamlRequests()
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+
+	s.expectSelectQueryToReturnNoRows("saml_request", map[string]any{"id": expired.Id})
+	s.expectSelectQueryToReturnOneRow("saml_request", map[string]any{"id": pending.Id})
+}
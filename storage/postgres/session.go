@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base32"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Implementation of sessions.Store that keeps session data in the user_session table
+// The cookie only contains the (signed & encrypted) session id, so any instance sharing the database & keys can validate it
+type SessionStore struct {
+	db      *sql.DB
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+}
+
+// Keys are defined in pairs to allow key rotation. The first key in a pair is used for authentication and the second for encryption
+// Every instance behind a load balancer must use the same keys
+func (postgres *postgresStorage) NewSessionStore(keyPairs ...[]byte) *SessionStore {
+	store := &SessionStore{
+		db:     postgres.db,
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+	}
+	store.MaxAge(store.Options.MaxAge)
+
+	return store
+}
+
+// Returns a cached session if the request already fetched it, otherwise it is loaded from the database
+func (store *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(store, name)
+}
+
+// Returns a session for the given name without adding it to the registry
+// If the cookie's session no longer exists (e.g. it was deleted or has expired), the session keeps its id but has no values
+func (store *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(store, name)
+	options := *store.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		// Cookie not found, this is a new session
+		return session, nil
+	}
+
+	err = securecookie.DecodeMulti(name, cookie.Value, &session.ID, store.Codecs...)
+	if err != nil {
+		// Cookie could not be decoded, treat this as a new session
+		return session, err
+	}
+
+	var data []byte
+	err = store.db.QueryRow("SELECT data FROM user_session WHERE id = $1 AND expires_at > now()", session.ID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.IsNew = false
+
+	return session, nil
+}
+
+// Saves the session to the database & adds the session cookie to the response
+// Set Options.MaxAge to -1 before saving the session to delete it
+func (store *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			_, err := store.db.Exec("DELETE FROM user_session WHERE id = $1", session.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		for key := range session.Values {
+			delete(session.Values, key)
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(securecookie.GenerateRandomKey(32)), "=")
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(session.Values); err != nil {
+		return err
+	}
+
+	// Cookies without a max age last until the browser is closed, so the store's max age is used to expire them server-side
+	maxAge := session.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = store.Options.MaxAge
+	}
+	expiresAt := time.Now().Add(time.Duration(maxAge) * time.Second)
+
+	tenantId, _ := session.Values["tenantId"].(string)
+	userId, _ := session.Values["id"].(string)
+
+	// The client's ip & user agent are only recorded when the session is created so that the user can identify their sessions
+	query := `
+			INSERT INTO user_session (id, tenant_id, user_account_id, data, expires_at, client_ip, user_agent)
+			VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7)
+			ON CONFLICT (id) DO UPDATE
+			SET tenant_id = EXCLUDED.tenant_id, user_account_id = EXCLUDED.user_account_id, data = EXCLUDED.data,
+				expires_at = EXCLUDED.expires_at, updated_at = now()
+			`
+	_, err := store.db.Exec(query, session.ID, tenantId, userId, data.Bytes(), expiresAt, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, store.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+
+	return nil
+}
+
+// Deletes the session from the database & clears its id, so that the next save issues a new id
+// Unlike saving with a negative max age, no cookie is written, as the cookie is replaced when the session is saved again
+func (store *SessionStore) Regenerate(session *sessions.Session) error {
+	if session.ID != "" {
+		_, err := store.db.Exec("DELETE FROM user_session WHERE id = $1", session.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	session.ID = ""
+	session.IsNew = true
+	return nil
+}
+
+// Sets the default max age of the store's sessions & cookies
+func (store *SessionStore) MaxAge(age int) {
+	store.Options.MaxAge = age
+
+	for _, codec := range store.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Deletes all expired sessions & returns the number of sessions deleted
+func (store *SessionStore) DeleteExpiredSessions() (int64, error) {
+	result, err := store.db.Exec("DELETE FROM user_session WHERE expires_at <= now()")
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Periodically deletes expired sessions in the background until stop is called
+// onCleanup is called after every cleanup so that the caller can log the result
+func (store *SessionStore) StartCleanup(interval time.Duration, onCleanup func(deleted int64, err error)) (stop func()) {
+	quit := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				deleted, err := store.DeleteExpiredSessions()
+				onCleanup(deleted, err)
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+	}
+}
+
+func (postgres *postgresStorage) GetUserSessions(filter storage.UserSession) ([]storage.UserSession, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newUserSessionConditions(filter)
+	query := NewQueryWithFilter(`
+		SELECT public_id, id, tenant_id, user_account_id, COALESCE(client_ip, ''), COALESCE(user_agent, ''), expires_at, created_at, updated_at
+		FROM user_session`, conditions) + " ORDER BY created_at DESC"
+
+	rows, err := postgres.db.Query(query, values...)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	userSessions := []storage.UserSession{}
+	for rows.Next() {
+		var userSession storage.UserSession
+		err := rows.Scan(&userSession.Id, &userSession.SessionId, &userSession.TenantId, &userSession.UserId, &userSession.ClientIp,
+			&userSession.UserAgent, &userSession.ExpiresAt, &userSession.CreatedAt, &userSession.UpdatedAt)
+		if err != nil {
+			return nil, httperror.NewInternalServerError(err)
+		}
+
+		userSessions = append(userSessions, userSession)
+	}
+
+	return userSessions, nil
+}
+
+// Deletes the sessions matching the filter, which immediately logs out the clients using them
+func (postgres *postgresStorage) DeleteUserSessions(filter storage.UserSession) (int64, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return 0, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions, values := newUserSessionConditions(filter)
+	query := NewQueryWithFilter("DELETE FROM user_session", conditions)
+
+	result, err := postgres.db.Exec(query, values...)
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, httperror.NewInternalServerError(err)
+	}
+
+	return deleted, nil
+}
+
+// Expired sessions are excluded as they can no longer be used
+func newUserSessionConditions(filter storage.UserSession) ([]string, []any) {
+	conditions := []string{"tenant_id = $1", "expires_at > now()"}
+	values := []any{filter.TenantId}
+
+	if filter.UserId != "" {
+		values = append(values, filter.UserId)
+		conditions = append(conditions, fmt.Sprintf("user_account_id = $%v", len(values)))
+	}
+
+	if filter.Id != "" {
+		values = append(values, filter.Id)
+		conditions = append(conditions, fmt.Sprintf("public_id = $%v", len(values)))
+	}
+
+	return conditions, values
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+const testSessionName = "authenticated"
+
+func (s *IntegrationTestSuite) newTestSessionStore() *SessionStore {
+	return s.postgres.NewSessionStore([]byte("authkey123"), []byte("enckey12341234567890123456789012"))
+}
+
+// Saves a session with the given values & returns a request carrying its cookie
+func (s *IntegrationTestSuite) saveTestSession(store *SessionStore, values map[interface{}]interface{}) *http.Request {
+	r, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	session, err := store.New(r, testSessionName)
+	s.Equal(nil, err)
+	for key, value := range values {
+		session.Values[key] = value
+	}
+
+	w := httptest.NewRecorder()
+	err = store.Save(r, w, session)
+	s.Equal(nil, err)
+
+	cookieString := w.Header().Get("Set-Cookie")
+	first, _, _ := strings.Cut(cookieString, ";")
+	name, value, _ := strings.Cut(first, "=")
+
+	r2, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r2.AddCookie(&http.Cookie{Name: name, Value: value})
+
+	return r2
+}
+
+func (s *IntegrationTestSuite) TestSessionStoreSaveAndGet() {
+	store := s.newTestSessionStore()
+	r := s.saveTestSession(store, map[interface{}]interface{}{
+		"id":       s.defaultUser.Id,
+		"tenantId": s.defaultUser.TenantId,
+		"email":    s.defaultUser.Email,
+	})
+
+	// A 2nd store instance (i.e. another server) should be able to read the session
+	session, err := s.newTestSessionStore().New(r, testSessionName)
+	s.Equal(nil, err)
+	s.Equal(false, session.IsNew, "Session should have been loaded from the database")
+	s.Equal(s.defaultUser.Id, session.Values["id"])
+	s.Equal(s.defaultUser.Email, session.Values["email"])
+
+	s.expectSelectQueryToReturnOneRow(
+		"user_session",
+		map[string]any{
+			"id":              session.ID,
+			"tenant_id":       s.defaultUser.TenantId,
+			"user_account_id": s.defaultUser.Id,
+		},
+	)
+}
+
+func (s *IntegrationTestSuite) TestSessionStoreDelete() {
+	store := s.newTestSessionStore()
+	r := s.saveTestSession(store, map[interface{}]interface{}{"id": s.defaultUser.Id})
+
+	session, err := store.New(r, testSessionName)
+	s.Equal(nil, err)
+
+	session.Options.MaxAge = -1
+	err = store.Save(r, httptest.NewRecorder(), session)
+	s.Equal(nil, err)
+
+	// The session id is kept so that the use of a deleted session can be detected
+	deletedSession, err := store.New(r, testSessionName)
+	s.Equal(nil, err)
+	s.Equal(session.ID, deletedSession.ID)
+	s.Equal(0, len(deletedSession.Values), "Session values should have been deleted")
+
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"id": session.ID})
+}
+
+func (s *IntegrationTestSuite) TestSessionStoreRegenerate() {
+	store := s.newTestSessionStore()
+	r := s.saveTestSession(store, map[interface{}]interface{}{"id": s.defaultUser.Id, "tenantId": s.defaultUser.TenantId})
+
+	session, err := store.New(r, testSessionName)
+	s.Equal(nil, err)
+	oldId := session.ID
+
+	w := httptest.NewRecorder()
+	err = store.Regenerate(session)
+	s.Equal(nil, err)
+	s.Equal("", w.Header().Get("Set-Cookie"), "No cookie should have been written")
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"id": oldId})
+
+	err = store.Save(r, w, session)
+	s.Equal(nil, err)
+	s.NotEqual(oldId, session.ID)
+	s.expectSelectQueryToReturnOneRow("user_session", map[string]any{"id": session.ID, "user_account_id": s.defaultUser.Id})
+}
+
+func (s *IntegrationTestSuite) TestSessionStoreDeleteExpiredSessions() {
+	store := s.newTestSessionStore()
+	r := s.saveTestSession(store, map[interface{}]interface{}{"id": s.defaultUser.Id})
+
+	session, err := store.New(r, testSessionName)
+	s.Equal(nil, err)
+
+	_, err = s.dbRootConn.Exec("UPDATE user_session SET expires_at = now() - interval '1 second' WHERE id = $1", session.ID)
+	if err != nil {
+		log.Fatalf("Could not expire session: %s", err)
+	}
+
+	expiredSession, err := store.New(r, testSessionName)
+	s.Equal(nil, err)
+	s.Equal(0, len(expiredSession.Values), "Expired sessions should not be loaded")
+
+	deleted, err := store.DeleteExpiredSessions()
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+
+	s.expectSelectQueryToReturnNoRows("user_session", map[string]any{"id": session.ID})
+}
+
+func (s *IntegrationTestSuite) TestGetAndDeleteUserSessions() {
+	store := s.newTestSessionStore()
+	values := map[interface{}]interface{}{"id": s.defaultUser.Id, "tenantId": s.defaultUser.TenantId}
+	s.saveTestSession(store, values)
+	s.saveTestSession(store, values)
+
+	filter := storage.UserSession{TenantId: s.defaultUser.TenantId, UserId: s.defaultUser.Id}
+	userSessions, err := s.postgres.GetUserSessions(filter)
+	s.Equal(nil, err)
+	s.Equal(2, len(userSessions), "Both of the user's sessions should be returned")
+	if len(userSessions) != 2 {
+		return
+	}
+	s.NotEqual(userSessions[0].Id, userSessions[0].SessionId, "The public id should differ from the session id")
+
+	// Delete a single session by its public id
+	deleted, err := s.postgres.DeleteUserSessions(storage.UserSession{TenantId: s.defaultUser.TenantId, UserId: s.defaultUser.Id, Id: userSessions[0].Id})
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+
+	// Sessions cannot be deleted across tenants
+	deleted, err = s.postgres.DeleteUserSessions(storage.UserSession{TenantId: "a9f998c6-ba2e-4359-b308-e56404534974", UserId: s.defaultUser.Id})
+	s.Equal(nil, err)
+	s.Equal(int64(0), deleted)
+
+	// Delete the remaining sessions
+	deleted, err = s.postgres.DeleteUserSessions(filter)
+	s.Equal(nil, err)
+	s.Equal(int64(1), deleted)
+
+	userSessions, err = s.postgres.GetUserSessions(filter)
+	s.Equal(nil, err)
+	s.Equal(0, len(userSessions), "No sessions should remain")
+}
+
+func (s *IntegrationTestSuite) TestGetUserSessionsNoTenantId() {
+	userSessions, err := s.postgres.GetUserSessions(storage.UserSession{UserId: s.defaultUser.Id})
+	s.expectErrorCode(err, "INTERNAL-SERVER-ERROR")
+	s.Equal(0, len(userSessions))
+}
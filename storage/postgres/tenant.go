@@ -27,10 +27,15 @@ func (postgres *postgresStorage) CreateTenant(tenant storage.Tenant) error {
 	return nil
 }
 
-func (postgres *postgresStorage) GetTenants(filter storage.Tenant) ([]storage.Tenant, error) {
+var tenantSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+}
+
+func (postgres *postgresStorage) GetTenants(filter storage.Tenant, page storage.PageRequest) ([]storage.Tenant, string, error) {
 	// All queries must be conditional on the tenantId
 	if filter.Id == "" {
-		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+		return nil, "", httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
 	}
 
 	conditions := []string{"id = $1"}
@@ -41,24 +46,35 @@ func (postgres *postgresStorage) GetTenants(filter storage.Tenant) ([]storage.Te
 		filterByValues = append(filterByValues, filter.Name)
 	}
 
-	query := NewQueryWithFilter("SELECT * FROM tenant", conditions)
-	rows, err := postgres.db.Query(query, filterByValues...)
+	columns := []string{"id", "name", "created_at", "updated_at"}
+	query, values, err := NewPaginatedQuery(columns, "tenant", conditions, filterByValues, page, tenantSortColumns, "createdAt")
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := postgres.db.Query(query, values...)
 	if err != nil {
-		return nil, httperror.NewInternalServerError(err)
+		return nil, "", httperror.NewInternalServerError(err)
 	}
+	defer rows.Close()
 
 	tenants := []storage.Tenant{}
+	sortValues := []string{}
 	for rows.Next() {
 		var tenant storage.Tenant
-		err := rows.Scan(&tenant.Id, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt)
+		var sortValue string
+		err := rows.Scan(&tenant.Id, &tenant.Name, &tenant.CreatedAt, &tenant.UpdatedAt, &sortValue)
 		if err != nil {
-			return nil, httperror.NewInternalServerError(err)
+			return nil, "", httperror.NewInternalServerError(err)
 		}
 
 		tenants = append(tenants, tenant)
+		sortValues = append(sortValues, sortValue)
 	}
 
-	return tenants, nil
+	tenants, nextCursor := NewPage(page, "createdAt", tenants, sortValues, func(row storage.Tenant) string { return row.Id })
+
+	return tenants, nextCursor, nil
 }
 
 func (postgres *postgresStorage) CreateDivision(division storage.Division) error {
@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+// Stores the secret that the user is enrolling with. It is only used once the enrollment is confirmed
+// Users who have already enrolled must have their TOTP reset by an admin before they can enroll again
+func (postgres *postgresStorage) SetPendingTotpSecretKey(userId string, tenantId string, totpSecretKey string) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		UPDATE user_account SET totp_pending_secret_key = $1, updated_at = now()
+		WHERE id = $2 AND tenant_id = $3 AND totp_secret_key IS NULL`
+	result, err := postgres.db.Exec(query, totpSecretKey, userId, tenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("user pending TOTP enrollment")
+	}
+
+	return nil
+}
+
+// Activates the pending secret, after which the user must provide TOTPs generated with it
+// The recovery codes replace any codes that the user had from a previous enrollment
+func (postgres *postgresStorage) ConfirmTotpEnrollment(userId string, tenantId string, recoveryCodes []storage.RecoveryCode) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
+	query := `
+		UPDATE user_account SET totp_secret_key = totp_pending_secret_key, totp_pending_secret_key = NULL,
+			totp_enrollment_token_hash = NULL, totp_enrollment_token_expires_at = NULL, updated_at = now()
+		WHERE id = $1 AND tenant_id = $2 AND totp_secret_key IS NULL AND totp_pending_secret_key IS NOT NULL`
+	result, err := tx.Exec(query, userId, tenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("user pending TOTP enrollment")
+	}
+
+	query = "DELETE FROM recovery_code WHERE tenant_id = $1 AND user_account_id = $2"
+	_, err = tx.Exec(query, tenantId, userId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	if len(recoveryCodes) > 0 {
+		identifiers := []string{}
+		values := []any{}
+
+		for i, recoveryCode := range recoveryCodes {
+			values = append(values, tenantId, userId, recoveryCode.LookupHash, recoveryCode.CodeHash)
+			identifiers = append(identifiers, fmt.Sprintf("($%v, $%v, $%v, $%v)", i*4+1, i*4+2, i*4+3, i*4+4))
+		}
+
+		query = "INSERT INTO recovery_code (tenant_id, user_account_id, lookup_hash, code_hash) VALUES " + strings.Join(identifiers, ", ")
+		_, err = tx.Exec(query, values...)
+		if err != nil {
+			return httperror.NewInternalServerError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// Returns whether the user may enroll in TOTP. Users whose TOTP has been reset by an admin must provide the unexpired token
+// that was issued with the reset, while users who have never enrolled only need their password
+func (postgres *postgresStorage) IsTotpEnrollmentAllowed(userId string, tenantId string, enrollmentTokenHash string) (bool, error) {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return false, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := `
+		SELECT totp_enrollment_token_hash IS NULL OR (totp_enrollment_token_hash = $1 AND totp_enrollment_token_expires_at > now())
+		FROM user_account
+		WHERE id = $2 AND tenant_id = $3`
+	var allowed bool
+	err := postgres.db.QueryRow(query, enrollmentTokenHash, userId, tenantId).Scan(&allowed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, httperror.NewInternalServerError(err)
+	}
+
+	return allowed, nil
+}
+
+// Removes the user's TOTP secrets & recovery codes so that the user must enroll again, e.g. after losing their authenticator
+// Only the hash of the enrollment token is stored. The user must provide the token to enroll again, so that their password
+// alone is not sufficient to enroll an authenticator in their place
+func (postgres *postgresStorage) ResetTotp(userId string, tenantId string, enrollmentTokenHash string, enrollmentTokenExpiresAt time.Time) error {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
+	query := `
+		UPDATE user_account SET totp_secret_key = NULL, totp_pending_secret_key = NULL, totp_enrollment_token_hash = $1,
+			totp_enrollment_token_expires_at = $2, updated_at = now()
+		WHERE id = $3 AND tenant_id = $4`
+	result, err := tx.Exec(query, enrollmentTokenHash, enrollmentTokenExpiresAt, userId, tenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("user")
+	}
+
+	query = "DELETE FROM recovery_code WHERE tenant_id = $1 AND user_account_id = $2"
+	_, err = tx.Exec(query, tenantId, userId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// Returns the user's unused recovery codes
+func (postgres *postgresStorage) GetRecoveryCodes(filter storage.RecoveryCode) ([]storage.RecoveryCode, error) {
+	// All queries must be conditional on the tenantId
+	if filter.TenantId == "" {
+		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	conditions := []string{"tenant_id = $1", "used_at IS NULL"}
+	values := []any{filter.TenantId}
+
+	if filter.UserId != "" {
+		values = append(values, filter.UserId)
+		conditions = append(conditions, fmt.Sprintf("user_account_id = $%v", len(values)))
+	}
+
+	query := NewQueryWithFilter("SELECT id, tenant_id, user_account_id, lookup_hash, code_hash, created_at, updated_at FROM recovery_code", conditions)
+	rows, err := postgres.db.Query(query, values...)
+	if err != nil {
+		return nil, httperror.NewInternalServerError(err)
+	}
+	defer rows.Close()
+
+	recoveryCodes := []storage.RecoveryCode{}
+	for rows.Next() {
+		var recoveryCode storage.RecoveryCode
+		err := rows.Scan(&recoveryCode.Id, &recoveryCode.TenantId, &recoveryCode.UserId, &recoveryCode.LookupHash, &recoveryCode.CodeHash,
+			&recoveryCode.CreatedAt, &recoveryCode.UpdatedAt)
+		if err != nil {
+			return nil, httperror.NewInternalServerError(err)
+		}
+
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+	}
+
+	return recoveryCodes, nil
+}
+
+// Marks the recovery code as used. Returns a 404 error if it has already been used (e.g. by a concurrent request)
+func (postgres *postgresStorage) UseRecoveryCode(recoveryCode storage.RecoveryCode) error {
+	// All queries must be conditional on the tenantId
+	if recoveryCode.TenantId == "" {
+		return httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	query := "UPDATE recovery_code SET used_at = now(), updated_at = now() WHERE id = $1 AND tenant_id = $2 AND used_at IS NULL"
+	result, err := postgres.db.Exec(query, recoveryCode.Id, recoveryCode.TenantId)
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return New404NotFoundError("recovery code")
+	}
+
+	return nil
+}
+
+// Records the time step of a TOTP that the user has used
+// Returns false if a TOTP of the same or a later step has already been used, in which case the TOTP is being replayed
+func (postgres *postgresStorage) UseTotpStep(userId string, tenantId string, step int64) (bool, error) {
+	// All queries must be conditional on the tenantId
+	if tenantId == "" {
+		return false, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+	}
+
+	// The check & update are done in a single statement so that concurrent requests cannot both use the same step
+	query := `
+		UPDATE user_account SET totp_last_used_step = $1, updated_at = now()
+		WHERE id = $2 AND tenant_id = $3 AND (totp_last_used_step IS NULL OR totp_last_used_step < $1)`
+	result, err := postgres.db.Exec(query, step, userId, tenantId)
+	if err != nil {
+		return false, httperror.NewInternalServerError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, httperror.NewInternalServerError(err)
+	}
+
+	return affected != 0, nil
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"time"
+)
+
+func (s *IntegrationTestSuite) TestTotpEnrollment() {
+	const secret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+	// Users who have already enrolled cannot start enrollment
+	err := s.postgres.SetPendingTotpSecretKey(s.defaultUser.Id, s.defaultUser.TenantId, secret)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+
+	// Users who have never been reset do not need an enrollment token
+	allowed, err := s.postgres.IsTotpEnrollmentAllowed(s.defaultUser.Id, s.defaultUser.TenantId, "")
+	s.Equal(nil, err)
+	s.True(allowed)
+
+	enrollmentTokenHash := "3333333333333333333333333333333333333333333333333333333333333333"
+	err = s.postgres.ResetTotp(s.defaultUser.Id, s.defaultUser.TenantId, enrollmentTokenHash, time.Now().Add(time.Hour))
+	s.Equal(nil, err)
+	s.expectTotpSecretKeyToBeNull(s.defaultUser.Id)
+
+	allowed, err = s.postgres.IsTotpEnrollmentAllowed(s.defaultUser.Id, s.defaultUser.TenantId, "")
+	s.Equal(nil, err)
+	s.False(allowed, "The enrollment token should be required after a reset")
+	allowed, err = s.postgres.IsTotpEnrollmentAllowed(s.defaultUser.Id, s.defaultUser.TenantId, enrollmentTokenHash)
+	s.Equal(nil, err)
+	s.True(allowed)
+
+	// Enrollment cannot be confirmed before it is started
+	err = s.postgres.ConfirmTotpEnrollment(s.defaultUser.Id, s.defaultUser.TenantId, nil)
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+
+	err = s.postgres.SetPendingTotpSecretKey(s.defaultUser.Id, s.defaultUser.TenantId, secret)
+	s.Equal(nil, err)
+
+	err = s.postgres.ConfirmTotpEnrollment(s.defaultUser.Id, s.defaultUser.TenantId, nil)
+	s.Equal(nil, err)
+
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultUser.Id, "totp_secret_key": secret, "totp_enrollment_token_hash": ""})
+}
+
+func (s *IntegrationTestSuite) TestIsTotpEnrollmentAllowedShouldRejectExpiredTokens() {
+	enrollmentTokenHash := "4444444444444444444444444444444444444444444444444444444444444444"
+	err := s.postgres.ResetTotp(s.defaultUser.Id, s.defaultUser.TenantId, enrollmentTokenHash, time.Now().Add(-time.Second))
+	s.Equal(nil, err)
+
+	allowed, err := s.postgres.IsTotpEnrollmentAllowed(s.defaultUser.Id, s.defaultUser.TenantId, enrollmentTokenHash)
+	s.Equal(nil, err)
+	s.False(allowed)
+}
+
+func (s *IntegrationTestSuite) TestResetTotpShouldBeConditionalOnTenant() {
+	err := s.postgres.ResetTotp(s.defaultUser.Id, "a9f998c6-ba2e-4359-b308-e56404534974", "", time.Now().Add(time.Hour))
+	s.expectErrorCode(err, "RESOURCE-NOT-FOUND-ERROR")
+}
+
+func (s *IntegrationTestSuite) expectTotpSecretKeyToBeNull(userId string) {
+	var isNull bool
+	err := s.dbRootConn.QueryRow("SELECT totp_secret_key IS NULL FROM user_account WHERE id = $1", userId).Scan(&isNull)
+	s.Equal(nil, err)
+	s.True(isNull, "The TOTP secret key should have been removed")
+}
+
+func (s *IntegrationTestSuite) TestUseTotpStep() {
+	used, err := s.postgres.UseTotpStep(s.defaultUser.Id, s.defaultUser.TenantId, 100)
+	s.Equal(nil, err)
+	s.Equal(true, used)
+	s.expectSelectQueryToReturnOneRow("user_account", map[string]any{"id": s.defaultUser.Id, "totp_last_used_step": 100})
+
+	// The same or an earlier step is a replay
+	used, err = s.postgres.UseTotpStep(s.defaultUser.Id, s.defaultUser.TenantId, 100)
+	s.Equal(nil, err)
+	s.Equal(false, used, "The same step should not be usable twice")
+
+	used, err = s.postgres.UseTotpStep(s.defaultUser.Id, s.defaultUser.TenantId, 99)
+	s.Equal(nil, err)
+	s.Equal(false, used, "An earlier step should not be usable")
+
+	used, err = s.postgres.UseTotpStep(s.defaultUser.Id, s.defaultUser.TenantId, 101)
+	s.Equal(nil, err)
+	s.Equal(true, used)
+}
+
+func (s *IntegrationTestSuite) TestUseTotpStepShouldBeConditionalOnTenant() {
+	used, err := s.postgres.UseTotpStep(s.defaultUser.Id, "a9f998c6-ba2e-4359-b308-e56404534974", 100)
+	s.Equal(nil, err)
+	s.Equal(false, used)
+}
@@ -12,11 +12,18 @@ import (
 	"multi-tenant-HR-information-system-backend/storage"
 )
 
-func (postgres *postgresStorage) CreateUser(user storage.User) error {
+// Creates the user together with their policies, so that a user is never created without the rights they need
+func (postgres *postgresStorage) CreateUser(user storage.User, policies storage.Policies) error {
+	tx, err := postgres.db.Begin()
+	if err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+	defer tx.Rollback() // Will have no effect if tx.Commit() is called
+
 	query := `
-		INSERT INTO user_account (id, tenant_id, email, password, totp_secret_key) 
-		VALUES ($1, $2, $3, $4, $5)`
-	_, err := postgres.db.Exec(query, user.Id, user.TenantId, user.Email, user.Password, user.TotpSecretKey)
+		INSERT INTO user_account (id, tenant_id, email, password, totp_secret_key, must_change_password) 
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)`
+	_, err = tx.Exec(query, user.Id, user.TenantId, user.Email, user.Password, user.TotpSecretKey, user.MustChangePassword)
 	if pgErr, ok := err.(*pq.Error); ok {
 		switch pgErr.Code {
 		case "23505":
@@ -32,54 +39,81 @@ func (postgres *postgresStorage) CreateUser(user storage.User) error {
 		return httperror.NewInternalServerError(err)
 	}
 
+	err = insertPolicies(tx, policies)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return httperror.NewInternalServerError(err)
+	}
+
 	return nil
+}
 
+var userSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"email":     "email",
 }
 
-func (postgres postgresStorage) GetUsers(userFilter storage.User) ([]storage.User, error) {
+func (postgres postgresStorage) GetUsers(userFilter storage.User, page storage.PageRequest) ([]storage.User, string, error) {
 	// All queries must be conditional on the tenantId
 	if userFilter.TenantId == "" {
-		return nil, httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
+		return nil, "", httperror.NewInternalServerError(errors.New("TenantId must be provided to postgres model"))
 	}
 
 	conditions := []string{"tenant_id = $1"}
-	values := []any{userFilter.TenantId}
+	filterByValues := []any{userFilter.TenantId}
 
 	if userFilter.Id != "" {
 		conditions = append(conditions, fmt.Sprintf("id = $%v", len(conditions)+1))
-		values = append(values, userFilter.Id)
+		filterByValues = append(filterByValues, userFilter.Id)
 	}
 
 	if userFilter.Email != "" {
 		conditions = append(conditions, fmt.Sprintf("email = $%v", len(conditions)+1))
-		values = append(values, userFilter.Email)
+		filterByValues = append(filterByValues, userFilter.Email)
 	}
 
-	query := NewQueryWithFilter("SELECT * FROM user_account", conditions)
+	columns := []string{"id", "tenant_id", "email", "password", "totp_secret_key", "totp_pending_secret_key", "must_change_password",
+		"created_at", "updated_at", "last_login"}
+	query, values, err := NewPaginatedQuery(columns, "user_account", conditions, filterByValues, page, userSortColumns, "createdAt")
+	if err != nil {
+		return nil, "", err
+	}
 
 	rows, err := postgres.db.Query(query, values...)
 	if err != nil {
-		return nil, httperror.NewInternalServerError(err)
+		return nil, "", httperror.NewInternalServerError(err)
 	}
 	defer rows.Close()
 
 	var fetchedUsers []storage.User
+	sortValues := []string{}
 
 	for rows.Next() {
 		var user storage.User
-		var lastLogin sql.NullString // last_login may be null
+		var lastLogin sql.NullString                           // last_login may be null
+		var totpSecretKey, totpPendingSecretKey sql.NullString // Null unless the user has enrolled/is enrolling in TOTP
+		var sortValue string
 
-		if err := rows.Scan(&user.Id, &user.Email, &user.TenantId, &user.Password,
-			&user.TotpSecretKey, &user.CreatedAt, &user.UpdatedAt, &lastLogin); err != nil {
-			return nil, httperror.NewInternalServerError(err)
+		if err := rows.Scan(&user.Id, &user.TenantId, &user.Email, &user.Password, &totpSecretKey, &totpPendingSecretKey,
+			&user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt, &lastLogin, &sortValue); err != nil {
+			return nil, "", httperror.NewInternalServerError(err)
 		}
 
 		user.LastLogin = lastLogin.String
+		user.TotpSecretKey = totpSecretKey.String
+		user.TotpPendingSecretKey = totpPendingSecretKey.String
 
 		fetchedUsers = append(fetchedUsers, user)
+		sortValues = append(sortValues, sortValue)
 	}
 
-	return fetchedUsers, nil
+	fetchedUsers, nextCursor := NewPage(page, "createdAt", fetchedUsers, sortValues, func(row storage.User) string { return row.Id })
+
+	return fetchedUsers, nextCursor, nil
 }
 
 func (postgres *postgresStorage) GetUserSupervisors(userId string, tenantId string) ([]string, error) {
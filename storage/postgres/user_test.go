@@ -19,7 +19,13 @@ func (s *IntegrationTestSuite) TestCreateUser() {
 		Email:    "test@gmail.com",
 	}
 
-	err := s.postgres.CreateUser(wantUser)
+	wantPolicies := storage.Policies{
+		Subject:   wantUser.Id,
+		TenantId:  wantUser.TenantId,
+		Resources: []storage.Resource{{Path: "/api/tenants/*/users/" + wantUser.Id, Method: "GET"}},
+	}
+
+	err := s.postgres.CreateUser(wantUser, wantPolicies)
 	s.Equal(nil, err)
 
 	s.expectSelectQueryToReturnOneRow(
@@ -30,6 +36,16 @@ func (s *IntegrationTestSuite) TestCreateUser() {
 			"email":     wantUser.Email,
 		},
 	)
+	s.expectSelectQueryToReturnOneRow(
+		"casbin_rule",
+		map[string]any{
+			"Ptype": "p",
+			"V0":    wantPolicies.Subject,
+			"V1":    wantPolicies.TenantId,
+			"V2":    wantPolicies.Resources[0].Path,
+			"V3":    wantPolicies.Resources[0].Method,
+		},
+	)
 }
 
 func (s *IntegrationTestSuite) TestCreateUserViolatesUniqueConstraint() {
@@ -54,9 +70,24 @@ func (s *IntegrationTestSuite) TestCreateUserViolatesUniqueConstraint() {
 
 	for _, test := range tests {
 		s.Run(test.name, func() {
-			err := s.postgres.CreateUser(test.input)
+			policies := storage.Policies{
+				Subject:   test.input.Id,
+				TenantId:  test.input.TenantId,
+				Resources: []storage.Resource{{Path: "/api/tenants/*/users/" + test.input.Id, Method: "GET"}},
+			}
+			err := s.postgres.CreateUser(test.input, policies)
 			s.expectErrorCode(err, "UNIQUE-VIOLATION-ERROR")
 
+			// The user is created together with their policies, so the policies must not be created either
+			s.expectSelectQueryToReturnNoRows(
+				"casbin_rule",
+				map[string]any{
+					"Ptype": "p",
+					"V0":    policies.Subject,
+					"V2":    policies.Resources[0].Path,
+				},
+			)
+
 			s.expectSelectQueryToReturnNoRows(
 				"user_account",
 				map[string]any{
@@ -97,7 +128,7 @@ func (s *IntegrationTestSuite) TestCreateUserDoesNotViolateUniqueConstraint() {
 
 	for _, test := range tests {
 		s.Run(test.name, func() {
-			err := s.postgres.CreateUser(test.input)
+			err := s.postgres.CreateUser(test.input, storage.Policies{})
 			s.Equal(nil, err)
 
 			s.expectSelectQueryToReturnOneRow(
@@ -129,7 +160,7 @@ func (s *IntegrationTestSuite) TestCreateUserViolatesForeignKeyConstraint() {
 
 	for _, test := range tests {
 		s.Run(test.name, func() {
-			err := s.postgres.CreateUser(test.input)
+			err := s.postgres.CreateUser(test.input, storage.Policies{})
 			s.expectErrorCode(err, "INVALID-FOREIGN-KEY-ERROR")
 
 			s.expectSelectQueryToReturnNoRows(
@@ -201,7 +232,7 @@ func (s *IntegrationTestSuite) TestGetUsers() {
 
 	for _, test := range tests {
 		s.Run(test.name, func() {
-			users, err := s.postgres.GetUsers(test.input)
+			users, _, err := s.postgres.GetUsers(test.input, storage.PageRequest{})
 			s.Equal(nil, err)
 
 			s.Equal(test.want, len(users), fmt.Sprintf("Should have returned %v row(s)", test.want))
@@ -209,12 +240,71 @@ func (s *IntegrationTestSuite) TestGetUsers() {
 	}
 }
 
+func (s *IntegrationTestSuite) TestGetUsersWithPagination() {
+	// Seed new users
+	_, err := s.dbRootConn.Exec("INSERT INTO tenant (id, name) VALUES ($1, $2)", "a9f998c6-ba2e-4359-b308-e56404534974", "Macdonalds")
+	if err != nil {
+		log.Fatalf("Could not seed tenant: %s", err)
+	}
+
+	emails := []string{"a@gmail.com", "b@gmail.com", "c@gmail.com"}
+	userIds := []string{"1a288b1f-3c53-44e3-9ef9-c902af41cd7e", "20717410-4530-4f83-9cfa-5fd1b32c77a4", "c75d1e25-0e6a-478c-8332-1c48670ba74c"}
+	for i, email := range emails {
+		query := `INSERT INTO user_account (id, tenant_id, email, password, totp_secret_key) VALUES ($1, $2, $3, $4, $5)`
+		_, err = s.dbRootConn.Exec(query, userIds[i], "a9f998c6-ba2e-4359-b308-e56404534974", email, "", "")
+		if err != nil {
+			log.Fatalf("Could not seed user: %s", err)
+		}
+	}
+
+	filter := storage.User{TenantId: "a9f998c6-ba2e-4359-b308-e56404534974"}
+	page := storage.PageRequest{Limit: 2, SortBy: "email", SortOrder: "desc"}
+
+	users, nextCursor, err := s.postgres.GetUsers(filter, page)
+	s.Equal(nil, err)
+	s.Equal(2, len(users), "Should have returned 2 rows")
+	s.NotEqual("", nextCursor, "Should have returned a cursor to the next page")
+	if len(users) == 2 {
+		s.Equal("c@gmail.com", users[0].Email)
+		s.Equal("b@gmail.com", users[1].Email)
+	}
+
+	page.Cursor = nextCursor
+	users, nextCursor, err = s.postgres.GetUsers(filter, page)
+	s.Equal(nil, err)
+	s.Equal(1, len(users), "Should have returned the last row")
+	s.Equal("", nextCursor, "Should not have returned a cursor on the last page")
+	if len(users) == 1 {
+		s.Equal("a@gmail.com", users[0].Email)
+		s.Equal("a9f998c6-ba2e-4359-b308-e56404534974", users[0].TenantId)
+	}
+
+	page = storage.PageRequest{Limit: 2, Offset: 2, SortBy: "email"}
+	users, _, err = s.postgres.GetUsers(filter, page)
+	s.Equal(nil, err)
+	s.Equal(1, len(users), "Should have skipped the first 2 rows")
+}
+
+func (s *IntegrationTestSuite) TestGetUsersShouldValidatePagination() {
+	filter := storage.User{TenantId: s.defaultUser.TenantId}
+
+	_, _, err := s.postgres.GetUsers(filter, storage.PageRequest{SortBy: "password"})
+	s.expectErrorCode(err, "INVALID-SORT-FIELD-ERROR")
+
+	_, _, err = s.postgres.GetUsers(filter, storage.PageRequest{Cursor: "abc"})
+	s.expectErrorCode(err, "INVALID-CURSOR-ERROR")
+
+	// Cursors cannot be reused with a different sort
+	_, _, err = s.postgres.GetUsers(filter, storage.PageRequest{SortBy: "email", Cursor: encodeCursor(cursor{SortBy: "createdAt", SortOrder: "ASC"})})
+	s.expectErrorCode(err, "INVALID-CURSOR-ERROR")
+}
+
 func (s *IntegrationTestSuite) TestGetUsersNoTenantId() {
 	filter := storage.User{
 		Email: s.defaultUser.Email,
 	}
 
-	users, err := s.postgres.GetUsers(filter)
+	users, _, err := s.postgres.GetUsers(filter, storage.PageRequest{})
 	s.expectErrorCode(err, "INTERNAL-SERVER-ERROR")
 	s.Equal(0, len(users), "Users should be nil")
 }
@@ -3,6 +3,7 @@ package postgres
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/lib/pq"
@@ -47,12 +48,46 @@ var ErrMissingHiringManagerOffer = &httperror.Error{
 	Code: "MISSING-HIRING-MANAGER-OFFER-ERROR",
 }
 
+var ErrInvalidCursor = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The provided cursor is invalid",
+	Code:    "INVALID-CURSOR-ERROR",
+}
+
+var ErrInvalidSortOrder = &httperror.Error{
+	Status:  http.StatusBadRequest,
+	Message: "The sort order must either be ASC or DESC",
+	Code:    "INVALID-SORT-ORDER-ERROR",
+}
+
+var ErrInvalidPasswordResetToken = &httperror.Error{
+	Status:  400,
+	Message: "The password reset token is invalid, has expired or has already been used",
+	Code:    "INVALID-PASSWORD-RESET-TOKEN-ERROR",
+}
+
+func NewInvalidSortFieldError(sortBy string, sortColumns map[string]string) *httperror.Error {
+	fields := []string{}
+	for field := range sortColumns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return &httperror.Error{
+		Status:  http.StatusBadRequest,
+		Message: fmt.Sprintf("Results cannot be sorted by %s. They can only be sorted by %s", sortBy, strings.Join(fields, ", ")),
+		Code:    "INVALID-SORT-FIELD-ERROR",
+		Args:    []string{sortBy, strings.Join(fields, ", ")},
+	}
+}
+
 func NewUniqueViolationError(entity string, pgErr *pq.Error) *httperror.Error {
-	detail := pgErr.Detail
-	before, _, _ := strings.Cut(detail, ")=(")
-	before = strings.ReplaceAll(before, "_", " ") // Replace all underscores in column names with spaces
+	columns := constraintColumns(pgErr)
 
-	attributes := strings.Split(before[5:], ", ") // first 5 characters of "before" is excluded because it is a "Key ("
+	attributes := []string{}
+	for _, column := range columns {
+		attributes = append(attributes, strings.ReplaceAll(column, "_", " ")) // Replace all underscores in column names with spaces
+	}
 
 	message := `A %s with the provided %s already exists`
 	subMessage := ""
@@ -78,15 +113,18 @@ func NewUniqueViolationError(entity string, pgErr *pq.Error) *httperror.Error {
 		Status:  http.StatusConflict,
 		Message: message,
 		Code:    "UNIQUE-VIOLATION-ERROR",
+		Errors:  newColumnErrors(columns, "unique", message),
+		Args:    []string{entity, strings.Join(attributes, ", ")},
 	}
 }
 
 func NewInvalidForeignKeyError(pgErr *pq.Error) *httperror.Error {
-	detail := pgErr.Detail
-	before, _, _ := strings.Cut(detail, ")=(")
-	before = strings.ReplaceAll(before, "_", " ") // Replace all underscores in column names with spaces
+	columns := constraintColumns(pgErr)
 
-	attributes := strings.Split(before[5:], ", ") // first 5 characters of "before" is excluded because it is a "Key ("
+	attributes := []string{}
+	for _, column := range columns {
+		attributes = append(attributes, strings.ReplaceAll(column, "_", " ")) // Replace all underscores in column names with spaces
+	}
 
 	message := `The provided %s is invalid`
 	subMessage := ""
@@ -110,7 +148,40 @@ func NewInvalidForeignKeyError(pgErr *pq.Error) *httperror.Error {
 		Status:  http.StatusBadRequest,
 		Message: message,
 		Code:    "INVALID-FOREIGN-KEY-ERROR",
+		Errors:  newColumnErrors(columns, "foreignKey", message),
+		Args:    []string{strings.Join(attributes, ", ")},
+	}
+}
+
+// Returns the columns of the violated constraint from the error detail, e.g. "Key (tenant_id, email)=(...) already exists."
+func constraintColumns(pgErr *pq.Error) []string {
+	before, _, _ := strings.Cut(pgErr.Detail, ")=(")
+	before, ok := strings.CutPrefix(before, "Key (")
+	if !ok {
+		return []string{}
+	}
+
+	return strings.Split(before, ", ")
+}
+
+// Columns are reported by the name of their field in requests (e.g. tenant_id as tenantId), so that clients can match them to their inputs
+func newColumnErrors(columns []string, tag string, message string) []httperror.FieldError {
+	fieldErrors := []httperror.FieldError{}
+	for _, column := range columns {
+		words := strings.Split(column, "_")
+		for i := 1; i < len(words); i++ {
+			if words[i] != "" {
+				words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+			}
+		}
+
+		fieldErrors = append(fieldErrors, httperror.FieldError{
+			Field:   strings.Join(words, ""),
+			Tag:     tag,
+			Message: message,
+		})
 	}
+	return fieldErrors
 }
 
 func New404NotFoundError(entity string) *httperror.Error {
@@ -118,5 +189,6 @@ func New404NotFoundError(entity string) *httperror.Error {
 		Status:  404,
 		Message: fmt.Sprintf("The %s does not exist", entity),
 		Code:    "RESOURCE-NOT-FOUND-ERROR",
+		Args:    []string{entity},
 	}
 }
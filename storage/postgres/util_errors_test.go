@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"multi-tenant-HR-information-system-backend/httperror"
+)
+
+func TestNewUniqueViolationError(t *testing.T) {
+	pgErr := &pq.Error{Code: "23505", Detail: "Key (tenant_id, email)=(a9f3c1de-0b8c-4c4f-9d5f-2f6b1c1b1e10, jane@example.com) already exists."}
+
+	err := NewUniqueViolationError("user", pgErr)
+
+	if want := "A user with the provided tenant id and email already exists"; err.Message != want {
+		t.Errorf("Message = %q, want %q", err.Message, want)
+	}
+	want := []httperror.FieldError{
+		{Field: "tenantId", Tag: "unique", Message: err.Message},
+		{Field: "email", Tag: "unique", Message: err.Message},
+	}
+	if !reflect.DeepEqual(err.Errors, want) {
+		t.Errorf("Errors = %+v, want %+v", err.Errors, want)
+	}
+}
+
+func TestNewInvalidForeignKeyError(t *testing.T) {
+	pgErr := &pq.Error{Code: "23503", Detail: `Key (job_requisition_id, tenant_id)=(x, y) is not present in table "job_requisition".`}
+
+	err := NewInvalidForeignKeyError(pgErr)
+
+	if want := "The provided job requisition id-tenant id combination is invalid"; err.Message != want {
+		t.Errorf("Message = %q, want %q", err.Message, want)
+	}
+	want := []httperror.FieldError{
+		{Field: "jobRequisitionId", Tag: "foreignKey", Message: err.Message},
+		{Field: "tenantId", Tag: "foreignKey", Message: err.Message},
+	}
+	if !reflect.DeepEqual(err.Errors, want) {
+		t.Errorf("Errors = %+v, want %+v", err.Errors, want)
+	}
+}
+
+// Errors without a key in their detail (e.g. from a trigger) should not cause a panic
+func TestConstraintColumnsWithoutKey(t *testing.T) {
+	columns := constraintColumns(&pq.Error{Detail: "Failing row contains (...)."})
+	if len(columns) != 0 {
+		t.Errorf("constraintColumns() = %q, want none", columns)
+	}
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Notified by a trigger on casbin_rule whenever its rows change (see migration 000011)
+const policyChangeChannel = "casbin_rule_changed"
+
+// Sent to the callback instead of the statement type when the connection was re-established, as notifications may have
+// been missed while it was down
+const PolicyChangeReconnected = "RECONNECTED"
+
+// Prefixed to the statement type when the change was made by the instance itself, which has already reloaded its enforcer
+const ownPolicyChangePrefix = "OWN-"
+
+// Implements casbin's persist.Watcher by listening for changes to casbin_rule, so that every instance sharing the database
+// can reload its enforcer, including after the policies are changed directly in the database
+type PolicyWatcher struct {
+	listener        *pq.Listener
+	applicationName string // Of the instance's connections, which the trigger includes in the notification
+	quit            chan struct{}
+	closeOnce       sync.Once
+	mu              sync.Mutex
+	callback        func(string)
+}
+
+// The listener holds its own connection, as a pooled connection can be handed to another query while it is listening
+func (postgres *postgresStorage) NewPolicyWatcher() (*PolicyWatcher, error) {
+	listener := pq.NewListener(postgres.connStr, 10*time.Second, time.Minute, nil)
+	if err := listener.Listen(policyChangeChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	watcher := &PolicyWatcher{
+		listener:        listener,
+		applicationName: postgres.applicationName,
+		quit:            make(chan struct{}),
+		callback:        func(string) {},
+	}
+	go watcher.watch()
+
+	return watcher, nil
+}
+
+func (watcher *PolicyWatcher) watch() {
+	// A dead connection is only noticed when it is used, so it is pinged while there are no notifications
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case notification, ok := <-watcher.listener.Notify:
+			if !ok {
+				return
+			}
+			// pq sends nil after reconnecting
+			if notification == nil {
+				watcher.notify(PolicyChangeReconnected)
+			} else {
+				watcher.notify(watcher.parseChange(notification.Extra))
+			}
+		case <-ticker.C:
+			go watcher.listener.Ping()
+		case <-watcher.quit:
+			return
+		}
+	}
+}
+
+// The notification is the statement type & the application name of the connection that made the change, e.g. "INSERT hris-..."
+func (watcher *PolicyWatcher) parseChange(notification string) string {
+	change, applicationName, _ := strings.Cut(notification, " ")
+	if applicationName == watcher.applicationName {
+		return ownPolicyChangePrefix + change
+	}
+	return change
+}
+
+// Returns whether the change was made by the instance itself, in which case its enforcer does not need to be reloaded
+func (watcher *PolicyWatcher) IsOwnChange(change string) bool {
+	return strings.HasPrefix(change, ownPolicyChangePrefix)
+}
+
+func (watcher *PolicyWatcher) notify(change string) {
+	watcher.mu.Lock()
+	callback := watcher.callback
+	watcher.mu.Unlock()
+
+	callback(change)
+}
+
+// The callback is called with the type of statement that changed casbin_rule (e.g. INSERT or DELETE), or with
+// PolicyChangeReconnected. Changes made by the instance itself are passed too, see IsOwnChange
+func (watcher *PolicyWatcher) SetUpdateCallback(callback func(string)) error {
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+
+	watcher.callback = callback
+	return nil
+}
+
+// Does nothing, as the trigger on casbin_rule notifies every change, whichever instance (or person) made it
+func (watcher *PolicyWatcher) Update() error {
+	return nil
+}
+
+// Stops listening & closes the listener's connection. Calling Close again does nothing
+func (watcher *PolicyWatcher) Close() {
+	watcher.closeOnce.Do(func() {
+		close(watcher.quit)
+		watcher.listener.Close()
+	})
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"time"
+
+	"multi-tenant-HR-information-system-backend/storage"
+)
+
+func (s *IntegrationTestSuite) TestPolicyWatcher() {
+	watcher, err := s.postgres.NewPolicyWatcher()
+	s.Require().Equal(nil, err)
+	defer watcher.Close()
+
+	changes := make(chan string, 10)
+	err = watcher.SetUpdateCallback(func(change string) {
+		changes <- change
+	})
+	s.Equal(nil, err)
+
+	expectChange := func(want string) {
+		select {
+		case got := <-changes:
+			s.Equal(want, got)
+		case <-time.After(5 * time.Second):
+			s.Fail("The watcher should have been notified of the change", want)
+		}
+	}
+
+	policies := storage.Policies{
+		Subject:  "ROLE_WATCHED",
+		TenantId: "2ad1dcfc-8867-49f7-87a3-8bd8d1154924",
+		Resources: []storage.Resource{
+			{Path: "/api/tenants/{tenantId}", Method: "GET"},
+			{Path: "/api/tenants/{tenantId}/users", Method: "GET"},
+		},
+	}
+	err = s.postgres.CreatePolicies(policies)
+	s.Equal(nil, err)
+	// A statement that changes several rows is only notified once
+	// The change was made by the watcher's own instance, which has already reloaded its enforcer
+	expectChange("OWN-INSERT")
+	s.True(watcher.IsOwnChange("OWN-INSERT"))
+
+	// Changes made by another instance (or a person) are not the watcher's own
+	_, err = s.dbRootConn.Exec("DELETE FROM casbin_rule WHERE V0 = $1", policies.Subject)
+	s.Equal(nil, err)
+	expectChange("DELETE")
+	s.False(watcher.IsOwnChange("DELETE"))
+
+	select {
+	case change := <-changes:
+		s.Fail("The watcher should only be notified once per statement", change)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func (s *IntegrationTestSuite) TestPolicyWatcherCloseTwice() {
+	watcher, err := s.postgres.NewPolicyWatcher()
+	s.Require().Equal(nil, err)
+
+	watcher.Close()
+	s.NotPanics(watcher.Close, "Closing the watcher again should do nothing")
+}
@@ -51,6 +51,14 @@ func NewS3(credentialsProvider aws.CredentialsProvider, regionCode string) *S3 {
 	return &S3{client: client, baseUrl: baseUrl}
 }
 
+// Checks that the bucket can be reached with the client's credentials, e.g. for readiness probes
+func (s *S3) Ping(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(BucketName),
+	})
+	return err
+}
+
 func NewFakeS3(credentialsProvider aws.CredentialsProvider, fakeUrl string) *S3 {
 	cfg, _ := config.LoadDefaultConfig(
 		context.TODO(),
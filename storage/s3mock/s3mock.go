@@ -1,6 +1,7 @@
 package s3mock
 
 import (
+	"context"
 	"errors"
 	"io"
 	"multi-tenant-HR-information-system-backend/httperror"
@@ -16,4 +17,8 @@ func NewS3Mock() *s3Mock {
 
 func (s* s3Mock) UploadResume(file io.Reader, jobApplicationId string, firstName string, lastName string, fileExt string) (url string, err error) {
 	return "", httperror.NewInternalServerError(errors.New("this is a mocked error"))
-}
\ No newline at end of file
+}
+
+func (s *s3Mock) Ping(ctx context.Context) error {
+	return nil
+}
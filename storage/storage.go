@@ -1,19 +1,50 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"time"
 )
 
 type Storage interface {
+	Ping(ctx context.Context) error
+
 	CreateTenant(tenant Tenant) error
-	GetTenants(filter Tenant) ([]Tenant, error)
+	GetTenants(filter Tenant, page PageRequest) (tenants []Tenant, nextCursor string, err error)
 	CreateDivision(division Division) error
 	CreateDepartment(department Department) error
 
-	CreateUser(user User) error
-	GetUsers(userFilter User) ([]User, error)
+	CreateUser(user User, policies Policies) error
+	GetUsers(userFilter User, page PageRequest) (users []User, nextCursor string, err error)
 	GetUserSupervisors(userId string, TenantId string) ([]string, error)
+	ChangePassword(userId string, tenantId string, passwordHash string) error
+	CreatePasswordResetToken(token PasswordResetToken) error
+	IsPasswordResetTokenValid(token PasswordResetToken) (bool, error)
+	RedeemPasswordResetToken(token PasswordResetToken, passwordHash string) error
+	SetPendingTotpSecretKey(userId string, tenantId string, totpSecretKey string) error
+	ConfirmTotpEnrollment(userId string, tenantId string, recoveryCodes []RecoveryCode) error
+	IsTotpEnrollmentAllowed(userId string, tenantId string, enrollmentTokenHash string) (bool, error)
+	ResetTotp(userId string, tenantId string, enrollmentTokenHash string, enrollmentTokenExpiresAt time.Time) error
+	GetRecoveryCodes(filter RecoveryCode) ([]RecoveryCode, error)
+	UseRecoveryCode(recoveryCode RecoveryCode) error
+	UseTotpStep(userId string, tenantId string, step int64) (bool, error)
+	GetLoginAttempt(tenantId string, email string) (LoginAttempt, error)
+	RecordFailedLogin(tenantId string, email string) (LoginAttempt, error)
+	LockLogin(tenantId string, email string, lockedUntil time.Time) error
+	DeleteLoginAttempt(tenantId string, email string) error
+	SetOidcConfiguration(config OidcConfiguration) error
+	GetOidcConfiguration(tenantId string) (OidcConfiguration, error)
+	SetSamlConfiguration(config SamlConfiguration) error
+	GetSamlConfiguration(tenantId string) (SamlConfiguration, error)
+	CreateSamlRequest(samlRequest SamlRequest) error
+	UseSamlRequest(samlRequest SamlRequest) error
+	CreateServiceAccount(serviceAccount ServiceAccount) error
+	CreateApiToken(apiToken ApiToken) error
+	GetApiTokens(filter ApiToken) ([]ApiToken, error)
+	DeleteApiTokens(filter ApiToken) (deleted int64, err error)
+
+	GetUserSessions(filter UserSession) ([]UserSession, error)
+	DeleteUserSessions(filter UserSession) (deleted int64, err error)
 
 	CreatePosition(position Position) error
 
@@ -22,23 +53,40 @@ type Storage interface {
 	GetUserPositions(userId string, filter UserPosition) ([]UserPosition, error)
 
 	CreatePolicies(policies Policies) error
+	GetPolicies(filter Policies) ([]Policies, error)
+	ReplacePolicies(policies Policies) error
+	DeletePolicies(filter Policies) (deleted int64, err error)
 	CreateRoleAssignment(roleAssignment RoleAssignment) error
+	GetRoleAssignments(filter RoleAssignment) ([]RoleAssignment, error)
+	ReplaceRoleAssignments(userId string, tenantId string, roles []string) error
+	DeleteRoleAssignments(filter RoleAssignment) (deleted int64, err error)
 
-	CreateJobRequisition(jobRequisition JobRequisition) error
-	GetJobRequisitions(filter JobRequisition) ([]JobRequisition, error)
+	CreateJobRequisition(jobRequisition JobRequisition, hrApproverPolicies Policies) error
+	GetJobRequisitions(filter JobRequisition, page PageRequest) (jobRequisitions []JobRequisition, nextCursor string, err error)
 	UpdateJobRequisition(newValues JobRequisition, filter JobRequisition) error
-	HrApproveJobRequisition(jobRequisitionId string, tenantId string, hrApprover string, recruiter string) error
+	HrApproveJobRequisition(jobRequisitionId string, tenantId string, hrApprover string, recruiter string, recruiterPolicies Policies) error
 
 	CreateJobApplication(jobApplication JobApplication) error
-	GetJobApplications(filter JobApplication) ([]JobApplication, error)
+	GetJobApplications(filter JobApplication, page PageRequest) (jobApplications []JobApplication, nextCursor string, err error)
 	UpdateJobApplication(newValues JobApplication, filter JobApplication) error
-	OnboardNewHire(jobApplication JobApplication, newUser User) error
+	OnboardNewHire(jobApplication JobApplication, newUser User, newUserPolicies Policies) error
 }
 
 type FileStorage interface {
+	Ping(ctx context.Context) error
 	UploadResume(file io.Reader, jobApplicationId string, firstName string, lastName string, fileExt string) (url string, err error)
 }
 
+// Describes which page of results a Get* query should return
+// The zero value returns every matching row in the default order
+type PageRequest struct {
+	Limit     int    // Maximum number of rows to return. 0 means no limit
+	Cursor    string // Opaque cursor returned with the previous page. Takes precedence over Offset
+	Offset    int
+	SortBy    string // Each model defines the fields it can be sorted by
+	SortOrder string // Either ASC or DESC. Defaults to ASC
+}
+
 type Tenant struct {
 	Id        string
 	Name      string
@@ -46,6 +94,20 @@ type Tenant struct {
 	UpdatedAt string
 }
 
+// A session that a user is logged in with
+// Id is the public id used to refer to the session. SessionId is the secret session id kept in the session cookie & must not be exposed
+type UserSession struct {
+	Id        string
+	SessionId string
+	TenantId  string
+	UserId    string
+	ClientIp  string
+	UserAgent string
+	ExpiresAt string
+	CreatedAt string
+	UpdatedAt string
+}
+
 type Division struct {
 	Id        string
 	TenantId  string
@@ -64,14 +126,105 @@ type Department struct {
 }
 
 type User struct {
-	Id            string
-	TenantId      string
-	Email         string
-	Password      string
-	TotpSecretKey string
-	CreatedAt     string
-	UpdatedAt     string
-	LastLogin     string
+	Id                   string
+	TenantId             string
+	Email                string
+	Password             string
+	TotpSecretKey        string // Empty if the user has yet to enroll in TOTP
+	TotpPendingSecretKey string // Secret that the user is enrolling with, which has yet to be confirmed
+	MustChangePassword   bool   // Users with a generated password cannot log in until they change it
+	CreatedAt            string
+	UpdatedAt            string
+	LastLogin            string
+}
+
+// A one-time token that allows a user to reset their password. Only the SHA-256 hash of the token is stored
+type PasswordResetToken struct {
+	Id        string
+	TenantId  string
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    string
+	CreatedAt string
+	UpdatedAt string
+}
+
+// A single-use code that can be used in place of a TOTP. Codes that have been used are never returned
+type RecoveryCode struct {
+	Id         string
+	TenantId   string
+	UserId     string
+	LookupHash string // A truncated hash of the code, to find the code without comparing every argon2id hash
+	CodeHash   string
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+// Failed logins for a tenant & email, which may not belong to an existing user
+type LoginAttempt struct {
+	TenantId     string
+	Email        string
+	FailedCount  int
+	LockedUntil  time.Time // Zero if logins have never been locked
+	LastFailedAt time.Time
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+// A tenant's OpenID Connect identity provider. Users are matched by the value of EmailClaim in the ID token
+type OidcConfiguration struct {
+	TenantId     string
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	EmailClaim   string
+	CreatedAt    string
+	UpdatedAt    string
+}
+
+// A tenant's SAML 2.0 identity provider. Users are matched by the assertion's NameID, or by EmailAttribute if it is set
+type SamlConfiguration struct {
+	TenantId        string
+	EntityId        string
+	AcsUrl          string
+	IdpMetadata     string
+	EmailAttribute  string
+	JitProvisioning bool
+	CreatedAt       string
+	UpdatedAt       string
+}
+
+// An authentication request that has been sent to the tenant's SAML identity provider & not yet responded to
+type SamlRequest struct {
+	Id        string
+	TenantId  string
+	ExpiresAt time.Time
+	CreatedAt string
+}
+
+// A non-human principal (e.g. an integration) that authenticates with API tokens. Its id is used as its casbin subject
+type ServiceAccount struct {
+	Id        string
+	TenantId  string
+	Name      string
+	CreatedAt string
+	UpdatedAt string
+}
+
+// A bearer token that belongs to either a user or a service account. Only the SHA-256 hash of the token is stored
+type ApiToken struct {
+	Id               string
+	TenantId         string
+	UserId           string // Empty if the token belongs to a service account
+	ServiceAccountId string // Empty if the token belongs to a user
+	Name             string
+	TokenHash        string
+	Scopes           []string
+	ExpiresAt        time.Time // Zero if the token does not expire
+	CreatedAt        string
+	UpdatedAt        string
 }
 
 type Position struct {
@@ -159,9 +312,11 @@ type Policies struct {
 }
 
 type RoleAssignment struct {
-	UserId    string `validate:"required,notBlank,uuid" name:"user id"`
-	Role      string `validate:"required,notBlank" name:"role name"`
-	TenantId  string `validate:"required,notBlank,uuid" name:"tenant id"`
-	CreatedAt string
-	UpdatedAt string
+	UserId     string    `validate:"required,notBlank,uuid" name:"user id"`
+	Role       string    `validate:"required,notBlank" name:"role name"`
+	TenantId   string    `validate:"required,notBlank,uuid" name:"tenant id"`
+	ValidFrom  time.Time // Zero if the role applies as soon as it is assigned
+	ValidUntil time.Time // Zero if the role does not expire
+	CreatedAt  string
+	UpdatedAt  string
 }